@@ -0,0 +1,58 @@
+// Package checksum defines the per-record checksum algorithms used by the
+// hex record formats.  Readers and writers default to the algorithm their
+// format specifies, but accept any Algorithm for vendor tools that
+// deviate from the standard.  It also names the whole-file digests of
+// integrity trailer records.
+package checksum
+
+// Algorithm computes the checksum byte over the binary image of a record
+type Algorithm interface {
+	Sum(b []byte) byte
+}
+
+// Func adapts an ordinary function to the Algorithm interface
+type Func func(b []byte) byte
+
+// Sum calls f(b)
+func (f Func) Sum(b []byte) byte {
+	return f(b)
+}
+
+// Provided algorithms
+var (
+	// TwosComplement is the two's complement of the 8-bit sum (Intel Hex)
+	TwosComplement Algorithm = Func(twosComplement)
+
+	// OnesComplement is the one's complement of the 8-bit sum (S-Records)
+	OnesComplement Algorithm = Func(onesComplement)
+
+	// Sum is the plain 8-bit sum
+	Sum Algorithm = Func(sum)
+
+	// XOR is the exclusive-or of all bytes
+	XOR Algorithm = Func(xor)
+)
+
+func sum(b []byte) byte {
+	var cs byte
+	for _, v := range b {
+		cs += v
+	}
+	return cs
+}
+
+func twosComplement(b []byte) byte {
+	return -sum(b)
+}
+
+func onesComplement(b []byte) byte {
+	return ^sum(b)
+}
+
+func xor(b []byte) byte {
+	var cs byte
+	for _, v := range b {
+		cs ^= v
+	}
+	return cs
+}
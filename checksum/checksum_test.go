@@ -0,0 +1,28 @@
+package checksum
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAlgorithms(t *testing.T) {
+	fmt.Println("TestAlgorithms()")
+
+	b := []byte{0x10, 0x20, 0xF0}
+	cases := []struct {
+		a    Algorithm
+		want byte
+	}{
+		{TwosComplement, 0xE0},
+		{OnesComplement, 0xDF},
+		{Sum, 0x20},
+		{XOR, 0xC0},
+	}
+
+	for i, c := range cases {
+		if got := c.a.Sum(b); got != c.want {
+			fmt.Printf("case %d: got 0x%02X, want 0x%02X\n", i, got, c.want)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"hash"
+	"hash/crc32"
+)
+
+// Digest names a whole-file digest carried by an integrity trailer
+// record, for detecting tampering and corruption that per-record
+// checksums miss, such as dropped or reordered records
+type Digest string
+
+// Provided digests
+const (
+	CRC32  Digest = "CRC32"  // IEEE CRC-32
+	SHA256 Digest = "SHA256" // SHA-256
+)
+
+// Digests lists the provided digests
+var Digests = []Digest{CRC32, SHA256}
+
+// New returns a hash computing d, or nil if d is unknown
+func (d Digest) New() hash.Hash {
+	switch d {
+	case CRC32:
+		return crc32.NewIEEE()
+	case SHA256:
+		return sha256.New()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/peteArnt/GoHexIO/hexgen"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "anonymize",
+		summary: "replace the data of a hex file by random bytes, keeping its structure",
+		run:     runAnonymize,
+	})
+}
+
+func runAnonymize(args []string) error {
+	fs := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	out := fs.String("o", "-", "output file")
+	seed := fs.Int64("seed", 1, "seed for the random data; equal seeds give equal output")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return usageError("exactly one input file required")
+	}
+
+	in, err := openInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	f, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+
+	if err := hexgen.Anonymize(f, in, *seed); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "cat",
+		summary: "concatenate the records of several files into one",
+		run:     runCat,
+	})
+}
+
+const catUsage = `usage: gohexio cat [-o output] [-f format] input[@offset]...
+
+Concatenates the data of the inputs, in any formats, in command line
+order.  Unlike a merge, data is neither sorted nor checked for overlaps;
+the layout of the inputs is taken as already correct.  An input may be
+followed by @offset to move its data, e.g. app.bin@0x8000 or
+image.hex@-0x1000.
+`
+
+func runCat(args []string) error {
+	fs := flag.NewFlagSet("cat", flag.ExitOnError)
+	out := fs.String("o", "-", "output file")
+	format := fs.String("f", "", "output format; by default derived from the output name, else ihex")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), catUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return usageError("at least one input file required")
+	}
+
+	f := image.Format(*format)
+	if f == "" {
+		var ok bool
+		if f, ok = hexio.FormatForName(*out); !ok {
+			f = image.IntelHex
+		}
+	}
+	c, err := hexio.CodecFor(f)
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	w, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	x := c.NewWriter(w, image.EncodeOptions{Fill: 0xFF})
+
+	for _, arg := range fs.Args() {
+		if err := catInput(x, arg); err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	if err := x.Close(); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// catInput copies the data of the input named by arg, a file name with
+// an optional @offset, to x
+func catInput(x hexio.SegmentWriter, arg string) error {
+	fn, delta := inputOffset(arg)
+
+	in, err := openInput(fn)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	br := bufio.NewReaderSize(in, hexio.DetectSize)
+	f, ok := hexio.FormatForName(fn)
+	if !ok || f != image.Binary {
+		// Binary data cannot be detected, the other formats can
+		head, err := br.Peek(hexio.DetectSize)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if f, err = hexio.Detect(head); err != nil {
+			return &hexio.ParseError{File: fn, Err: err}
+		}
+	}
+	c, err := hexio.CodecFor(f)
+	if err != nil {
+		return err
+	}
+
+	r := c.NewReader(br)
+	for {
+		s, err := r.ReadSegment()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if pe, ok := err.(*hexio.ParseError); ok && pe.File == "" {
+				pe.File = fn
+			}
+			return err
+		}
+
+		addr := int64(s.Address) + delta
+		if addr < 0 || addr+int64(len(s.Data)) > 1<<32 {
+			return fmt.Errorf("%s: offset %d moves data at 0x%X out of the 32-bit address space",
+				fn, delta, s.Address)
+		}
+		s.Address = uint32(addr)
+		if err := x.WriteSegment(s); err != nil {
+			return err
+		}
+	}
+}
+
+// inputOffset splits an input argument into the file name and the
+// offset given by an @offset suffix, 0 if there is none
+func inputOffset(arg string) (string, int64) {
+	if i := strings.LastIndexByte(arg, '@'); i >= 0 {
+		if d, err := strconv.ParseInt(arg[i+1:], 0, 64); err == nil {
+			return arg[:i], d
+		}
+	}
+	return arg, 0
+}
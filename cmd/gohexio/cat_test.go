@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+)
+
+func TestCat(t *testing.T) {
+	fmt.Println("TestCat()")
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "app.bin")
+	hex := filepath.Join(dir, "boot.hex")
+	out := filepath.Join(dir, "out.hex")
+	os.WriteFile(bin, []byte("app"), 0644)
+	os.WriteFile(hex, []byte(":0500100048656C6C6FF7\n:00000001FF\n"), 0644)
+
+	// Binary input is taken by its name, as it cannot be detected
+	if err := runCat([]string{"-o", out, hex, bin + "@0x8000"}); err != nil {
+		fmt.Println(err)
+		t.FailNow()
+	}
+	m, _, err := hexio.Open(out)
+	if err != nil {
+		fmt.Println(err)
+		t.FailNow()
+	}
+	if string(m.Extract(0x10, 5, 0)) != "Hello" || string(m.Extract(0x8000, 3, 0)) != "app" || m.Len() != 8 {
+		fmt.Println(m.Segments())
+		t.Fail()
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "convert",
+		summary: "convert a file to another format, keeping its start address and header",
+		run:     runConvert,
+	})
+}
+
+const convertUsage = `usage: gohexio convert [-o output] [-f format] [-from format] input
+
+Converts the input, e.g. between Intel Hex, S-Records and raw binary.
+Start addresses and S-Record headers are carried over wherever the
+output format has room for them.  The input format is detected from the
+content unless given, which raw binary input requires; its data loads
+at address 0.
+`
+
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	out := fs.String("o", "-", "output file")
+	format := fs.String("f", "", "output format; by default derived from the output name, else ihex")
+	from := fs.String("from", "", "input format; by default detected")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), convertUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return usageError("exactly one input file required")
+	}
+
+	f := image.Format(*format)
+	if f == "" {
+		var ok bool
+		if f, ok = hexio.FormatForName(*out); !ok {
+			f = image.IntelHex
+		}
+	}
+	for _, g := range []image.Format{f, image.Format(*from)} {
+		if _, err := hexio.CodecFor(g); g != "" && err != nil {
+			return usageError(err.Error())
+		}
+	}
+
+	in, err := openInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	w, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	if err := hexio.Convert(w, f, in, image.Format(*from)); err != nil {
+		w.Close()
+		if pe, ok := err.(*hexio.ParseError); ok && pe.File == "" {
+			pe.File = fs.Arg(0)
+		}
+		return err
+	}
+	return w.Close()
+}
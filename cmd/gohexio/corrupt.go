@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/peteArnt/GoHexIO/hexgen"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "corrupt",
+		summary: "inject controlled defects into a valid hex file",
+		run:     runCorrupt,
+	})
+}
+
+func runCorrupt(args []string) error {
+	var defects defectList
+
+	fs := flag.NewFlagSet("corrupt", flag.ExitOnError)
+	out := fs.String("o", "-", "output file")
+	fs.Var(&defects, "defect", defectUsage)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return usageError("exactly one input file required")
+	}
+
+	in, err := openInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	f, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+
+	if err := hexgen.Corrupt(f, in, defects...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+)
+
+// Exit codes.  They are a stable contract: scripts and CI pipelines may
+// rely on them to tell classes of failure apart.
+const (
+	exitOK       = 0
+	exitError    = 1 // Failure of any other kind, such as I/O errors
+	exitUsage    = 2 // Bad command line
+	exitParse    = 3 // Malformed input
+	exitMismatch = 4 // Verification found differences
+	exitOverlap  = 5 // Inputs provide data for the same address
+	exitProtect  = 6 // An input provides data for a protected region
+)
+
+// usageError reports a bad command line
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// mismatchError reports a failed verification
+type mismatchError struct {
+	file string // Input found to differ
+	msg  string
+}
+
+func (e *mismatchError) Error() string { return e.file + ": " + e.msg }
+
+// diagnose classifies err, returning the fields of its diagnostic line
+// and the exit code
+func diagnose(err error) (file string, line int, code string, msg string, exit int) {
+	var (
+		pe *hexio.ParseError
+		oe *hexio.OverlapError
+		pr *hexio.ProtectedError
+		me *mismatchError
+		ue usageError
+	)
+
+	switch {
+	case errors.As(err, &pe):
+		return pe.File, pe.Line, "parse", pe.Err.Error(), exitParse
+	case errors.As(err, &oe):
+		return oe.File, 0, "overlap", fmt.Sprintf("data at 0x%08X overlaps an earlier input", oe.Address), exitOverlap
+	case errors.As(err, &pr):
+		return pr.File, 0, "protected", fmt.Sprintf("data at 0x%08X falls within protected region %s",
+			pr.Address, pr.Region.Name), exitProtect
+	case errors.As(err, &me):
+		return me.file, 0, "mismatch", me.msg, exitMismatch
+	case errors.As(err, &ue):
+		return "", 0, "usage", ue.Error(), exitUsage
+	}
+	return "", 0, "error", err.Error(), exitError
+}
+
+// report writes the diagnostic for err to w, one line of the form
+//
+//	file:line: code: message
+//
+// where file is "-" and line 0 when not known, and code is one of parse,
+// overlap, protected, mismatch, usage or error.  It returns the exit code.
+func report(w io.Writer, err error) int {
+	file, line, code, msg, exit := diagnose(err)
+	if file == "" {
+		file = "-"
+	}
+	fmt.Fprintf(w, "%s:%d: %s: %s\n", file, line, code, msg)
+	return exit
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestDiagnose(t *testing.T) {
+	fmt.Println("TestDiagnose()")
+
+	cases := []struct {
+		err  error
+		line string // Diagnostic written by report
+		exit int
+	}{
+		{&hexio.ParseError{File: "a.hex", Line: 7, Err: errors.New("bad checksum")},
+			"a.hex:7: parse: bad checksum", 3},
+		{fmt.Errorf("merge: %w", &hexio.ParseError{Err: errors.New("bad record")}),
+			"-:0: parse: bad record", 3},
+		{&mismatchError{file: "flash.bin", msg: "2 bytes differ"},
+			"flash.bin:0: mismatch: 2 bytes differ", 4},
+		{&hexio.OverlapError{File: "b.hex", Address: 0x100},
+			"b.hex:0: overlap: data at 0x00000100 overlaps an earlier input", 5},
+		{&hexio.ProtectedError{File: "c.hex", Region: image.Region{Name: "otp"}, Address: 0x1FFF7800},
+			"c.hex:0: protected: data at 0x1FFF7800 falls within protected region otp", 6},
+		{usageError("exactly one job file required"),
+			"-:0: usage: exactly one job file required", 2},
+		{fs.ErrNotExist,
+			"-:0: error: file does not exist", 1},
+	}
+	for _, c := range cases {
+		var sb strings.Builder
+		if exit := report(&sb, c.err); exit != c.exit || sb.String() != c.line+"\n" {
+			fmt.Printf("%v: %q, exit %d; want %q, exit %d\n", c.err, sb.String(), exit, c.line, c.exit)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "diff",
+		summary: "show the bytes at which two files, in any formats, differ",
+		run:     runDiff,
+	})
+}
+
+const diffUsage = `usage: gohexio diff old new
+
+Compares the data of two files, in any formats, such as two builds of a
+firmware, byte by byte.  Every differing address range is listed with
+the old bytes marked '-' and the new bytes marked '+', in rows lining up
+with a hexdump of the binary; "--" marks addresses without data.  The
+exit status is that of verify.
+`
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), diffUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		return usageError("exactly two input files required")
+	}
+
+	diffs, err := hexio.DiffFiles(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+	if err := hexio.WriteDiff(os.Stdout, diffs); err != nil {
+		return err
+	}
+
+	var n int
+	for _, d := range diffs {
+		n += d.Len()
+	}
+	return &mismatchError{file: fs.Arg(1), msg: fmt.Sprintf("%d bytes differ in %d range(s)", n, len(diffs))}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "dump",
+		summary: "print the records of an Intel Hex or S-Record file",
+		run:     runDump,
+	})
+}
+
+func runDump(args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	follow := fs.Bool("follow", false, "keep printing records as the file grows, like tail -f, until interrupted")
+	poll := fs.Duration("poll", 250*time.Millisecond, "how often -follow checks the file for new records")
+	page := fs.Int("page", 0, "repeat the column headings every `n` lines, 0 for none")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return usageError("exactly one input file required")
+	}
+	if *poll <= 0 {
+		return usageError("poll interval must be positive")
+	}
+
+	in, err := openInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var (
+		r      io.Reader = in
+		format string
+	)
+	if *follow {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		br := bufio.NewReader(hexio.Follow(ctx, in, *poll))
+		format, err = sniffFormat(br)
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		r = br
+	} else {
+		format, err = detectFormat(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "ihex":
+		d, dd := ihex.NewDecoder(r), ihex.NewDumper(os.Stdout)
+		dd.SetPage(*page)
+		for rec, err := range d.Records() {
+			if err != nil {
+				return dumpError(fs.Arg(0), d.Line(), err)
+			}
+			if err := dd.Dump(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "srec":
+		d, dd := srec.NewDecoder(r), srec.NewDumper(os.Stdout)
+		dd.SetPage(*page)
+		for rec, err := range d.Records() {
+			if err != nil {
+				return dumpError(fs.Arg(0), d.Line(), err)
+			}
+			if err := dd.Dump(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%s: cannot dump %s files", fs.Arg(0), format)
+}
+
+// Work out whether r, which may still be growing, holds Intel Hex or
+// S-Records from the start code of its first record
+func sniffFormat(r *bufio.Reader) (string, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return "", err
+		}
+		switch b[0] {
+		case '\r', '\n':
+			r.Discard(1)
+		case ':':
+			return "ihex", nil
+		case 'S':
+			return "srec", nil
+		default:
+			return "", fmt.Errorf("cannot dump records starting with %q", b[0])
+		}
+	}
+}
+
+// dumpError reports a failure to decode the record on the given line;
+// an interrupted -follow is not one
+func dumpError(fn string, line int, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return &hexio.ParseError{File: fn, Line: line, Err: err}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "explain",
+		summary: "break a single record into labeled fields",
+		run:     runExplain,
+	})
+}
+
+func runExplain(args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return usageError("exactly one record required")
+	}
+
+	rec := strings.TrimSpace(fs.Arg(0))
+	if strings.HasPrefix(rec, "S") {
+		fmt.Print(srec.Explain(rec))
+	} else {
+		fmt.Print(ihex.Explain(rec))
+	}
+	return nil
+}
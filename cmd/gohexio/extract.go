@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "extract",
+		summary: "pull the data of an ELF symbol out of an image",
+		run:     runExtract,
+	})
+}
+
+const extractUsage = `usage: gohexio extract -elf file -symbol name [-o output] [-f format] input
+
+Looks up the address and size of a symbol, such as a calibration table,
+in the symbol table of the ELF file the input was built from, and shows
+its data in the input as a hex dump.  With -o or -f the data is written
+as an image in the given format instead.
+`
+
+func runExtract(args []string) error {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	elfFile := fs.String("elf", "", "ELF file holding the symbol table")
+	symbol := fs.String("symbol", "", "symbol to extract")
+	out := fs.String("o", "", "output file, - for standard output")
+	format := fs.String("f", "", "output format; by default derived from the output name, else ihex")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), extractUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *elfFile == "" || *symbol == "" {
+		return usageError("an ELF file, a symbol and exactly one input file required")
+	}
+
+	m, _, err := hexio.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	r, err := hexio.ELFSymbol(*elfFile, *symbol)
+	if err != nil {
+		return err
+	}
+	data, err := hexio.ExtractSymbol(m, *elfFile, *symbol)
+	if err != nil {
+		return err
+	}
+
+	part := image.New()
+	part.Write(r.Start, data)
+	if *out != "" || *format != "" {
+		return writeImage(*out, part, image.Format(*format))
+	}
+
+	fmt.Printf("%s: 0x%08X-0x%08X, %d bytes\n", r.Name, r.Start, r.End()-1, r.Size)
+	in := &inspector{m: part, addr: r.Start &^ 0xF, w: os.Stdout}
+	in.rows = int((r.End() - uint64(in.addr) + 15) / 16)
+	in.page()
+	return nil
+}
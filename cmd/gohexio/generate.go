@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/hexgen"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "generate",
+		summary: "generate a synthetic hex file for testing",
+		run:     runGenerate,
+	})
+}
+
+// defectList collects repeated -defect flags
+type defectList []hexgen.Defect
+
+func (d *defectList) String() string {
+	return fmt.Sprint(*d)
+}
+
+// Set parses "checksum:N", "truncate:N", "drop:N", "shuffle:SEED" or
+// "noterm"
+func (d *defectList) Set(s string) error {
+	kind, arg, _ := strings.Cut(s, ":")
+
+	var def hexgen.Defect
+	switch kind {
+	case "checksum":
+		def.Kind = hexgen.BadChecksum
+	case "truncate":
+		def.Kind = hexgen.TruncateRecord
+	case "drop":
+		def.Kind = hexgen.DropRecord
+	case "shuffle":
+		def.Kind = hexgen.ShuffleRecords
+	case "noterm":
+		def.Kind = hexgen.DropTerminator
+		*d = append(*d, def)
+		return nil
+	default:
+		return fmt.Errorf("unknown defect %q", kind)
+	}
+
+	n, err := strconv.ParseInt(arg, 0, 64)
+	if err != nil {
+		return fmt.Errorf("defect %q needs a numeric argument", kind)
+	}
+	if def.Kind == hexgen.ShuffleRecords {
+		def.Seed = n
+	} else {
+		def.Record = int(n)
+	}
+	*d = append(*d, def)
+	return nil
+}
+
+const defectUsage = "inject a defect: checksum:N, truncate:N, drop:N, shuffle:SEED or noterm (repeatable)"
+
+func runGenerate(args []string) error {
+	var defects defectList
+
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	format := fs.String("format", "ihex", "output format: ihex or srec")
+	base := fs.Uint64("base", 0, "address of the first block")
+	blocks := fs.Int("blocks", 1, "number of data blocks")
+	size := fs.Int("size", 256, "bytes per block")
+	gap := fs.Uint64("gap", 0, "unused bytes between blocks")
+	width := fs.Int("width", 0, "data bytes per record (0 for the format default)")
+	seed := fs.Int64("seed", 1, "seed for the generated data")
+	out := fs.String("o", "-", "output file")
+	fs.Var(&defects, "defect", defectUsage)
+	fs.Parse(args)
+
+	if *base > math.MaxUint32 || *gap > math.MaxUint32 {
+		return errors.New("addresses must fit in 32 bits")
+	}
+
+	spec := hexgen.Spec{
+		Base:    uint32(*base),
+		Blocks:  *blocks,
+		Size:    *size,
+		Gap:     uint32(*gap),
+		Width:   *width,
+		Seed:    *seed,
+		Defects: defects,
+	}
+
+	switch *format {
+	case "ihex":
+		spec.Format = hexgen.IntelHex
+	case "srec":
+		spec.Format = hexgen.SRecord
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	f, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+
+	if err := hexgen.Generate(f, spec); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
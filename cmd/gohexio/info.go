@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "info",
+		summary: "summarize the format, address ranges, records and checksums of a file",
+		run:     runInfo,
+	})
+}
+
+const infoUsage = `usage: gohexio info input...
+
+Prints, for each input, its format, the address ranges holding data and
+the number of data bytes, the records by type and the ranges provided
+by more than one record for Intel Hex and S-Records, the start address
+if there is one, and checksums over the data in address order: its
+CRC-32 and 32-bit byte sum, gaps left out.
+`
+
+func runInfo(args []string) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), infoUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return usageError("at least one input file required")
+	}
+
+	for i, fn := range fs.Args() {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := info(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// info prints the summary of the named file
+func info(fn string) error {
+	in, err := openInput(fn)
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(in)
+	in.Close()
+	if err != nil {
+		return err
+	}
+
+	m, f, err := hexio.Decode(bytes.NewReader(content))
+	if err != nil {
+		if pe, ok := err.(*hexio.ParseError); ok && pe.File == "" {
+			pe.File = fn
+		}
+		return err
+	}
+
+	// Record counts, by type name, and start address of the formats
+	// with records of several types
+	var (
+		counts   map[string]int
+		start    uint32
+		hasStart bool
+		spans    []image.Span
+	)
+	switch f {
+	case image.IntelHex:
+		recs, err := ihex.ReadAll(bytes.NewReader(content))
+		if err != nil {
+			return &hexio.ParseError{File: fn, Err: err}
+		}
+		spans = ihex.Layout(recs)
+		file := ihex.NewFile(recs)
+		counts = map[string]int{}
+		for t, n := range file.Stats().ByType {
+			counts[t.String()] = n
+		}
+		var kind ihex.StartKind
+		start, kind, hasStart = file.EntryPoint()
+		if kind == ihex.StartSegment {
+			start = start>>16<<4 + start&0xFFFF
+		}
+	case image.SRecord:
+		recs, err := srec.ReadAll(bytes.NewReader(content))
+		if err != nil {
+			return &hexio.ParseError{File: fn, Err: err}
+		}
+		spans = srec.Layout(recs)
+		file := srec.NewFile(recs)
+		counts = map[string]int{}
+		for t, n := range file.Stats().ByType {
+			counts[t.String()] = n
+		}
+		start, hasStart = file.StartAddress()
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "File:\t%s\n", fn)
+	fmt.Fprintf(tw, "Format:\t%s\n", f)
+	fmt.Fprintf(tw, "Data bytes:\t%d\n", m.Len())
+
+	var (
+		crc uint32
+		sum uint32
+	)
+	for _, s := range m.Segments() {
+		fmt.Fprintf(tw, "Range:\t0x%08X-0x%08X\t%d bytes\n", s.Address, s.End()-1, len(s.Data))
+		crc = crc32.Update(crc, crc32.IEEETable, s.Data)
+		for _, b := range s.Data {
+			sum += uint32(b)
+		}
+	}
+
+	for _, s := range spans {
+		if s.Kind == image.Overlap {
+			fmt.Fprintf(tw, "Overlap:\t0x%08X-0x%08X\t%d bytes\n", s.Start, s.End-1, s.Size())
+		}
+	}
+
+	if counts != nil {
+		types := make([]string, 0, len(counts))
+		for t := range counts {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		for _, t := range types {
+			fmt.Fprintf(tw, "Records:\t%s\t%d\n", t, counts[t])
+		}
+	}
+	if hasStart {
+		fmt.Fprintf(tw, "Start:\t0x%08X\n", start)
+	}
+	fmt.Fprintf(tw, "CRC-32:\t0x%08X\n", crc)
+	fmt.Fprintf(tw, "Sum:\t0x%08X\n", sum)
+	return tw.Flush()
+}
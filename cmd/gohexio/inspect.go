@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "inspect",
+		summary: "browse the data of a file interactively",
+		run:     runInspect,
+	})
+}
+
+const inspectHelp = `commands:
+  <enter>, n     next page
+  p              previous page
+  g ADDR         go to address ADDR
+  s              list segments
+  s N            go to the start of segment N
+  f HEX...       find bytes, e.g. "f DE AD BE EF"
+  f "TEXT"       find text
+  r N            show N rows per page
+  h, ?           this help
+  q              quit
+`
+
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	rows := fs.Int("rows", 16, "rows of 16 bytes per page")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return usageError("exactly one input file required")
+	}
+
+	m, f, err := hexio.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	in := &inspector{m: m, segs: m.Segments(), rows: max(*rows, 1), w: os.Stdout}
+	fmt.Fprintf(in.w, "%s: %s, %d bytes in %d segments; h for help\n",
+		fs.Arg(0), f, m.Len(), len(in.segs))
+	if len(in.segs) > 0 {
+		in.addr = in.segs[0].Address &^ 0xF
+	}
+	in.page()
+
+	sc := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(in.w, "> ")
+		if !sc.Scan() {
+			fmt.Fprintln(in.w)
+			return sc.Err()
+		}
+		if !in.exec(strings.TrimSpace(sc.Text())) {
+			return nil
+		}
+	}
+}
+
+// inspector is the state of an inspect session
+type inspector struct {
+	m    *image.Image
+	segs []image.Segment
+	addr uint32 // Address of the first row shown
+	from uint32 // Address the next search starts at
+	rows int    // Rows per page
+	w    io.Writer
+}
+
+// exec runs one command line, returning false to end the session
+func (in *inspector) exec(line string) bool {
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch cmd {
+	case "", "n":
+		// Stop at the last row of the address space rather than wrap
+		in.addr = uint32(min(uint64(in.addr)+in.pageSize(), 1<<32-16))
+	case "p":
+		in.addr = uint32(uint64(in.addr) - min(uint64(in.addr), in.pageSize()))
+	case "g":
+		a, err := strconv.ParseUint(arg, 0, 32)
+		if err != nil {
+			fmt.Fprintf(in.w, "bad address %q\n", arg)
+			return true
+		}
+		in.addr = uint32(a) &^ 0xF
+	case "s":
+		if arg == "" {
+			for i, s := range in.segs {
+				fmt.Fprintf(in.w, "%3d  0x%08X-0x%08X  %d bytes\n", i, s.Address, s.End()-1, len(s.Data))
+			}
+			return true
+		}
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || n >= len(in.segs) {
+			fmt.Fprintf(in.w, "no segment %q\n", arg)
+			return true
+		}
+		in.addr = in.segs[n].Address &^ 0xF
+	case "f":
+		pat, err := parsePattern(arg)
+		if err != nil {
+			fmt.Fprintln(in.w, err)
+			return true
+		}
+		a, ok := in.find(pat)
+		if !ok {
+			fmt.Fprintln(in.w, "not found")
+			return true
+		}
+		fmt.Fprintf(in.w, "found at 0x%08X\n", a)
+		in.addr = a &^ 0xF
+		in.page()
+		in.from = a + 1
+		return true
+	case "r":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			fmt.Fprintf(in.w, "bad row count %q\n", arg)
+			return true
+		}
+		in.rows = n
+	case "h", "?":
+		fmt.Fprint(in.w, inspectHelp)
+		return true
+	case "q":
+		return false
+	default:
+		fmt.Fprintf(in.w, "unknown command %q; h for help\n", cmd)
+		return true
+	}
+
+	in.page()
+	in.from = in.addr
+	return true
+}
+
+// pageSize returns the number of bytes a page spans
+func (in *inspector) pageSize() uint64 {
+	return uint64(in.rows) * 16
+}
+
+// page shows the rows starting at in.addr; bytes without data show as
+// "--" and rows without any data are skipped
+func (in *inspector) page() {
+	shown := 0
+	for r := 0; r < in.rows; r++ {
+		row := uint64(in.addr) + uint64(r)*16
+		if row >= 1<<32 {
+			break
+		}
+		segs := in.m.Crop(uint32(row), 16).Segments()
+		if len(segs) == 0 {
+			continue
+		}
+
+		var hx, asc strings.Builder
+		for i := uint64(0); i < 16; i++ {
+			b, ok := byteAt(segs, row+i)
+			switch {
+			case !ok:
+				hx.WriteString("-- ")
+				asc.WriteByte(' ')
+			case b >= 0x20 && b < 0x7F:
+				fmt.Fprintf(&hx, "%02X ", b)
+				asc.WriteByte(b)
+			default:
+				fmt.Fprintf(&hx, "%02X ", b)
+				asc.WriteByte('.')
+			}
+			if i == 7 {
+				hx.WriteByte(' ')
+			}
+		}
+		fmt.Fprintf(in.w, "%08X  %s |%s|\n", row, hx.String(), asc.String())
+		shown++
+	}
+	if shown == 0 {
+		fmt.Fprintf(in.w, "no data at 0x%08X-0x%08X\n", in.addr, min(uint64(in.addr)+in.pageSize(), 1<<32)-1)
+	}
+}
+
+func byteAt(segs []image.Segment, addr uint64) (byte, bool) {
+	for _, s := range segs {
+		if addr >= uint64(s.Address) && addr < s.End() {
+			return s.Data[addr-uint64(s.Address)], true
+		}
+	}
+	return 0, false
+}
+
+// find returns the address of the first occurrence of pat at or past
+// in.from, wrapping around to the lowest address
+func (in *inspector) find(pat []byte) (uint32, bool) {
+	var first *uint32
+	for _, s := range in.segs {
+		for off := 0; ; off++ {
+			i := bytes.Index(s.Data[off:], pat)
+			if i < 0 {
+				break
+			}
+			a := s.Address + uint32(off+i)
+			if a >= in.from {
+				return a, true
+			}
+			if first == nil {
+				first = &a
+			}
+			off += i
+		}
+	}
+	if first == nil {
+		return 0, false
+	}
+	return *first, true
+}
+
+// parsePattern parses the argument of the find command
+func parsePattern(arg string) ([]byte, error) {
+	if s, err := strconv.Unquote(arg); err == nil && s != "" {
+		return []byte(s), nil
+	}
+	b, err := hex.DecodeString(strings.Join(strings.Fields(arg), ""))
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("bad search pattern %q", arg)
+	}
+	return b, nil
+}
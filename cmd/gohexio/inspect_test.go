@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// newTestInspector returns an inspector of m writing to sb
+func newTestInspector(m *image.Image, sb *strings.Builder) *inspector {
+	return &inspector{m: m, segs: m.Segments(), rows: 2, w: sb}
+}
+
+func TestInspectCommands(t *testing.T) {
+	fmt.Println("TestInspectCommands()")
+
+	m := image.New()
+	m.Write(0x100, []byte("Hello, world"))
+	m.Write(0x8000, []byte{0xDE, 0xAD, 0xBE, 0xEF})
+
+	var sb strings.Builder
+	in := newTestInspector(m, &sb)
+
+	cases := []struct {
+		line string
+		addr uint32 // Address shown afterwards
+		out  string // Part of the output
+		more bool
+	}{
+		{"g 0x105", 0x100, "00000100  48 65 6C 6C", true},
+		{"n", 0x120, "no data at 0x00000120-0x0000013F", true},
+		{"p", 0x100, "|Hello, world", true},
+		{"g 0x10", 0x10, "no data", true},
+		{"p", 0, "no data", true},
+		{"p", 0, "no data", true}, // Stays at the bottom
+		{"s", 0, "  1  0x00008000-0x00008003  4 bytes", true},
+		{"s 1", 0x8000, "00008000  DE AD BE EF", true},
+		{"s 2", 0x8000, `no segment "2"`, true},
+		{"g nowhere", 0x8000, `bad address "nowhere"`, true},
+		{"r 0", 0x8000, `bad row count "0"`, true},
+		{"f 6C 6C", 0x100, "found at 0x00000102", true},
+		{"f 6C", 0x100, "found at 0x00000103", true},      // Continues past the last match
+		{`f "world"`, 0x100, "found at 0x00000107", true}, // Text
+		{"f DE AD", 0x8000, "found at 0x00008000", true},
+		{"f 6c 6c", 0x100, "found at 0x00000102", true}, // Wraps around
+		{"f 01 02", 0x100, "not found", true},
+		{"f zz", 0x100, `bad search pattern "zz"`, true},
+		{"x", 0x100, `unknown command "x"`, true},
+		{"?", 0x100, "g ADDR", true},
+		{"q", 0x100, "", false},
+	}
+	for _, c := range cases {
+		sb.Reset()
+		more := in.exec(c.line)
+		if more != c.more || in.addr != c.addr || !strings.Contains(sb.String(), c.out) {
+			fmt.Printf("%q: at 0x%X, %v, output %q\n", c.line, in.addr, more, sb.String())
+			t.Fail()
+		}
+	}
+}
+
+func TestInspectTop(t *testing.T) {
+	fmt.Println("TestInspectTop()")
+
+	m := image.New()
+	m.Write(0xFFFFFFF8, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+
+	var sb strings.Builder
+	in := newTestInspector(m, &sb)
+	in.exec("g 0xFFFFFFE0")
+
+	// Paging on stops at the last row instead of wrapping to 0
+	for range 3 {
+		in.exec("n")
+		if in.addr != 0xFFFFFFF0 || !strings.Contains(sb.String(), "FFFFFFF0  -- -- -- -- -- -- -- --  01 02") {
+			fmt.Printf("at 0x%X: %q\n", in.addr, sb.String())
+			t.Fail()
+		}
+		sb.Reset()
+	}
+	in.exec("p")
+	if in.addr != 0xFFFFFFD0 {
+		fmt.Printf("back at 0x%X\n", in.addr)
+		t.Fail()
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	fmt.Println("TestParsePattern()")
+
+	for _, c := range []struct {
+		arg, want string // want "" for an error
+	}{
+		{"DE AD BE EF", "\xDE\xAD\xBE\xEF"},
+		{"deadbeef", "\xDE\xAD\xBE\xEF"},
+		{`"boot"`, "boot"},
+		{`"a\x00b"`, "a\x00b"},
+		{`""`, ""},
+		{"", ""},
+		{"ABC", ""},
+		{"GG", ""},
+	} {
+		b, err := parsePattern(c.arg)
+		if string(b) != c.want || (err != nil) != (c.want == "") {
+			fmt.Printf("%q: %q, %v\n", c.arg, b, err)
+			t.Fail()
+		}
+	}
+}
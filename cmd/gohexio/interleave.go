@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func init() {
+	commands = append(commands,
+		&command{
+			name:    "deinterleave",
+			summary: "split an image into per-chip images for multi-ROM boards",
+			run:     runDeinterleave,
+		},
+		&command{
+			name:    "interleave",
+			summary: "merge per-chip images into one",
+			run:     runInterleave,
+		})
+}
+
+func runDeinterleave(args []string) error {
+	fs := flag.NewFlagSet("deinterleave", flag.ExitOnError)
+	chips := fs.Int("chips", 2, "number of chips")
+	stride := fs.Int("stride", 1, "bytes per chip and turn, e.g. 2 for 16-bit chips")
+	out := fs.String("o", "chip%d.hex", "output file name pattern; %d is replaced by the chip number")
+	format := fs.String("f", "", "output format; by default derived from the output name, else ihex")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return usageError("exactly one input file required")
+	}
+	if *chips < 1 || *stride < 1 {
+		return usageError("chips and stride must be at least 1")
+	}
+	if !strings.Contains(*out, "%d") {
+		return usageError("output name pattern lacks %d")
+	}
+
+	m, _, err := hexio.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for c, chip := range m.Deinterleave(*chips, *stride) {
+		if err := writeImage(fmt.Sprintf(*out, c), chip, image.Format(*format)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runInterleave(args []string) error {
+	fs := flag.NewFlagSet("interleave", flag.ExitOnError)
+	stride := fs.Int("stride", 1, "bytes per chip and turn, e.g. 2 for 16-bit chips")
+	out := fs.String("o", "-", "output file")
+	format := fs.String("f", "", "output format; by default derived from the output name, else ihex")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return usageError("chip image files required, in chip order")
+	}
+	if *stride < 1 {
+		return usageError("stride must be at least 1")
+	}
+
+	var chips []*image.Image
+	for _, fn := range fs.Args() {
+		m, _, err := hexio.Open(fn)
+		if err != nil {
+			return err
+		}
+		chips = append(chips, m)
+	}
+
+	m, err := image.Interleave(*stride, chips...)
+	if err != nil {
+		return err
+	}
+	return writeImage(*out, m, image.Format(*format))
+}
@@ -0,0 +1,131 @@
+// Command gohexio is a command line front end to the GoHexIO packages.
+//
+// Usage:
+//
+//	gohexio <command> [flags] [arguments]
+//
+// Run "gohexio <command> -h" for the flags of an individual command.
+//
+// Failures are reported on standard error as one diagnostic line each,
+//
+//	file:line: code: message
+//
+// with "-" for an unknown file and 0 for an unknown line.  The code
+// classifies the failure and selects the exit status:
+//
+//	parse     3  malformed input
+//	mismatch  4  verify found the inputs to differ
+//	overlap   5  inputs provide data for the same address
+//	protected 6  an input provides data for a protected region
+//	usage     2  bad command line
+//	error     1  anything else, such as I/O errors
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// A command is one gohexio subcommand
+type command struct {
+	name    string // Name used on the command line
+	summary string // One line description for usage output
+	run     func(args []string) error
+}
+
+// commands lists the subcommands in the order usage shows them
+var commands []*command
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gohexio <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				os.Exit(report(os.Stderr, err))
+			}
+			return
+		}
+	}
+
+	report(os.Stderr, usageError(fmt.Sprintf("unknown command %q", os.Args[1])))
+	usage()
+	os.Exit(exitUsage)
+}
+
+// stdout is standard output with a Close that leaves it open
+type stdout struct{ io.Writer }
+
+func (stdout) Close() error { return nil }
+
+// Create the named output file, or use standard output for "" or "-"
+func createOutput(fn string) (io.WriteCloser, error) {
+	if fn == "" || fn == "-" {
+		return stdout{os.Stdout}, nil
+	}
+	return os.Create(fn)
+}
+
+// Write m to the named output file in format f; "" selects the format
+// customary for the file name, Intel Hex if there is none
+func writeImage(fn string, m *image.Image, f image.Format, opts ...image.Option) error {
+	if f == "" {
+		var ok bool
+		if f, ok = hexio.FormatForName(fn); !ok {
+			f = image.IntelHex
+		}
+	}
+
+	w, err := createOutput(fn)
+	if err != nil {
+		return err
+	}
+	if err := m.Encode(w, f, opts...); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Open the named input file; "archive.zip!member" names a member of a
+// zip archive
+func openInput(fn string) (io.ReadCloser, error) {
+	return hexio.OpenInput(fn)
+}
+
+// Work out the format of the named file, such as "ihex" or "srec", from
+// its content.  Formats registered through hexio.RegisterCodec are
+// recognized as well.
+func detectFormat(fn string) (string, error) {
+	f, err := openInput(fn)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head, err := io.ReadAll(io.LimitReader(f, hexio.DetectSize))
+	if err != nil {
+		return "", err
+	}
+
+	format, err := hexio.Detect(head)
+	if err != nil {
+		return "", &hexio.ParseError{File: fn, Err: err}
+	}
+	return string(format), nil
+}
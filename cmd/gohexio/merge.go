@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "merge",
+		summary: "combine several files into one image, refusing overlaps",
+		run:     runMerge,
+	})
+}
+
+const mergeUsage = `usage: gohexio merge [-o output] [-f format] [-profile file] input[@offset]...
+
+Merges the data of the inputs, in any formats, into one image written in
+address order, as for a bootloader and application built separately.
+Unlike cat, inputs providing data for the same address are an error, as
+is data within the protected regions of the profile.  An input may be
+followed by @offset to move its data, e.g. app.bin@0x8000.  Formats are
+detected from the content, apart from raw binary, which is recognized
+by the .bin extension and loads at address 0 plus the offset.
+`
+
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	out := fs.String("o", "-", "output file")
+	format := fs.String("f", "", "output format; by default derived from the output name, else ihex")
+	profile := fs.String("profile", "", "region map of the device's protected regions")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), mergeUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return usageError("at least one input file required")
+	}
+
+	j := hexio.NewJob()
+	for _, arg := range fs.Args() {
+		fn, delta := inputOffset(arg)
+		in := hexio.Input{File: fn, Offset: delta}
+		if f, ok := hexio.FormatForName(fn); ok && f == image.Binary {
+			// Binary data cannot be detected
+			in.Format = f
+		}
+		j.Inputs = append(j.Inputs, in)
+	}
+	if *profile != "" {
+		p, err := hexio.LoadProfile(*profile)
+		if err != nil {
+			return err
+		}
+		j.Profile = p
+	}
+
+	m, err := j.Image()
+	if err != nil {
+		return err
+	}
+	return writeImage(*out, m, image.Format(*format))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "run",
+		summary: "execute a job file of inputs, filters and outputs",
+		run:     runJob,
+	})
+}
+
+func runJob(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	profile := fs.String("profile", "", "region map of the device's protected regions, added to the job's")
+	strict := fs.Bool("reject-overlaps", false, "fail if inputs provide data for the same address, instead of letting later ones win")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return usageError("exactly one job file required")
+	}
+
+	j, err := hexio.LoadJob(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	j.RejectOverlaps = *strict
+	if *profile != "" {
+		p, err := hexio.LoadProfile(*profile)
+		if err != nil {
+			return err
+		}
+		for _, r := range p.Protected {
+			j.Protect(r.Name, r.Start, r.Size)
+		}
+	}
+	return j.Run()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "script",
+		summary: "generate a flash programmer command script for a file",
+		run:     runScript,
+	})
+}
+
+func runScript(args []string) error {
+	fs := flag.NewFlagSet("script", flag.ExitOnError)
+	tool := fs.String("tool", "plain", "built-in template: "+strings.Join(hexio.ScriptTools(), ", "))
+	tmpl := fs.String("template", "", "custom template file, overriding -tool")
+	sector := fs.String("sector", "0", "flash erase sector size, e.g. 4K; 0 erases just the data")
+	start := fs.String("start", "", "execution start address; by default the file's own, if any")
+	profile := fs.String("profile", "", "region map of the device's protected regions, whose data is skipped; -tool openocd refuses such data")
+	out := fs.String("o", "-", "output file")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return usageError("exactly one input file required")
+	}
+	size, err := image.ParseSize(*sector)
+	if err != nil || size >= 1<<32 {
+		return usageError(fmt.Sprintf("bad sector size %q", *sector))
+	}
+
+	var t *template.Template
+	if *tmpl != "" {
+		text, err := os.ReadFile(*tmpl)
+		if err != nil {
+			return err
+		}
+		t, err = hexio.ParseScriptTemplate(string(text))
+		if err != nil {
+			return fmt.Errorf("%s: %v", *tmpl, err)
+		}
+	} else if t, err = hexio.ScriptTemplate(*tool); err != nil {
+		return usageError(err.Error())
+	}
+
+	var p *hexio.DeviceProfile
+	if *profile != "" {
+		if p, err = hexio.LoadProfile(*profile); err != nil {
+			return err
+		}
+	}
+
+	fn := fs.Arg(0)
+	m, _, err := hexio.Open(fn)
+	if err != nil {
+		return err
+	}
+	s, err := hexio.NewScript(fn, m, uint32(size), p)
+	if err != nil {
+		return err
+	}
+
+	if *start != "" {
+		a, err := strconv.ParseUint(*start, 0, 32)
+		if err != nil {
+			return usageError(fmt.Sprintf("bad start address %q", *start))
+		}
+		s.SetStart(uint32(a))
+	} else if a, ok, err := hexio.EntryPoint(fn); err != nil {
+		return err
+	} else if ok {
+		s.SetStart(a)
+	}
+
+	w, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+	if err := s.Execute(w, t); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "split",
+		summary: "break a file into numbered files within size or record limits",
+		run:     runSplit,
+	})
+}
+
+const splitUsage = `usage: gohexio split [-size n] [-records n] [-o pattern] [-f format] input
+
+Breaks the input into files that each stay within the given limits, for
+programmers and transfer protocols that accept files of a bounded size
+only: a file is completed once its next record would exceed them.  Every
+file is complete in itself; the files are numbered from 1 and hold the
+data in ascending address order.  Intel Hex and S-Record output can be
+split.
+`
+
+func runSplit(args []string) error {
+	fs := flag.NewFlagSet("split", flag.ExitOnError)
+	size := fs.String("size", "", "maximum bytes per file, e.g. 64K")
+	records := fs.Int("records", 0, "maximum records (lines) per file")
+	width := fs.Int("w", 0, "data bytes per record, 0 for the format's default")
+	out := fs.String("o", "part%d.hex", "output file name pattern; %d is replaced by the file number")
+	format := fs.String("f", "", "output format; by default derived from the output name, else ihex")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), splitUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return usageError("exactly one input file required")
+	}
+	if !strings.Contains(*out, "%d") {
+		return usageError("output name pattern lacks %d")
+	}
+
+	var l hexio.SplitLimits
+	if *size != "" {
+		n, err := image.ParseSize(*size)
+		if err != nil {
+			return usageError(fmt.Sprintf("bad size %q", *size))
+		}
+		l.MaxBytes = int64(n)
+	}
+	l.MaxRecords = *records
+	if l.MaxBytes == 0 && l.MaxRecords == 0 {
+		return usageError("a size or record limit required")
+	}
+
+	f := image.Format(*format)
+	if f == "" {
+		var ok bool
+		if f, ok = hexio.FormatForName(*out); !ok {
+			f = image.IntelHex
+		}
+	}
+
+	m, _, err := hexio.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	create := func(n int) (io.WriteCloser, error) {
+		return createOutput(fmt.Sprintf(*out, n))
+	}
+	_, err = hexio.SplitImage(m, f, l, create, image.WithWidth(*width))
+	return err
+}
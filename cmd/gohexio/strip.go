@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "strip",
+		summary: "remove headers, start addresses and other non-data records",
+		run:     runStrip,
+	})
+}
+
+const stripUsage = `usage: gohexio strip [-o output] [-f format] input
+
+Rewrites the input with its data alone, for loaders that accept nothing
+else and for scrubbing build metadata: headers, record counts and start
+addresses are dropped, and the fewest address records needed are used.
+The output is in the format of the input unless given.
+`
+
+func runStrip(args []string) error {
+	fs := flag.NewFlagSet("strip", flag.ExitOnError)
+	out := fs.String("o", "-", "output file")
+	format := fs.String("f", "", "output format; by default that of the input")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), stripUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return usageError("exactly one input file required")
+	}
+
+	m, f, err := hexio.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *format != "" {
+		f = image.Format(*format)
+	}
+	return writeImage(*out, m, f, image.WithDataOnly())
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "usage",
+		summary: "report how much of each memory region a file occupies",
+		run:     runUsage,
+	})
+}
+
+const usageUsage = `usage: gohexio usage -regions file [-max percent] input
+
+Reports, for each memory region, the bytes of the input's data within it
+and the share of the region they take.  The regions come from a GNU ld
+map file, such as written by ld -Map, or a region map listing them as
+linker script MEMORY entries or "name start size" lines.  Data outside
+all regions is reported as unmapped.
+`
+
+func runUsage(args []string) error {
+	fs := flag.NewFlagSet("usage", flag.ExitOnError)
+	regions := fs.String("regions", "", "linker map or region map file")
+	limit := fs.Float64("max", 0, "fail if a region is more than this percent full, 0 for no limit")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usageUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *regions == "" {
+		return usageError("a region file and exactly one input file required")
+	}
+
+	rs, err := hexio.LoadRegions(*regions)
+	if err != nil {
+		return err
+	}
+	m, _, err := hexio.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Region\tStart\tSize\tUsed\tFree\tUse\t")
+	var full []string
+	for _, u := range m.Usage(rs) {
+		fmt.Fprintf(tw, "%s\t0x%08X\t%d\t%d\t%d\t%.1f%%\t\n", u.Name, u.Start, u.Size, u.Used, u.Free(), u.Percent())
+		if *limit > 0 && u.Percent() > *limit {
+			full = append(full, u.Name)
+		}
+	}
+	var stray int
+	for _, s := range m.Unmapped(rs) {
+		stray += len(s.Data)
+	}
+	if stray > 0 {
+		fmt.Fprintf(tw, "unmapped\t\t\t%d\t\t\t\n", stray)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(full) > 0 {
+		return fmt.Errorf("%s: regions over %g%% full: %v", fs.Arg(0), *limit, full)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "validate",
+		summary: "list every bad record of Intel Hex or S-Record files",
+		run:     runValidate,
+	})
+}
+
+const validateUsage = `usage: gohexio validate [-strict] input...
+
+Checks every record of the inputs, Intel Hex or S-Records, reporting
+each bad one as a diagnostic line rather than stopping at the first, so
+a corrupted file can be repaired in one pass.
+`
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	strict := fs.Bool("strict", false, "also enforce the canonical record order")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), validateUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return usageError("at least one input file required")
+	}
+
+	var bad int
+	for _, fn := range fs.Args() {
+		n, err := validate(fn, *strict)
+		if err != nil {
+			return err
+		}
+		bad += n
+	}
+	if bad > 0 {
+		return &hexio.ParseError{Err: fmt.Errorf("%d problem(s) found", bad)}
+	}
+	return nil
+}
+
+// validate reports the problems of the named file, returning their
+// number
+func validate(fn string, strict bool) (int, error) {
+	format, err := detectFormat(fn)
+	if err != nil {
+		return 0, err
+	}
+	in, err := openInput(fn)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	var problems []error
+	switch image.Format(format) {
+	case image.IntelHex:
+		d := ihex.NewDecoder(in)
+		d.SetStrictOrder(strict)
+		_, errs, err := d.Validate()
+		if err != nil {
+			return 0, err
+		}
+		for _, e := range errs {
+			problems = append(problems, &hexio.ParseError{File: fn, Line: e.Line, Err: e.Reason})
+		}
+	case image.SRecord:
+		d := srec.NewDecoder(in)
+		d.SetStrictOrder(strict)
+		_, errs, err := d.Validate()
+		if err != nil {
+			return 0, err
+		}
+		for _, e := range errs {
+			problems = append(problems, &hexio.ParseError{File: fn, Line: e.Line, Err: e.Reason})
+		}
+	default:
+		return 0, usageError(fmt.Sprintf("%s: %s files have no records to validate", fn, format))
+	}
+
+	for _, p := range problems {
+		report(os.Stderr, p)
+	}
+	return len(problems), nil
+}
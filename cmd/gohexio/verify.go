@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "verify",
+		summary: "check that two files, in any formats, hold the same data",
+		run:     runVerify,
+	})
+}
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	profile := fs.String("profile", "", "region map of the device's protected regions, which are ignored")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		return usageError("exactly two input files required")
+	}
+
+	want, _, err := hexio.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	got, _, err := hexio.Open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	if *profile != "" {
+		p, err := hexio.LoadProfile(*profile)
+		if err != nil {
+			return err
+		}
+		want, got = p.Mask(want), p.Mask(got)
+	}
+
+	if err := image.RequireEqual(want, got); err != nil {
+		// The full report goes to standard output, the diagnostic only
+		// carries its first line
+		fmt.Println(err)
+		msg, _, _ := strings.Cut(err.Error(), "\n")
+		return &mismatchError{file: fs.Arg(1), msg: msg}
+	}
+	return nil
+}
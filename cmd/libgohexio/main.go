@@ -0,0 +1,242 @@
+//go:build cgo
+
+// Command libgohexio packages the GoHexIO formats as a C shared library,
+// so Python test benches, Electron flashing GUIs and other programs
+// outside Go can reuse them instead of reimplementing the formats:
+//
+//	go build -buildmode=c-shared -o libgohexio.so ./cmd/libgohexio
+//
+// The build also writes libgohexio.h, declaring
+//
+//	char *gohexio_parse(void *data, int n, char **out);
+//	char *gohexio_convert(void *data, int n, char *format, void **out, int *outlen);
+//	char *gohexio_verify(void *want, int wantlen, void *got, int gotlen, char **out);
+//	void gohexio_free(void *p);
+//
+// Inputs are whole files in any format the hexio package detects.  Each
+// function returns NULL on success and an error message otherwise, also
+// for NULL or negative arguments and for failures inside the library,
+// which never take the host process down.  Strings and buffers handed
+// out, error messages included, are allocated with malloc and must be
+// released with gohexio_free.
+//
+// gohexio_parse describes the data of a file as JSON,
+//
+//	{"format": "ihex", "segments": [{"address": 4096, "data": "48656c6c6f"}]}
+//
+// gohexio_convert re-encodes a file in the named format, e.g. "srec",
+// refusing output beyond 256 MiB, as binary output filling the gaps of
+// far apart data would take.  gohexio_verify compares the data of two
+// files, listing the runs of addresses at which they differ, [] if none:
+//
+//	[{"address": 4098, "length": 2}]
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/peteArnt/GoHexIO/hexio"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Largest output gohexio_convert hands out
+const maxOutput = 256 << 20
+
+// A segment as described by gohexio_parse
+type segment struct {
+	Address uint32 `json:"address"`
+	Data    string `json:"data"`
+}
+
+// A difference as listed by gohexio_verify
+type difference struct {
+	Address uint32 `json:"address"`
+	Length  int    `json:"length"`
+}
+
+var errNilOut = errors.New("NULL output argument")
+
+//export gohexio_parse
+func gohexio_parse(data unsafe.Pointer, n C.int, out **C.char) *C.char {
+	return cError(guard(func() error {
+		if out == nil {
+			return errNilOut
+		}
+		b, err := input(data, int(n))
+		if err != nil {
+			return err
+		}
+		desc, err := parse(b)
+		if err != nil {
+			return err
+		}
+		*out = C.CString(desc)
+		return nil
+	}))
+}
+
+//export gohexio_convert
+func gohexio_convert(data unsafe.Pointer, n C.int, format *C.char, out *unsafe.Pointer, outlen *C.int) *C.char {
+	return cError(guard(func() error {
+		if format == nil || out == nil || outlen == nil {
+			return errNilOut
+		}
+		b, err := input(data, int(n))
+		if err != nil {
+			return err
+		}
+		enc, err := convert(b, image.Format(C.GoString(format)))
+		if err != nil {
+			return err
+		}
+		*out, *outlen = C.CBytes(enc), C.int(len(enc))
+		return nil
+	}))
+}
+
+//export gohexio_verify
+func gohexio_verify(want unsafe.Pointer, wantlen C.int, got unsafe.Pointer, gotlen C.int, out **C.char) *C.char {
+	return cError(guard(func() error {
+		if out == nil {
+			return errNilOut
+		}
+		a, err := input(want, int(wantlen))
+		if err != nil {
+			return fmt.Errorf("want: %v", err)
+		}
+		b, err := input(got, int(gotlen))
+		if err != nil {
+			return fmt.Errorf("got: %v", err)
+		}
+		diffs, err := verify(a, b)
+		if err != nil {
+			return err
+		}
+		*out = C.CString(diffs)
+		return nil
+	}))
+}
+
+//export gohexio_free
+func gohexio_free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+// cError hands err to the caller as a message, NULL for none
+func cError(err error) *C.char {
+	if err == nil {
+		return nil
+	}
+	return C.CString(err.Error())
+}
+
+// guard runs f, turning a panic into an error, as a panic must not
+// unwind into the C caller
+func guard(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error: %v", r)
+		}
+	}()
+	return f()
+}
+
+// input returns the n bytes at data, which may be NULL only if n is 0
+func input(data unsafe.Pointer, n int) ([]byte, error) {
+	switch {
+	case n < 0:
+		return nil, fmt.Errorf("negative length %d", n)
+	case n == 0:
+		return nil, nil
+	case data == nil:
+		return nil, errors.New("NULL data")
+	}
+	return unsafe.Slice((*byte)(data), n), nil
+}
+
+// parse describes the data of the file b as JSON
+func parse(b []byte) (string, error) {
+	m, f, err := hexio.Decode(bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+
+	desc := struct {
+		Format   image.Format `json:"format"`
+		Segments []segment    `json:"segments"`
+	}{Format: f, Segments: []segment{}}
+	for _, s := range m.Segments() {
+		desc.Segments = append(desc.Segments, segment{s.Address, hex.EncodeToString(s.Data)})
+	}
+	return jsonString(desc)
+}
+
+// convert re-encodes the file b in format f
+func convert(b []byte, f image.Format) ([]byte, error) {
+	m, _, err := hexio.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	// Binary output spans the gaps; refuse it before filling them
+	if segs := m.Segments(); f == image.Binary && len(segs) > 0 {
+		if span := segs[len(segs)-1].End() - uint64(segs[0].Address); span > maxOutput {
+			return nil, fmt.Errorf("binary output of %d bytes exceeds the limit of %d", span, maxOutput)
+		}
+	}
+
+	out := &limitedBuffer{max: maxOutput}
+	if err := m.Encode(out, f); err != nil {
+		return nil, err
+	}
+	return out.buf.Bytes(), nil
+}
+
+// verify lists the differences between the data of the files want and
+// got as JSON
+func verify(want, got []byte) (string, error) {
+	a, _, err := hexio.Decode(bytes.NewReader(want))
+	if err != nil {
+		return "", fmt.Errorf("want: %v", err)
+	}
+	b, _, err := hexio.Decode(bytes.NewReader(got))
+	if err != nil {
+		return "", fmt.Errorf("got: %v", err)
+	}
+
+	diffs := []difference{}
+	for _, d := range image.Compare(a, b) {
+		diffs = append(diffs, difference{d.Address, d.Len()})
+	}
+	return jsonString(diffs)
+}
+
+func jsonString(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// limitedBuffer collects writes up to max bytes, failing beyond
+type limitedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > b.max {
+		return 0, fmt.Errorf("output exceeds the limit of %d bytes", b.max)
+	}
+	return b.buf.Write(p)
+}
+
+func main() {}
@@ -0,0 +1,111 @@
+//go:build cgo
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+const testHex = ":0500100048656C6C6FF7\n:00000001FF\n"
+
+func TestInput(t *testing.T) {
+	fmt.Println("TestInput()")
+
+	b := []byte("abc")
+	for _, c := range []struct {
+		data unsafe.Pointer
+		n    int
+		ok   bool
+	}{
+		{unsafe.Pointer(&b[0]), 3, true},
+		{nil, 0, true},
+		{unsafe.Pointer(&b[0]), -1, false},
+		{nil, 3, false},
+	} {
+		got, err := input(c.data, c.n)
+		if (err == nil) != c.ok || c.ok && len(got) != c.n {
+			fmt.Println(c.n, got, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestGuard(t *testing.T) {
+	fmt.Println("TestGuard()")
+
+	var m map[string]int
+	if err := guard(func() error { m["x"] = 1; return nil }); err == nil || !strings.Contains(err.Error(), "internal error") {
+		fmt.Println(err)
+		t.Fail()
+	}
+	if err := guard(func() error { return nil }); err != nil {
+		fmt.Println(err)
+		t.Fail()
+	}
+}
+
+func TestParse(t *testing.T) {
+	fmt.Println("TestParse()")
+
+	desc, err := parse([]byte(testHex))
+	if want := `{"format":"ihex","segments":[{"address":16,"data":"48656c6c6f"}]}`; err != nil || desc != want {
+		fmt.Println(desc, err)
+		t.Fail()
+	}
+	if _, err := parse([]byte("garbage")); err == nil {
+		fmt.Println("garbage parsed")
+		t.Fail()
+	}
+}
+
+func TestConvert(t *testing.T) {
+	fmt.Println("TestConvert()")
+
+	out, err := convert([]byte(testHex), "binary")
+	if err != nil || string(out) != "Hello" {
+		fmt.Printf("%q %v\n", out, err)
+		t.Fail()
+	}
+
+	// Far apart data would flatten to 512 MiB
+	sparse := ":0100000000FF\n:020000042000DA\n:0100000000FF\n:00000001FF\n"
+	if _, err := convert([]byte(sparse), "binary"); err == nil {
+		fmt.Println("oversized binary output accepted")
+		t.Fail()
+	}
+	if out, err := convert([]byte(sparse), "srec"); err != nil || !strings.HasPrefix(string(out), "S3") {
+		fmt.Printf("%q %v\n", out, err)
+		t.Fail()
+	}
+
+	b := &limitedBuffer{max: 4}
+	if _, err := b.Write([]byte("Hello")); err == nil {
+		fmt.Println("limit not enforced")
+		t.Fail()
+	}
+}
+
+func TestVerify(t *testing.T) {
+	fmt.Println("TestVerify()")
+
+	other := strings.Replace(testHex, ":0500100048656C6C6FF7", ":05001000486558586628", 1)
+	for _, c := range []struct {
+		got, want string
+	}{
+		{testHex, "[]"},
+		{other, `[{"address":18,"length":3}]`},
+	} {
+		diffs, err := verify([]byte(testHex), []byte(c.got))
+		if err != nil || diffs != c.want {
+			fmt.Println(diffs, err)
+			t.Fail()
+		}
+	}
+	if _, err := verify([]byte(testHex), []byte("garbage")); err == nil || !strings.HasPrefix(err.Error(), "got: ") {
+		fmt.Println(err)
+		t.Fail()
+	}
+}
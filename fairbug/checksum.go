@@ -0,0 +1,10 @@
+package fairbug
+
+// Fairbug checksum algorithm: the sum of all data nybbles, modulo 16
+func calcChecksum(b []byte) byte {
+	var cs byte
+	for _, v := range b {
+		cs += v>>4 + v&0x0F
+	}
+	return cs & 0x0F
+}
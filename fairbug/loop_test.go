@@ -0,0 +1,53 @@
+package fairbug
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestLoopback(t *testing.T) {
+	fmt.Println("TestLoopback()")
+
+	m := image.New()
+	m.Write(0x0100, []byte("0123456789ABCDEF"))
+	m.Write(0x0113, []byte{1, 2})
+	m.Write(0x0200, []byte{0xAA})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m); err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	m2, err := Decode(&buf)
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	// Blocks are padded out to BlockSize with 0xFF
+	want := append([]byte("0123456789ABCDEF"), 0xFF, 0xFF, 0xFF, 1, 2, 0xFF, 0xFF, 0xFF)
+	segs := m2.Segments()
+	if len(segs) != 2 || segs[0].Address != 0x100 || !bytes.Equal(segs[0].Data, want) {
+		fmt.Printf("failure: unexpected image %v\n", segs)
+		t.Fail()
+	}
+}
+
+func TestRunTogether(t *testing.T) {
+	fmt.Println("TestRunTogether()")
+
+	recs, err := ReadAll(strings.NewReader("S0010X00112233445566778*"))
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+	if len(recs) != 3 || recs[1].Address != 0x10 || recs[1].Data[7] != 0x77 {
+		fmt.Printf("bad records: %v\n", recs)
+		t.Fail()
+	}
+}
@@ -0,0 +1,152 @@
+// Package fairbug reads and writes the Fairchild Fairbug format.  A file
+// is a sequence of three kinds of record:
+//
+//	SAAAA              set the load address to AAAA
+//	XDDDDDDDDDDDDDDDDC 8 data bytes followed by a checksum nybble
+//	*                  end of file
+//
+// Records may be separated by line breaks or simply run together.  The
+// checksum is the sum of the 16 data nybbles, modulo 16.
+package fairbug
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// RecTyp indicates the type of Fairbug record
+type RecTyp byte
+
+// Enumerated Fairbug record types, named after their leading character
+const (
+	Address RecTyp = 'S'
+	Data    RecTyp = 'X'
+	End     RecTyp = '*'
+)
+
+// BlockSize is the fixed number of data bytes in every data record
+const BlockSize = 8
+
+// HexRec is a decoded Fairbug record.  Data records carry the address
+// they load at, tracked from the preceding address record.
+type HexRec struct {
+	Address    uint16
+	RecordType RecTyp
+	Data       []byte
+}
+
+// String is the idiomatic Go string-ize method
+func (r HexRec) String() string {
+	return fmt.Sprintf("Address: 0x%04X, Type: %c, content: %v",
+		r.Address, r.RecordType, r.Data)
+}
+
+// ReadAll reads Fairbug records from r up to and including the end of
+// file record and returns them in file order.
+func ReadAll(r io.Reader) ([]*HexRec, error) {
+	var (
+		hrecs []*HexRec
+		addr  uint16
+		br    = bufio.NewReader(r)
+	)
+
+	// Read exactly n hex characters
+	field := func(n int) (string, error) {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		return string(buf), nil
+	}
+
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			return nil, errors.New("Missing end of file record")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch RecTyp(c) {
+		case Address:
+			s, err := field(4)
+			if err != nil {
+				return nil, err
+			}
+			a, err := strconv.ParseUint(s, 16, 16)
+			if err != nil {
+				return nil, fmt.Errorf("Address field error: %s", err)
+			}
+			addr = uint16(a)
+			hrecs = append(hrecs, &HexRec{Address: addr, RecordType: Address})
+
+		case Data:
+			s, err := field(2*BlockSize + 1)
+			if err != nil {
+				return nil, err
+			}
+			data, err := hex.DecodeString(s[:2*BlockSize])
+			if err != nil {
+				return nil, fmt.Errorf("Data chars bad: %s", err)
+			}
+			cs, err := strconv.ParseUint(s[2*BlockSize:], 16, 4)
+			if err != nil {
+				return nil, fmt.Errorf("Checksum field error: %s", err)
+			}
+			if byte(cs) != calcChecksum(data) {
+				return nil, errors.New("Checksum error")
+			}
+			hrecs = append(hrecs, &HexRec{Address: addr, RecordType: Data, Data: data})
+			addr += BlockSize
+
+		case End:
+			hrecs = append(hrecs, &HexRec{Address: addr, RecordType: End})
+			return hrecs, nil
+
+		case ' ', '\t', '\r', '\n':
+			// record separators
+
+		default:
+			return nil, fmt.Errorf("Unknown Fairbug record type %q", c)
+		}
+	}
+}
+
+// ReadFile reads the Fairbug file named fn
+func ReadFile(fn string) ([]*HexRec, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadAll(f)
+}
+
+// Decode reads Fairbug records from r into a memory image
+func Decode(r io.Reader) (*image.Image, error) {
+	recs, err := ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := image.New()
+	for _, rec := range recs {
+		if rec.RecordType == Data {
+			m.Write(uint32(rec.Address), rec.Data)
+		}
+	}
+
+	return m, nil
+}
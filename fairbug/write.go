@@ -0,0 +1,152 @@
+package fairbug
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Writer implements a Fairchild Fairbug writer.  Data records always
+// hold exactly BlockSize bytes; a short final block is padded with the
+// fill byte.
+type Writer struct {
+	w       io.Writer    // Underlying writer object
+	addr    uint16       // Address of the next data record
+	fill    byte         // Pad value for short blocks
+	newAddr bool         // An address record is due before the next data record
+	fin     bool         // Close() has been called
+	fifo    bytes.Buffer // FIFO for writes
+}
+
+// NewWriter creates a new Fairbug writer starting at address 0 and
+// padding short blocks with 0xFF
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, fill: 0xFF, newAddr: true}
+}
+
+// SetAddress flushes any buffered data and sets the address of the next
+// data record
+func (x *Writer) SetAddress(a uint16) error {
+	if err := x.Flush(); err != nil {
+		return err
+	}
+	x.addr = a
+	x.newAddr = true
+	return nil
+}
+
+// SetFill sets the value used to pad a short final block
+func (x *Writer) SetFill(b byte) {
+	x.fill = b
+}
+
+func (x *Writer) emitBlock(p []byte) error {
+	if x.newAddr {
+		if _, err := fmt.Fprintf(x.w, "S%04X\n", x.addr); err != nil {
+			return err
+		}
+		x.newAddr = false
+	}
+
+	s := strings.ToUpper(hex.EncodeToString(p))
+	if _, err := fmt.Fprintf(x.w, "X%s%X\n", s, calcChecksum(p)); err != nil {
+		return err
+	}
+
+	x.addr += BlockSize
+	return nil
+}
+
+// Write is the idiomatic Go Write() method.  Residual data shorter than
+// a block is held until a follow-up Write(), Flush() or Close().
+func (x *Writer) Write(p []byte) (int, error) {
+	if x.fin {
+		return 0, errors.New("Writer closed")
+	}
+
+	x.fifo.Write(p)
+
+	for x.fifo.Len() >= BlockSize {
+		if err := x.emitBlock(x.fifo.Next(BlockSize)); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any data remaining in the FIFO as a padded block
+func (x *Writer) Flush() error {
+	if n := x.fifo.Len(); n > 0 {
+		block := make([]byte, BlockSize)
+		copy(block, x.fifo.Next(n))
+		for i := n; i < BlockSize; i++ {
+			block[i] = x.fill
+		}
+		return x.emitBlock(block)
+	}
+	return nil
+}
+
+// Close flushes buffered data and writes the end of file record.
+// Note: the underlying io.Writer is NOT closed
+func (x *Writer) Close() error {
+	if x.fin {
+		return errors.New("Writer already closed")
+	}
+	x.fin = true
+
+	if err := x.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(x.w, string(End))
+	return err
+}
+
+// Fairbug blocks have a fixed size, so Encode has no use for a width
+func init() {
+	image.RegisterEncoder(image.Fairbug, func(w io.Writer, m *image.Image, _ image.EncodeOptions) error {
+		return Encode(w, m)
+	})
+}
+
+// Encode writes the memory image m to w in Fairbug format.  Data is
+// emitted in BlockSize aligned blocks; bytes of a block not covered by
+// the image are set to 0xFF.  All data must lie below 64K.
+func Encode(w io.Writer, m *image.Image) error {
+	var (
+		x       = NewWriter(w)
+		next    uint64 // address following the last block emitted
+		started bool
+	)
+
+	for _, s := range m.Segments() {
+		if s.End() > 0x10000 {
+			return fmt.Errorf("segment at 0x%X exceeds the 16-bit address space", s.Address)
+		}
+
+		for blk := uint64(s.Address &^ (BlockSize - 1)); blk < s.End(); blk += BlockSize {
+			if started && blk < next {
+				continue // block shared with the previous segment
+			}
+			if !started || blk != next {
+				if err := x.SetAddress(uint16(blk)); err != nil {
+					return err
+				}
+			}
+			if _, err := x.Write(m.Extract(uint32(blk), BlockSize, 0xFF)); err != nil {
+				return err
+			}
+			next = blk + BlockSize
+			started = true
+		}
+	}
+
+	return x.Close()
+}
@@ -0,0 +1,3 @@
+module github.com/peteArnt/GoHexIO
+
+go 1.24
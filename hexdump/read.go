@@ -0,0 +1,136 @@
+// Package hexdump imports memory dump listings, such as those produced by
+// `hexdump -C` or `xxd`, into a memory image.  Each line consists of an
+// address, a run of hex bytes and an optional ASCII column:
+//
+//	00000000  48 65 6c 6c 6f 0a 00 00  00 00 00 00 00 00 00 00  |Hello...........|
+//	00000000: 4865 6c6c 6f0a 0000 0000 0000 0000 0000  Hello...........
+//
+// The `*` line hexdump uses to elide repeated lines is expanded, and a
+// trailing line holding nothing but an address is accepted.
+package hexdump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Line is one decoded line of a dump listing
+type Line struct {
+	Address uint32
+	Data    []byte
+}
+
+// Split a listing line into its address and hex byte fields
+func parseLine(s string) (*Line, error) {
+	// Address; xxd terminates it with a ':'
+	end := strings.IndexFunc(s, func(c rune) bool { return !isHexDigit(c) })
+	if end < 0 {
+		end = len(s)
+	}
+	if end == 0 {
+		return nil, errors.New("Missing address field")
+	}
+	addr, err := strconv.ParseUint(s[:end], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Address field error: %s", err)
+	}
+	rest := strings.TrimPrefix(s[end:], ":")
+
+	// Drop the ASCII column; hexdump -C brackets it with '|', xxd sets
+	// it off with two or more spaces.
+	if i := strings.IndexByte(rest, '|'); i >= 0 {
+		rest = rest[:i]
+	} else if i := strings.Index(strings.TrimLeft(rest, " \t"), "  "); i >= 0 {
+		rest = strings.TrimLeft(rest, " \t")[:i]
+	}
+
+	var data []byte
+	for _, f := range strings.Fields(rest) {
+		b, err := hex.DecodeString(f)
+		if err != nil {
+			return nil, fmt.Errorf("Data chars bad: %s", err)
+		}
+		data = append(data, b...)
+	}
+
+	return &Line{Address: uint32(addr), Data: data}, nil
+}
+
+func isHexDigit(c rune) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// ReadAll parses a dump listing from r.  Repeated lines elided with `*`
+// are reinstated, so the result holds every line of the original dump.
+func ReadAll(r io.Reader) ([]*Line, error) {
+	var (
+		lines  []*Line
+		repeat bool // previous line was a '*'
+	)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		s := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case s == "":
+			continue
+		case s == "*":
+			repeat = true
+			continue
+		}
+
+		l, err := parseLine(s)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", n, err)
+		}
+
+		if repeat && len(lines) > 0 {
+			prev := lines[len(lines)-1]
+			if len(prev.Data) == 0 {
+				return nil, fmt.Errorf("line %d: nothing to repeat", n)
+			}
+			for a := prev.Address + uint32(len(prev.Data)); a < l.Address; a += uint32(len(prev.Data)) {
+				lines = append(lines, &Line{Address: a, Data: prev.Data})
+			}
+		}
+		repeat = false
+
+		if len(l.Data) > 0 {
+			lines = append(lines, l)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// Decode parses a dump listing from r into a memory image
+func Decode(r io.Reader) (*image.Image, error) {
+	lines, err := ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := image.New()
+	for _, l := range lines {
+		m.Write(l.Address, l.Data)
+	}
+
+	return m, nil
+}
+
+// DecodeBytes is a convenience wrapper around Decode for in-memory listings
+func DecodeBytes(b []byte) (*image.Image, error) {
+	return Decode(bytes.NewReader(b))
+}
@@ -0,0 +1,50 @@
+package hexdump
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestHexdumpC(t *testing.T) {
+	fmt.Println("TestHexdumpC()")
+
+	listing := `00000000  48 65 6c 6c 6f 0a 00 00  00 00 00 00 00 00 00 00  |Hello...........|
+00000010  ff ff ff ff ff ff ff ff  ff ff ff ff ff ff ff ff  |................|
+*
+00000040  41 42                                             |AB|
+00000042
+`
+	m, err := DecodeBytes([]byte(listing))
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	if m.Len() != 0x42 {
+		fmt.Printf("expected 0x42 bytes, got %#x\n", m.Len())
+		t.Fail()
+	}
+	if !bytes.Equal(m.Extract(0x3E, 4, 0), []byte{0xFF, 0xFF, 'A', 'B'}) {
+		fmt.Println("failure: repeated lines not expanded")
+		t.Fail()
+	}
+}
+
+func TestXxd(t *testing.T) {
+	fmt.Println("TestXxd()")
+
+	listing := `00000000: 4865 6c6c 6f0a 0000 0000 0000 0000 0000  Hello...........
+00000010: cafe                                     ..
+`
+	m, err := DecodeBytes([]byte(listing))
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	if m.Len() != 18 || !bytes.Equal(m.Extract(0x10, 2, 0), []byte{0xCA, 0xFE}) {
+		fmt.Printf("failure: bad xxd import %v\n", m.Segments())
+		t.Fail()
+	}
+}
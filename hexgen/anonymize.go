@@ -0,0 +1,91 @@
+package hexgen
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+)
+
+// Anonymize copies the hex file read from r to w with the content of its
+// data records replaced by pseudo-random bytes drawn from seed, so the
+// structure of a proprietary file can be shared, say to reproduce a bug,
+// without its firmware.  Intel Hex and S-Record data records keep their
+// addresses and lengths and get valid checksums; all other records and
+// lines, such as headers, address, count and start records, are copied
+// unchanged, as are the case of the hex digits and the line endings.
+// Equal input and seed give equal output.  An integrity trailer no
+// longer matches the data.
+func Anonymize(w io.Writer, r io.Reader, seed int64) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	for n, line := range bytes.SplitAfter(content, []byte("\n")) {
+		body := bytes.TrimRight(line, "\r\n")
+		rec, err := anonymizeRecord(string(body), rng)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", n+1, err)
+		}
+		if _, err := io.WriteString(w, rec); err != nil {
+			return err
+		}
+		if _, err := w.Write(line[len(body):]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// anonymizeRecord returns rec with its data randomized if it is a data
+// record, otherwise unchanged
+func anonymizeRecord(rec string, rng *rand.Rand) (string, error) {
+	var (
+		prefix string
+		skip   int  // Bytes ahead of the data
+		twos   bool // Two's complement checksum, else one's complement
+	)
+	switch {
+	case strings.HasPrefix(rec, ":") && len(rec) >= 9 && rec[7:9] == "00":
+		prefix, skip, twos = ":", 4, true
+	case strings.HasPrefix(rec, "S1"), strings.HasPrefix(rec, "S2"), strings.HasPrefix(rec, "S3"):
+		prefix, skip = rec[:2], int(rec[1]-'0')+2
+	default:
+		return rec, nil
+	}
+
+	b, err := hex.DecodeString(rec[len(prefix):])
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(b) < skip+1:
+		return "", errors.New("record too short")
+	case twos && int(b[0]) != len(b)-5, !twos && int(b[0]) != len(b)-1:
+		return "", fmt.Errorf("byte count 0x%02X does not match the record", b[0])
+	}
+
+	for i := skip; i < len(b)-1; i++ {
+		b[i] = byte(rng.Intn(256))
+	}
+	var sum byte
+	for _, v := range b[:len(b)-1] {
+		sum += v
+	}
+	if twos {
+		b[len(b)-1] = -sum
+	} else {
+		b[len(b)-1] = ^sum
+	}
+
+	digits := strings.ToUpper(hex.EncodeToString(b))
+	if strings.ContainsAny(rec, "abcdef") {
+		digits = strings.ToLower(digits)
+	}
+	return prefix + digits, nil
+}
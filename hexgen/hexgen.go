@@ -0,0 +1,186 @@
+// Package hexgen generates synthetic, reproducible hex files: a chosen
+// number of blocks of pseudo-random data at given addresses, with gaps,
+// record widths and optional deliberate defects.  It is meant for
+// building fixtures for parsers, converters and flashers, and Anonymize
+// turns real files into shareable fixtures of the same structure.
+package hexgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+// Format selects the record format generated
+type Format int
+
+// Supported output formats
+const (
+	IntelHex Format = iota
+	SRecord
+)
+
+// DefectKind enumerates the deliberate defects that can be injected
+type DefectKind int
+
+// Defect kinds
+const (
+	BadChecksum    DefectKind = iota // Corrupt the checksum of a record
+	TruncateRecord                   // Cut a record short
+	DropTerminator                   // Omit the final (EOF/start) record
+	DropRecord                       // Omit a record
+	ShuffleRecords                   // Randomly reorder all records
+)
+
+// Defect is a deliberate defect applied to record number Record (counted
+// from 0, in file order).  Record is ignored for DropTerminator and
+// ShuffleRecords; Seed is only used by ShuffleRecords.
+type Defect struct {
+	Kind   DefectKind
+	Record int
+	Seed   int64
+}
+
+// Spec describes the file to generate
+type Spec struct {
+	Format  Format   // Output format
+	Base    uint32   // Address of the first block
+	Blocks  int      // Number of blocks of data; 0 means 1
+	Size    int      // Bytes per block
+	Gap     uint32   // Unused addresses between consecutive blocks
+	Width   int      // Data bytes per record; 0 selects the format default
+	Seed    int64    // Seed for the data; equal specs give equal files
+	Defects []Defect // Defects to inject
+}
+
+// Image returns the memory image described by spec
+func Image(spec Spec) *image.Image {
+	var (
+		m    = image.New()
+		rng  = rand.New(rand.NewSource(spec.Seed))
+		addr = spec.Base
+	)
+
+	blocks := spec.Blocks
+	if blocks == 0 {
+		blocks = 1
+	}
+
+	for i := 0; i < blocks; i++ {
+		data := make([]byte, spec.Size)
+		rng.Read(data)
+		m.Write(addr, data)
+		addr += uint32(spec.Size) + spec.Gap
+	}
+
+	return m
+}
+
+// Generate writes the file described by spec to w
+func Generate(w io.Writer, spec Spec) error {
+	var (
+		buf bytes.Buffer
+		m   = Image(spec)
+	)
+
+	switch spec.Format {
+	case IntelHex:
+		x := ihex.NewWriter(&buf)
+		if spec.Width > 0 {
+			x = ihex.NewWriterWidth(&buf, spec.Width)
+		}
+		if err := x.WriteImage(m); err != nil {
+			return err
+		}
+		if err := x.Close(); err != nil {
+			return err
+		}
+
+	case SRecord:
+		x := srec.NewWriter(&buf, srec.AddrModeFor(m))
+		if spec.Width > 0 {
+			x.SetWidth(spec.Width)
+		}
+		x.SetStartAddress(spec.Base)
+		if err := x.WriteImage(m); err != nil {
+			return err
+		}
+		if err := x.Close(); err != nil {
+			return err
+		}
+
+	default:
+		return errors.New("unknown format")
+	}
+
+	return Corrupt(w, &buf, spec.Defects...)
+}
+
+// Corrupt copies the hex file read from r to w, applying defects in
+// order.  Starting from a valid file it produces controlled corruptions
+// for negative testing of parsers and loaders.  Defects address records
+// by their position among the non-blank lines of the file at the time
+// the defect is applied.
+func Corrupt(w io.Writer, r io.Reader, defects ...Defect) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, l := range strings.Split(string(content), "\n") {
+		if l = strings.TrimRight(l, "\r"); l != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	for _, d := range defects {
+		switch d.Kind {
+		case DropTerminator:
+			if len(lines) > 0 {
+				lines = lines[:len(lines)-1]
+			}
+			continue
+		case ShuffleRecords:
+			rng := rand.New(rand.NewSource(d.Seed))
+			rng.Shuffle(len(lines), func(i, j int) { lines[i], lines[j] = lines[j], lines[i] })
+			continue
+		}
+
+		if d.Record < 0 || d.Record >= len(lines) {
+			return fmt.Errorf("defect targets record %d of %d", d.Record, len(lines))
+		}
+
+		rec := lines[d.Record]
+		switch d.Kind {
+		case BadChecksum:
+			last := rec[len(rec)-1]
+			if last == '0' {
+				last = '1'
+			} else {
+				last = '0'
+			}
+			lines[d.Record] = rec[:len(rec)-1] + string(last)
+		case TruncateRecord:
+			lines[d.Record] = rec[:len(rec)/2]
+		case DropRecord:
+			lines = append(lines[:d.Record], lines[d.Record+1:]...)
+		default:
+			return errors.New("unknown defect")
+		}
+	}
+
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(w, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
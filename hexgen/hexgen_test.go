@@ -0,0 +1,132 @@
+package hexgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/hexiotest"
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+func TestGenerate(t *testing.T) {
+	fmt.Println("TestGenerate()")
+
+	spec := Spec{Base: 0xFFF0, Blocks: 3, Size: 100, Gap: 0x1000, Width: 32, Seed: 7}
+
+	var a, b bytes.Buffer
+	Generate(&a, spec)
+	Generate(&b, spec)
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		fmt.Println("failure: output not reproducible")
+		t.Fail()
+	}
+
+	m, err := ihex.Decode(&a)
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+	hexiotest.AssertImagesEqual(t, Image(spec), m)
+
+	if len(m.Segments()) != 3 {
+		fmt.Println("failure: expected 3 segments")
+		t.Fail()
+	}
+}
+
+func TestDefects(t *testing.T) {
+	fmt.Println("TestDefects()")
+
+	spec := Spec{Format: SRecord, Size: 64, Defects: []Defect{{Kind: BadChecksum, Record: 2}}}
+
+	var buf bytes.Buffer
+	if err := Generate(&buf, spec); err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+	if _, err := srec.Decode(&buf); err == nil {
+		fmt.Println("failure: bad checksum not detected")
+		t.Fail()
+	}
+}
+
+func TestCorrupt(t *testing.T) {
+	fmt.Println("TestCorrupt()")
+
+	var valid, out bytes.Buffer
+	Generate(&valid, Spec{Size: 64})
+
+	err := Corrupt(&out, bytes.NewReader(valid.Bytes()),
+		Defect{Kind: DropTerminator}, Defect{Kind: DropRecord, Record: 0})
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	recs, err := ihex.ParseBytes(out.Bytes())
+	if err != nil || len(recs) != 3 {
+		fmt.Printf("failure: %d records, err=%v\n", len(recs), err)
+		t.Fail()
+	}
+
+	out.Reset()
+	Corrupt(&out, bytes.NewReader(valid.Bytes()), Defect{Kind: ShuffleRecords, Seed: 3})
+	if out.Len() != valid.Len() {
+		fmt.Println("failure: shuffle changed content")
+		t.Fail()
+	}
+}
+
+func TestAnonymize(t *testing.T) {
+	fmt.Println("TestAnonymize()")
+
+	for _, f := range []Format{IntelHex, SRecord} {
+		var in bytes.Buffer
+		Generate(&in, Spec{Format: f, Base: 0xFFF8, Blocks: 2, Size: 40, Gap: 7, Seed: 1})
+		src := strings.ReplaceAll(in.String(), "\n", "\r\n")
+
+		var a, b bytes.Buffer
+		if err := Anonymize(&a, strings.NewReader(src), 5); err != nil {
+			fmt.Println("\t", err)
+			t.FailNow()
+		}
+		Anonymize(&b, strings.NewReader(src), 5)
+		if a.String() != b.String() || a.Len() != len(src) || a.String() == src {
+			fmt.Printf("failure: %d output not reproducible or unchanged:\n%s", f, a.String())
+			t.Fail()
+		}
+
+		var want, got []image.Segment
+		if f == IntelHex {
+			recs, err := ihex.ParseBytes([]byte(src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			anon, err := ihex.ParseBytes(a.Bytes())
+			if err != nil || len(anon) != len(recs) {
+				fmt.Printf("failure: %d records, err=%v\n", len(anon), err)
+				t.FailNow()
+			}
+			want, got = ihex.NewFile(recs).Segments(), ihex.NewFile(anon).Segments()
+		} else {
+			m, err := srec.Decode(strings.NewReader(src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			anon, err := srec.Decode(&a)
+			if err != nil {
+				fmt.Println("\t", err)
+				t.FailNow()
+			}
+			want, got = m.Segments(), anon.Segments()
+		}
+		if len(got) != len(want) || got[0].Address != want[0].Address || len(got[1].Data) != len(want[1].Data) {
+			fmt.Printf("failure: layout %v, want %v\n", got, want)
+			t.Fail()
+		}
+	}
+}
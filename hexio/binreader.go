@@ -0,0 +1,113 @@
+package hexio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// ErrUnordered is returned by a BinaryReader for data at an address it
+// has already passed
+var ErrUnordered = errors.New("data not in ascending address order")
+
+// BinaryReader streams the data of a hex file as raw binary: the bytes
+// from the lowest address with data up to the highest, with the gaps in
+// between filled.  Intel Hex and S-Records are decoded record by record,
+// so the image is never held in memory and binary consumers such as
+// flash protocols, hashes or io.Copy to a device node can take hex files
+// directly.  This requires the data records to come in ascending address
+// order, as most tools write them; files that don't fail with
+// ErrUnordered, and must be read through Open instead.
+type BinaryReader struct {
+	r    SegmentReader
+	fill byte
+
+	seg     image.Segment // Data of the current record not yet read
+	pos     uint64        // Address of the next byte to read
+	started bool          // The first data has been seen
+	err     error         // Sticky error, io.EOF at the end of the data
+}
+
+// NewBinaryReader returns a BinaryReader of the hex file read from r, in
+// format f or, for "", the format Detect finds in it, filling gaps with
+// fill
+func NewBinaryReader(r io.Reader, f image.Format, fill byte) (*BinaryReader, error) {
+	if f == "" {
+		var err error
+		if r, f, err = sniff(r, ReadOptions{}); err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := CodecFor(f)
+	if err != nil {
+		return nil, err
+	}
+	return &BinaryReader{r: c.NewReader(r), fill: fill}, nil
+}
+
+// Base returns the lowest address with data, that of the first byte
+// read, reading ahead to the first data record if need be.  A file
+// without data fails with io.EOF.
+func (b *BinaryReader) Base() (uint32, error) {
+	if !b.started && !b.load() {
+		return 0, b.err
+	}
+	return uint32(b.pos), nil
+}
+
+// Offset returns the address of the next byte Read returns
+func (b *BinaryReader) Offset() uint64 {
+	return b.pos
+}
+
+// load makes the next data current if the current data is used up,
+// reporting whether there is any
+func (b *BinaryReader) load() bool {
+	for len(b.seg.Data) == 0 {
+		if b.err != nil {
+			return false
+		}
+		s, err := b.r.ReadSegment()
+		if err != nil {
+			b.err = err
+			return false
+		}
+		if !b.started {
+			b.pos, b.started = uint64(s.Address), true
+		}
+		if uint64(s.Address) < b.pos && len(s.Data) > 0 {
+			b.err = fmt.Errorf("%w: data at 0x%X after 0x%X", ErrUnordered, s.Address, b.pos-1)
+			return false
+		}
+		b.seg = s
+	}
+	return true
+}
+
+// Read reads the next bytes of the binary data
+func (b *BinaryReader) Read(p []byte) (int, error) {
+	var n int
+	for n < len(p) && b.load() {
+		if gap := uint64(b.seg.Address) - b.pos; gap > 0 {
+			k := int(min(gap, uint64(len(p)-n)))
+			for i := range p[n : n+k] {
+				p[n+i] = b.fill
+			}
+			n += k
+			b.pos += uint64(k)
+			continue
+		}
+		k := copy(p[n:], b.seg.Data)
+		n += k
+		b.pos += uint64(k)
+		b.seg.Address += uint32(k)
+		b.seg.Data = b.seg.Data[k:]
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, b.err
+}
@@ -0,0 +1,51 @@
+package hexio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestBinaryReader(t *testing.T) {
+	fmt.Println("TestBinaryReader()")
+
+	m := image.Generate(image.Incrementing, 0xFFF0, 0x30) // Crosses a 64K page
+	m.Write(0x10100, []byte{1, 2, 3})
+
+	for _, f := range []image.Format{image.IntelHex, image.SRecord} {
+		var enc bytes.Buffer
+		if err := m.Encode(&enc, f); err != nil {
+			t.Fatal(err)
+		}
+
+		br, err := NewBinaryReader(bytes.NewReader(enc.Bytes()), "", 0xEE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if base, err := br.Base(); base != 0xFFF0 || err != nil {
+			fmt.Printf("failure: %s base 0x%X, %v\n", f, base, err)
+			t.Fail()
+		}
+		want := m.Extract(0xFFF0, 0x10103-0xFFF0, 0xEE)
+		if err := iotest.TestReader(br, want); err != nil {
+			fmt.Println("failure:", f, err)
+			t.Fail()
+		}
+	}
+
+	const unordered = ":0100100001EE\n:0100000002FD\n:00000001FF\n"
+	br, err := NewBinaryReader(strings.NewReader(unordered), image.IntelHex, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(br); !errors.Is(err, ErrUnordered) || len(b) != 1 {
+		fmt.Printf("failure: read % X, %v\n", b, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,498 @@
+package hexio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/peteArnt/GoHexIO/fairbug"
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/plainhex"
+	"github.com/peteArnt/GoHexIO/signetics"
+	"github.com/peteArnt/GoHexIO/srec"
+	"github.com/peteArnt/GoHexIO/tekhex"
+	"github.com/peteArnt/GoHexIO/verilog"
+)
+
+// SegmentReader is the decoding side of a Codec
+type SegmentReader interface {
+	// ReadSegment returns the next run of data at its absolute address,
+	// or io.EOF at the end of the input
+	ReadSegment() (image.Segment, error)
+}
+
+// SegmentWriter is the encoding side of a Codec.  Segments may be
+// written in any order; Close completes the output, but does not close
+// the underlying io.Writer.
+type SegmentWriter interface {
+	WriteSegment(s image.Segment) error
+	Close() error
+}
+
+// Codec implements one serialization of memory images.  Packages adding
+// formats of their own, proprietary ones included, register a Codec with
+// RegisterCodec from their init function, which makes the format
+// available to Open, Copy, image.Encode and the auto-detection of the
+// gohexio command.
+type Codec interface {
+	// Detect reports whether head, the first bytes of an input, looks
+	// like this format.  head holds at least DetectSize bytes unless the
+	// input is shorter.
+	Detect(head []byte) bool
+
+	// NewReader returns a reader decoding the data of r
+	NewReader(r io.Reader) SegmentReader
+
+	// NewWriter returns a writer encoding to w
+	NewWriter(w io.Writer, o image.EncodeOptions) SegmentWriter
+}
+
+// DetectSize is the number of leading bytes of an input Detect looks at
+const DetectSize = 1024
+
+type registered struct {
+	f image.Format
+	c Codec
+}
+
+var (
+	codecsMu sync.RWMutex
+
+	// The built-in codecs, listed in reverse order of detection
+	codecs = []registered{
+		{image.Binary, wholeImage(image.Binary, decodeBinary, nil)},
+		{image.Verilog, wholeImage(image.Verilog, decodeVerilog, nil)},
+		{image.PlainHex, wholeImage(image.PlainHex, plainhex.Decode, firstLine(isPlainHex))},
+		{image.Fairbug, wholeImage(image.Fairbug, fairbug.Decode, firstLine(isFairbug))},
+		{image.TekHex, wholeImage(image.TekHex, tekhex.Decode,
+			firstLine(func(s string) bool { _, err := tekhex.ReadAll(strings.NewReader(s)); return err == nil }))},
+		{image.Signetics, wholeImage(image.Signetics, signetics.Decode,
+			firstLine(func(s string) bool { _, err := signetics.ReadAll(strings.NewReader(s)); return err == nil }))},
+		{image.SRecord, builtin{
+			detect: firstLine(func(s string) bool { _, err := srec.DecodeRecordString(s); return err == nil }),
+			reader: func(r io.Reader, o ReadOptions) SegmentReader {
+				return &srecReader{d: srec.NewDecoder(r, srec.WithDecoderLogger(o.Logger))}
+			},
+			writer: func(w io.Writer, o image.EncodeOptions) SegmentWriter {
+				// Without the top address up front, only 32-bit
+				// addresses are sure to reach all the data
+				mode := srec.Addr32
+				if o.End > 0 {
+					mode = srec.AddrModeReaching(o.End)
+				}
+				x := srec.NewWriter(w, mode)
+				if o.Width > 0 {
+					x.SetWidth(o.Width)
+				}
+				if !o.DataOnly {
+					x.SetStartAddress(o.Start)
+				}
+				return x
+			},
+		}},
+		{image.IntelHex, builtin{
+			detect: firstLine(func(s string) bool { _, err := ihex.DecodeRecordString(s); return err == nil }),
+			reader: func(r io.Reader, o ReadOptions) SegmentReader {
+				d := ihex.NewDecoder(r, ihex.WithDecoderLogger(o.Logger))
+				if o.Lenient {
+					d.SetLenient(ihex.Lenient)
+				}
+				return &ihexReader{d: d}
+			},
+			writer: func(w io.Writer, o image.EncodeOptions) SegmentWriter {
+				var opts []ihex.Option
+				if o.Width > 0 {
+					opts = append(opts, ihex.WithWidth(o.Width))
+				}
+				if o.HasStart && !o.DataOnly {
+					opts = append(opts, ihex.WithStartAddress(o.Start))
+				}
+				return ihex.NewWriter(w, opts...)
+			},
+		}},
+	}
+)
+
+// RegisterCodec makes format f available to Open, Copy and image.Encode.
+// Registering a format a second time replaces the earlier codec.  Detect
+// tries codecs in reverse order of registration, so codecs added by
+// other packages get to claim an input before the built-in ones.
+func RegisterCodec(f image.Format, c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	for i, r := range codecs {
+		if r.f == f {
+			codecs = append(codecs[:i], codecs[i+1:]...)
+			break
+		}
+	}
+	codecs = append(codecs, registered{f, c})
+
+	image.RegisterEncoder(f, func(w io.Writer, m *image.Image, o image.EncodeOptions) error {
+		x := c.NewWriter(w, o)
+		for s := range m.Regions() {
+			if err := x.WriteSegment(s); err != nil {
+				return err
+			}
+		}
+		return x.Close()
+	})
+}
+
+// CodecFor returns the codec registered for format f
+func CodecFor(f image.Format) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	for _, r := range codecs {
+		if r.f == f {
+			return r.c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown format %q", f)
+}
+
+// Detect returns the format of an input starting with head.  Binary
+// data cannot be told apart from anything else and is never detected.
+func Detect(head []byte) (image.Format, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	for i := len(codecs) - 1; i >= 0; i-- {
+		if codecs[i].c.Detect(head) {
+			return codecs[i].f, nil
+		}
+	}
+	return "", errors.New("unable to detect format")
+}
+
+// ReadOptions tune Open and Decode.  The zero value reads strictly,
+// without logging.
+type ReadOptions struct {
+	Logger *slog.Logger // Diagnostics of the Intel Hex and S-Record decoders, nil for none
+
+	// Lenient accepts Intel Hex input with the deviations of
+	// ihex.Lenient, such as comment lines, which detection skips too
+	Lenient bool
+}
+
+// ReadOption adjusts the ReadOptions of a single Open or Decode call
+type ReadOption func(*ReadOptions)
+
+// WithReadLogger routes the diagnostics of the Intel Hex and S-Record
+// decoders to l, so services can tell the logs of concurrent conversions
+// apart
+func WithReadLogger(l *slog.Logger) ReadOption {
+	return func(o *ReadOptions) { o.Logger = l }
+}
+
+// WithLenient accepts hand-edited and tool-mangled Intel Hex input; see
+// ReadOptions.Lenient
+func WithLenient() ReadOption {
+	return func(o *ReadOptions) { o.Lenient = true }
+}
+
+// Open reads the named input, which may be a zip archive member as for
+// OpenInput, into a memory image, detecting its format from its content.
+func Open(name string, opts ...ReadOption) (*image.Image, image.Format, error) {
+	in, err := OpenInput(name)
+	if err != nil {
+		return nil, "", err
+	}
+	defer in.Close()
+
+	m, f, err := Decode(in, opts...)
+	if err != nil {
+		return nil, f, inFile(err, name)
+	}
+	return m, f, nil
+}
+
+// Decode reads r into a memory image, detecting its format from its
+// content.  Malformed input is reported as a *ParseError.
+func Decode(r io.Reader, opts ...ReadOption) (*image.Image, image.Format, error) {
+	var o ReadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	br, f, err := sniff(r, o)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := CodecFor(f)
+	if err != nil {
+		return nil, "", err
+	}
+
+	m, err := readSegments(newReader(c, br, o))
+	if err != nil {
+		return nil, f, err
+	}
+	return m, f, nil
+}
+
+// sniff detects the format of r from its content, returning a reader
+// that still yields all of it.  Leading comment lines are skipped under
+// o.Lenient.
+func sniff(r io.Reader, o ReadOptions) (io.Reader, image.Format, error) {
+	br := bufio.NewReaderSize(r, DetectSize)
+	head, err := br.Peek(DetectSize)
+	if err != nil && err != io.EOF {
+		return nil, "", err
+	}
+	if o.Lenient {
+		head = skipComments(head)
+	}
+
+	f, err := Detect(head)
+	if err != nil {
+		return nil, "", &ParseError{Err: err}
+	}
+	return br, f, nil
+}
+
+// skipComments drops the blank lines and the lines starting with '#' or
+// ';' from the start of head
+func skipComments(head []byte) []byte {
+	for {
+		head = bytes.TrimLeft(head, " \t\r\n")
+		if len(head) == 0 || (head[0] != '#' && head[0] != ';') {
+			return head
+		}
+		_, head, _ = bytes.Cut(head, []byte("\n"))
+	}
+}
+
+// readSegments collects all the data of r into an image
+func readSegments(r SegmentReader) (*image.Image, error) {
+	m := image.New()
+	for s, err := range segments(r) {
+		if err != nil {
+			return nil, err
+		}
+		m.Write(s.Address, s.Data)
+	}
+	return m, nil
+}
+
+// builtin adapts the formats of the GoHexIO packages to Codec
+type builtin struct {
+	detect func(head []byte) bool
+	reader func(r io.Reader, o ReadOptions) SegmentReader
+	writer func(w io.Writer, o image.EncodeOptions) SegmentWriter
+}
+
+func (b builtin) Detect(head []byte) bool {
+	return b.detect != nil && b.detect(head)
+}
+
+func (b builtin) NewReader(r io.Reader) SegmentReader {
+	return b.reader(r, ReadOptions{})
+}
+
+// newReader returns the reader of codec c for r, configured by o if c is
+// a built-in codec
+func newReader(c Codec, r io.Reader, o ReadOptions) SegmentReader {
+	if b, ok := c.(builtin); ok {
+		return b.reader(r, o)
+	}
+	return c.NewReader(r)
+}
+
+func (b builtin) NewWriter(w io.Writer, o image.EncodeOptions) SegmentWriter {
+	if o.Trim {
+		return &trimWriter{SegmentWriter: b.writer(w, o), erase: o.Erase}
+	}
+	return b.writer(w, o)
+}
+
+// trimWriter drops the erased runs of each segment before passing it on
+type trimWriter struct {
+	SegmentWriter
+	erase byte
+}
+
+func (x *trimWriter) WriteSegment(s image.Segment) error {
+	m := image.New()
+	m.Write(s.Address, s.Data)
+	m.Trim(x.erase)
+	for s := range m.Regions() {
+		if err := x.SegmentWriter.WriteSegment(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wholeImage makes a codec for a format decoded and encoded as a whole
+func wholeImage(f image.Format, decode func(io.Reader) (*image.Image, error), detect func([]byte) bool) builtin {
+	return builtin{
+		detect: detect,
+		reader: func(r io.Reader, _ ReadOptions) SegmentReader { return &imageReader{r: r, decode: decode} },
+		writer: func(w io.Writer, o image.EncodeOptions) SegmentWriter {
+			return &imageWriter{dst: w, f: f, o: o, m: image.New()}
+		},
+	}
+}
+
+func decodeBinary(r io.Reader) (*image.Image, error) {
+	m := image.New()
+	_, err := m.LoadBinaryAt(0, r)
+	return m, err
+}
+
+// decodeVerilog reads a byte wide $readmemh file.  It is not detected:
+// such files are indistinguishable from plain hex.
+func decodeVerilog(r io.Reader) (*image.Image, error) {
+	return verilog.Decode(r, verilog.Options{})
+}
+
+// firstLine turns a check of the first non-blank line into a detector
+func firstLine(ok func(line string) bool) func([]byte) bool {
+	return func(head []byte) bool {
+		full := len(head) >= DetectSize
+		head = bytes.TrimLeft(head, " \t\r\n")
+		line, _, complete := bytes.Cut(head, []byte("\n"))
+		if !complete && full {
+			// The line is cut short; drop its last, partial field
+			if i := bytes.LastIndexAny(line, " \t"); i >= 0 {
+				line = line[:i]
+			}
+		}
+		return ok(strings.TrimSpace(string(line)))
+	}
+}
+
+// isFairbug recognizes the address record opening a Fairbug file
+func isFairbug(line string) bool {
+	if len(line) != 5 || line[0] != byte(fairbug.Address) {
+		return false
+	}
+	_, err := hex.DecodeString(line[1:])
+	return err == nil
+}
+
+// isPlainHex recognizes a line of hex bytes or @address markers
+func isPlainHex(line string) bool {
+	toks := strings.Fields(line)
+	for _, tok := range toks {
+		var err error
+		if a, ok := strings.CutPrefix(tok, "@"); ok {
+			_, err = strconv.ParseUint(a, 16, 32)
+		} else {
+			_, err = hex.DecodeString(tok)
+		}
+		if err != nil {
+			return false
+		}
+	}
+	return len(toks) > 0
+}
+
+// segments adapts a SegmentReader to a sequence
+func segments(r SegmentReader) iter.Seq2[image.Segment, error] {
+	return func(yield func(image.Segment, error) bool) {
+		for {
+			s, err := r.ReadSegment()
+			if err == io.EOF {
+				return
+			}
+			if !yield(s, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ihexReader streams the data records of Intel Hex input
+type ihexReader struct {
+	d *ihex.Decoder
+}
+
+func (r *ihexReader) ReadSegment() (image.Segment, error) {
+	for {
+		rec, err := r.d.Decode()
+		if err == io.EOF {
+			return image.Segment{}, err
+		}
+		if err != nil {
+			return image.Segment{}, &ParseError{Line: r.d.Line(), Err: err}
+		}
+		if addr, isData := r.d.Address(); isData && len(rec.Data) > 0 {
+			return image.Segment{Address: addr, Data: rec.Data}, nil
+		}
+	}
+}
+
+// srecReader streams the data records of S-Record input
+type srecReader struct {
+	d *srec.Decoder
+}
+
+func (r *srecReader) ReadSegment() (image.Segment, error) {
+	for {
+		rec, err := r.d.Decode()
+		if err == io.EOF {
+			return image.Segment{}, err
+		}
+		if err != nil {
+			return image.Segment{}, &ParseError{Line: r.d.Line(), Err: err}
+		}
+		if rec.RecordType.IsData() && len(rec.Data) > 0 {
+			return image.Segment{Address: rec.Address, Data: rec.Data}, nil
+		}
+	}
+}
+
+// imageReader decodes its input as a whole on first use
+type imageReader struct {
+	r      io.Reader
+	decode func(io.Reader) (*image.Image, error)
+	segs   []image.Segment
+	done   bool
+}
+
+func (r *imageReader) ReadSegment() (image.Segment, error) {
+	if !r.done {
+		r.done = true
+		m, err := r.decode(r.r)
+		if err != nil {
+			return image.Segment{}, &ParseError{Err: err}
+		}
+		r.segs = m.Segments()
+	}
+	if len(r.segs) == 0 {
+		return image.Segment{}, io.EOF
+	}
+	s := r.segs[0]
+	r.segs = r.segs[1:]
+	return s, nil
+}
+
+// imageWriter collects segments into an image and encodes it on Close
+type imageWriter struct {
+	dst io.Writer
+	f   image.Format
+	o   image.EncodeOptions
+	m   *image.Image
+}
+
+func (x *imageWriter) WriteSegment(s image.Segment) error {
+	x.m.Write(s.Address, s.Data)
+	return nil
+}
+
+func (x *imageWriter) Close() error {
+	return x.m.Encode(x.dst, x.f, image.WithWidth(x.o.Width), image.WithFill(x.o.Fill))
+}
@@ -0,0 +1,170 @@
+package hexio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestDetect(t *testing.T) {
+	fmt.Println("TestDetect()")
+
+	m := image.New()
+	m.Write(0x100, []byte("detect me, please"))
+
+	for _, f := range []image.Format{image.IntelHex, image.SRecord, image.Signetics, image.Fairbug, image.TekHex, image.PlainHex} {
+		var buf bytes.Buffer
+		if err := m.Encode(&buf, f); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := Detect(buf.Bytes()); got != f {
+			fmt.Printf("%s detected as %q (%v)\n", f, got, err)
+			t.Fail()
+		}
+	}
+
+	if _, err := Detect([]byte("\x7fELF\x02\x01")); err == nil {
+		fmt.Println("binary data detected")
+		t.Fail()
+	}
+}
+
+// upperCodec is a toy format: "UPPER " followed by the data at address 0
+// in upper case
+type upperCodec struct{}
+
+func (upperCodec) Detect(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("UPPER "))
+}
+
+func (upperCodec) NewReader(r io.Reader) SegmentReader {
+	return &imageReader{r: r, decode: func(r io.Reader) (*image.Image, error) {
+		b, err := io.ReadAll(r)
+		m := image.New()
+		m.Write(0, bytes.TrimPrefix(b, []byte("UPPER ")))
+		return m, err
+	}}
+}
+
+func (upperCodec) NewWriter(w io.Writer, o image.EncodeOptions) SegmentWriter {
+	return &upperWriter{w: w}
+}
+
+type upperWriter struct {
+	w io.Writer
+}
+
+func (x *upperWriter) WriteSegment(s image.Segment) error {
+	_, err := fmt.Fprintf(x.w, "UPPER %s", bytes.ToUpper(s.Data))
+	return err
+}
+
+func (x *upperWriter) Close() error { return nil }
+
+func TestRegisterCodec(t *testing.T) {
+	fmt.Println("TestRegisterCodec()")
+
+	const upper image.Format = "upper"
+	RegisterCodec(upper, upperCodec{})
+
+	m := image.New()
+	m.Write(0, []byte("hello"))
+
+	var buf bytes.Buffer
+	if err := m.Encode(&buf, upper); err != nil || buf.String() != "UPPER HELLO" {
+		fmt.Printf("encoded %q, %v\n", buf.String(), err)
+		t.Fail()
+	}
+
+	fn := filepath.Join(t.TempDir(), "fw.up")
+	os.WriteFile(fn, buf.Bytes(), 0644)
+
+	got, f, err := Open(fn)
+	if err != nil || f != upper || string(got.Extract(0, 5, 0)) != "HELLO" {
+		fmt.Printf("opened %q, %v\n", f, err)
+		t.Fail()
+	}
+
+	var out strings.Builder
+	if _, err := Copy(&out, image.IntelHex, strings.NewReader("UPPER A"), upper); err != nil ||
+		!strings.HasPrefix(out.String(), ":0100000041BE") {
+		fmt.Printf("copied %q, %v\n", out.String(), err)
+		t.Fail()
+	}
+}
+
+func TestTrimWriter(t *testing.T) {
+	fmt.Println("TestTrimWriter()")
+
+	c, err := CodecFor(image.IntelHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	x := c.NewWriter(&buf, image.EncodeOptions{Trim: true, Erase: 0xFF})
+	x.WriteSegment(image.Segment{Address: 0x100, Data: []byte{0xFF, 0xFF, 1, 2, 0xFF}})
+	x.WriteSegment(image.Segment{Address: 0x200, Data: bytes.Repeat([]byte{0xFF}, 32)})
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := readSegments(c.NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if segs := m.Segments(); len(segs) != 1 || segs[0].Address != 0x102 || len(segs[0].Data) != 2 {
+		fmt.Println("unexpected segments", segs)
+		t.Fail()
+	}
+}
+
+func TestSrecCodecAddrMode(t *testing.T) {
+	fmt.Println("TestSrecCodecAddrMode()")
+
+	c, err := CodecFor(image.SRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		end  uint64
+		want string
+	}{
+		{0, "S3"},
+		{0x104, "S1"},
+		{0x20000, "S2"},
+	} {
+		var buf bytes.Buffer
+		x := c.NewWriter(&buf, image.EncodeOptions{End: tc.end})
+		x.WriteSegment(image.Segment{Address: 0x100, Data: []byte{1, 2, 3, 4}})
+		if err := x.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(buf.String(), tc.want) {
+			fmt.Printf("end 0x%X: %q, want %s records\n", tc.end, buf.String(), tc.want)
+			t.Fail()
+		}
+	}
+}
+
+func TestOpenLenient(t *testing.T) {
+	fmt.Println("TestOpenLenient()")
+
+	fn := filepath.Join(t.TempDir(), "fw.hex")
+	os.WriteFile(fn, []byte("# built by hand\n; more notes\n  :0500100048656c6c6ff7 \n:00000001FF\n"), 0644)
+
+	if _, _, err := Open(fn); err == nil {
+		fmt.Println("comments accepted by default")
+		t.Fail()
+	}
+	m, f, err := Open(fn, WithLenient())
+	if err != nil || f != image.IntelHex || string(m.Extract(0x10, 5, 0)) != "Hello" {
+		fmt.Printf("lenient open: %q, %v\n", f, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,126 @@
+package hexio
+
+import (
+	"io"
+
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+// metadata is what Convert carries over besides the data
+type metadata struct {
+	header   []byte // S-Record header content, nil for none
+	start    uint32 // Execution start address
+	segment  bool   // start is an Intel Hex CS<<16 | IP start segment address
+	hasStart bool   // Whether start is known
+}
+
+// physical returns the start address as a linear address
+func (md metadata) physical() uint32 {
+	if md.segment {
+		return md.start>>16<<4 + md.start&0xFFFF
+	}
+	return md.start
+}
+
+// Convert reads a file in srcFormat from src and writes it to dst in
+// dstFormat, carrying over its metadata as far as the formats allow,
+// unlike Copy, which converts the data alone.  An empty srcFormat is
+// detected from the content.
+//
+// The data is placed at its absolute addresses in either format: Intel
+// Hex output gets Extended Linear Address records wherever it crosses
+// 64K, S-Record output the smallest address mode reaching both the data
+// and the start address.  The start address maps between Start Linear
+// Address and S7/S8/S9 records; a start segment address CS:IP becomes
+// the linear address (CS << 4) + IP, but stays a start segment record
+// when converting Intel Hex to itself.  Without a start address
+// S-Record output ends in a start record for address 0, as Encode
+// writes it.  The S0 header is kept by S-Record output and dropped by
+// Intel Hex output, which has no place for it, as are record counts.
+// Other formats convert the data alone.
+func Convert(dst io.Writer, dstFormat image.Format, src io.Reader, srcFormat image.Format) error {
+	if srcFormat == "" {
+		var err error
+		if src, srcFormat, err = sniff(src, ReadOptions{}); err != nil {
+			return err
+		}
+	}
+
+	var (
+		m   *image.Image
+		md  metadata
+		err error
+	)
+	switch srcFormat {
+	case image.IntelHex:
+		recs, err := ihex.ReadAll(src)
+		if err != nil {
+			return &ParseError{Err: err}
+		}
+		f := ihex.NewFile(recs)
+		m = f.Image()
+		if f.Start != nil {
+			md.start, md.hasStart = f.Start.Address, true
+			md.segment = f.Start.Kind == ihex.StartSegment
+		}
+	case image.SRecord:
+		recs, err := srec.ReadAll(src)
+		if err != nil {
+			return &ParseError{Err: err}
+		}
+		f := srec.NewFile(recs)
+		m = f.Image()
+		md.header = f.Header
+		md.start, md.hasStart = f.StartAddress()
+	default:
+		c, err := CodecFor(srcFormat)
+		if err != nil {
+			return err
+		}
+		if m, err = readSegments(c.NewReader(src)); err != nil {
+			return err
+		}
+	}
+
+	switch dstFormat {
+	case image.IntelHex:
+		x := ihex.NewWriter(dst)
+		if err = x.WriteImage(m); err != nil {
+			return err
+		}
+		switch {
+		case md.hasStart && md.segment && srcFormat == image.IntelHex:
+			err = x.WriteStartSegAddr(uint16(md.start>>16), uint16(md.start))
+		case md.hasStart:
+			err = x.WriteStartLinAddr(md.physical())
+		}
+		if err != nil {
+			return err
+		}
+		return x.Close()
+
+	case image.SRecord:
+		mode := srec.AddrModeFor(m)
+		switch start := md.physical(); {
+		case start >= 1<<24:
+			mode = srec.Addr32
+		case start >= 1<<16 && mode == srec.Addr16:
+			mode = srec.Addr24
+		}
+		x := srec.NewWriter(dst, mode)
+		if md.header != nil {
+			x.SetHeader(md.header)
+		}
+		x.SetStartAddress(md.physical())
+		if err := x.WriteImage(m); err != nil {
+			return err
+		}
+		return x.Close()
+	}
+	if md.hasStart {
+		return m.Encode(dst, dstFormat, image.WithStart(md.physical()))
+	}
+	return m.Encode(dst, dstFormat)
+}
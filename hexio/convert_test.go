@@ -0,0 +1,77 @@
+package hexio
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+func TestConvert(t *testing.T) {
+	fmt.Println("TestConvert()")
+
+	m := image.Generate(image.Incrementing, 0x0800FFF0, 0x20)
+
+	// S-Records with a header and a start address to Intel Hex and back
+	var s1 bytes.Buffer
+	x := srec.NewWriter(&s1, srec.Addr32)
+	x.SetHeader([]byte("app"))
+	x.SetStartAddress(0x08000101)
+	x.SetCountEmit()
+	x.WriteImage(m)
+	x.Close()
+
+	var h bytes.Buffer
+	if err := Convert(&h, image.IntelHex, bytes.NewReader(s1.Bytes()), ""); err != nil {
+		t.Fatal(err)
+	}
+	recs, err := ihex.ReadAll(bytes.NewReader(h.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := ihex.NewFile(recs)
+	if f.Start == nil || f.Start.Address != 0x08000101 || f.Start.Kind != ihex.StartLinear || len(f.Other) != 0 {
+		fmt.Printf("failure: start %v, other %v\n", f.Start, f.Other)
+		t.Fail()
+	}
+	if len(image.Compare(f.Image(), m)) > 0 {
+		fmt.Println("failure: Intel Hex data differs")
+		t.Fail()
+	}
+
+	var s2 bytes.Buffer
+	if err := Convert(&s2, image.SRecord, bytes.NewReader(h.Bytes()), image.IntelHex); err != nil {
+		t.Fatal(err)
+	}
+	sr, err := srec.ReadAll(bytes.NewReader(s2.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf := srec.NewFile(sr)
+	if start, ok := sf.StartAddress(); !ok || start != 0x08000101 || len(image.Compare(sf.Image(), m)) > 0 {
+		fmt.Printf("failure: round trip\n%s", s2.String())
+		t.Fail()
+	}
+
+	// A start segment address becomes linear in S-Records; a high one
+	// widens the address mode
+	const seg = ":0100000011EE\n:0400000310002000C9\n:00000001FF\n"
+	var s3 bytes.Buffer
+	if err := Convert(&s3, image.SRecord, strings.NewReader(seg), image.IntelHex); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(s3.String(), "S804012000da\n") {
+		fmt.Printf("failure: start segment address converted to\n%s", s3.String())
+		t.Fail()
+	}
+	var h2 bytes.Buffer
+	Convert(&h2, image.IntelHex, strings.NewReader(seg), image.IntelHex)
+	if h2.String() != seg {
+		fmt.Printf("failure: Intel Hex to itself\n%s", h2.String())
+		t.Fail()
+	}
+}
@@ -0,0 +1,283 @@
+package hexio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+// CopyOptions tune Copy.  The zero value copies sequentially with each
+// format's default record width.
+type CopyOptions struct {
+	Concurrent bool          // Decode and encode in separate goroutines
+	Buffer     int           // Records in flight when Concurrent; 0 means 64
+	Width      int           // Output data bytes per record; 0 for the format default
+	AddrMode   srec.AddrMode // S-Record output address mode; 0 means Addr32
+}
+
+// CopyOption adjusts the CopyOptions of a single Copy call
+type CopyOption func(*CopyOptions)
+
+// WithConcurrency runs decoding and encoding in separate goroutines
+// connected by a channel holding up to buffer records, overlapping I/O
+// and CPU work on large conversions.  buffer 0 selects a default.
+func WithConcurrency(buffer int) CopyOption {
+	return func(o *CopyOptions) {
+		o.Concurrent = true
+		o.Buffer = buffer
+	}
+}
+
+// WithWidth sets the number of data bytes per output record
+func WithWidth(n int) CopyOption {
+	return func(o *CopyOptions) { o.Width = n }
+}
+
+// WithAddrMode sets the address mode of S-Record output
+func WithAddrMode(m srec.AddrMode) CopyOption {
+	return func(o *CopyOptions) { o.AddrMode = m }
+}
+
+// Copy reads the data in srcFormat from src and writes it to dst in
+// dstFormat, returning the number of data bytes copied.  Intel Hex and
+// S-Records are streamed record by record, so the whole image is never
+// held in memory; other formats are decoded or encoded as a whole.
+// Contiguous data is re-blocked into records of the output width,
+// whatever the records it came in.  Records are re-encoded from their
+// data alone: start addresses and headers need Convert, unless the fast
+// path below keeps them.  Since the top address is not known up front,
+// S-Record output uses 32-bit addresses unless told otherwise with
+// WithAddrMode.
+//
+// Copying Intel Hex or S-Records to the same format without options
+// takes a fast path: every record is validated, but then passed through
+// as is, headers and start records included, instead of being decoded
+// and re-encoded.  Only blank lines are dropped and line endings
+// normalized to "\n", making such a copy nearly I/O-bound.  Any option,
+// WithConcurrency included, makes Copy re-encode the data instead.
+func Copy(dst io.Writer, dstFormat image.Format, src io.Reader, srcFormat image.Format, opts ...CopyOption) (int64, error) {
+	var o CopyOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if srcFormat == dstFormat && o == (CopyOptions{}) {
+		switch srcFormat {
+		case image.IntelHex, image.SRecord:
+			return passthrough(dst, src, srcFormat)
+		}
+	}
+
+	segs, err := segmentsOf(src, srcFormat)
+	if err != nil {
+		return 0, err
+	}
+	width := o.Width
+	if width == 0 {
+		width = max(recordWidths[dstFormat], 1)
+	}
+	segs = merged(segs, width*mergeRecords)
+	w, err := newSegmentWriter(dst, dstFormat, o)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	if o.Concurrent {
+		n, err = copyConcurrent(w, segs, o.Buffer)
+	} else {
+		n, err = copySegments(w, segs)
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, w.Close()
+}
+
+// passthrough copies the records of src to dst line by line after
+// validating them, returning the number of data bytes they hold
+func passthrough(dst io.Writer, src io.Reader, f image.Format) (int64, error) {
+	var (
+		s   = bufio.NewScanner(src)
+		w   = bufio.NewWriter(dst)
+		n   int64
+		num int
+	)
+
+	for s.Scan() {
+		num++
+		line := s.Text()
+		if line == "" {
+			continue
+		}
+
+		if f == image.IntelHex {
+			r, err := ihex.DecodeRecordString(line)
+			if err != nil {
+				return n, &ParseError{Line: num, Err: err}
+			}
+			if r.RecordType == ihex.Data {
+				n += int64(len(r.Data))
+			}
+		} else {
+			r, err := srec.DecodeRecordString(line)
+			if err != nil {
+				return n, &ParseError{Line: num, Err: err}
+			}
+			if r.RecordType.IsData() {
+				n += int64(len(r.Data))
+			}
+		}
+
+		w.WriteString(line)
+		if err := w.WriteByte('\n'); err != nil {
+			return n, err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return n, err
+	}
+
+	return n, w.Flush()
+}
+
+// Records' worth of data merged at most before it is written
+const mergeRecords = 256
+
+// merged joins the contiguous segments of segs, so the writer blocks the
+// data into records of its own width rather than those of the input.
+// No more than limit bytes are held; with limit a multiple of the record
+// width, the pieces of a longer run still make up full records.
+func merged(segs iter.Seq2[image.Segment, error], limit int) iter.Seq2[image.Segment, error] {
+	return func(yield func(image.Segment, error) bool) {
+		var run image.Segment
+		for s, err := range segs {
+			if err != nil {
+				yield(image.Segment{}, err)
+				return
+			}
+			if len(run.Data) > 0 && uint64(s.Address) != run.End() {
+				if !yield(run, nil) {
+					return
+				}
+				run = image.Segment{}
+			}
+			if len(run.Data) == 0 {
+				run = image.Segment{Address: s.Address, Data: make([]byte, 0, limit)}
+			}
+
+			for data := s.Data; len(data) > 0; {
+				n := min(len(data), limit-len(run.Data))
+				run.Data = append(run.Data, data[:n]...)
+				data = data[n:]
+				if len(run.Data) == limit {
+					if !yield(run, nil) {
+						return
+					}
+					run = image.Segment{Address: run.Address + uint32(limit), Data: make([]byte, 0, limit)}
+				}
+			}
+		}
+		if len(run.Data) > 0 {
+			yield(run, nil)
+		}
+	}
+}
+
+func copySegments(w SegmentWriter, segs iter.Seq2[image.Segment, error]) (int64, error) {
+	var n int64
+	for s, err := range segs {
+		if err != nil {
+			return n, err
+		}
+		if err := w.WriteSegment(s); err != nil {
+			return n, err
+		}
+		n += int64(len(s.Data))
+	}
+	return n, nil
+}
+
+// copyConcurrent decodes in a separate goroutine, handing segments over
+// through a channel of the given capacity
+func copyConcurrent(w SegmentWriter, segs iter.Seq2[image.Segment, error], buffer int) (int64, error) {
+	type item struct {
+		s   image.Segment
+		err error
+	}
+
+	if buffer <= 0 {
+		buffer = 64
+	}
+
+	var (
+		ch   = make(chan item, buffer)
+		done = make(chan struct{})
+	)
+
+	go func() {
+		defer close(ch)
+		for s, err := range segs {
+			select {
+			case ch <- item{s, err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// Stop the decoder and wait for it to finish, so it no longer
+	// touches src once Copy has returned
+	defer func() {
+		close(done)
+		for range ch {
+		}
+	}()
+
+	var n int64
+	for it := range ch {
+		if it.err != nil {
+			return n, it.err
+		}
+		if err := w.WriteSegment(it.s); err != nil {
+			return n, err
+		}
+		n += int64(len(it.s.Data))
+	}
+	return n, nil
+}
+
+// segmentsOf returns the data held in src as a sequence of segments at
+// absolute addresses, decoding the record formats incrementally
+func segmentsOf(src io.Reader, f image.Format) (iter.Seq2[image.Segment, error], error) {
+	c, err := CodecFor(f)
+	if err != nil {
+		return nil, fmt.Errorf("unknown source format %q", f)
+	}
+	return segments(c.NewReader(src)), nil
+}
+
+func newSegmentWriter(dst io.Writer, f image.Format, o CopyOptions) (SegmentWriter, error) {
+	if f == image.SRecord && o.AddrMode != 0 {
+		x := srec.NewWriter(dst, o.AddrMode)
+		if o.Width > 0 {
+			x.SetWidth(o.Width)
+		}
+		x.SetStartAddress(0)
+		return x, nil
+	}
+
+	c, err := CodecFor(f)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewWriter(dst, image.EncodeOptions{Width: o.Width, Fill: 0xFF}), nil
+}
@@ -0,0 +1,139 @@
+package hexio
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+func TestCopy(t *testing.T) {
+	fmt.Println("TestCopy()")
+
+	m := image.Generate(image.PRNG(1), 0x0800FF00, 200000)
+
+	var hex bytes.Buffer
+	if err := ihex.Encode(&hex, m); err != nil {
+		fmt.Println(err)
+		t.FailNow()
+	}
+
+	for _, opts := range [][]CopyOption{nil, {WithConcurrency(4)}} {
+		var out bytes.Buffer
+		n, err := Copy(&out, image.SRecord, bytes.NewReader(hex.Bytes()), image.IntelHex, opts...)
+		if err != nil || n != int64(m.Len()) {
+			fmt.Println(n, err)
+			t.FailNow()
+		}
+
+		got, err := srec.Decode(&out)
+		if err != nil {
+			fmt.Println(err)
+			t.FailNow()
+		}
+		if err := image.RequireEqual(m, got); err != nil {
+			fmt.Println(err)
+			t.Fail()
+		}
+	}
+}
+
+func TestCopyDecodeError(t *testing.T) {
+	fmt.Println("TestCopyDecodeError()")
+
+	src := ":0400000001020304F2\n:04000400FFFFFFFF00\n"
+	for _, opts := range [][]CopyOption{nil, {WithConcurrency(1)}} {
+		var out bytes.Buffer
+		if _, err := Copy(&out, image.Binary, strings.NewReader(src), image.IntelHex, opts...); err == nil {
+			fmt.Println("bad checksum not reported")
+			t.Fail()
+		}
+	}
+}
+
+func TestCopyPassthrough(t *testing.T) {
+	fmt.Println("TestCopyPassthrough()")
+
+	src := "S00600004844521B\r\nS1060010010203E3\r\n\r\nS9030000FC\r\n"
+	var out bytes.Buffer
+	n, err := Copy(&out, image.SRecord, strings.NewReader(src), image.SRecord)
+	if err != nil || n != 3 {
+		fmt.Println(n, err)
+		t.FailNow()
+	}
+	if want := "S00600004844521B\nS1060010010203E3\nS9030000FC\n"; out.String() != want {
+		fmt.Printf("got %q\n", out.String())
+		t.Fail()
+	}
+
+	// Options call for re-encoding, which copies the data only
+	out.Reset()
+	if n, err := Copy(&out, image.SRecord, strings.NewReader(src), image.SRecord, WithConcurrency(1)); err != nil || n != 3 ||
+		strings.HasPrefix(out.String(), "S0") {
+		fmt.Printf("got %q, %d, %v\n", out.String(), n, err)
+		t.Fail()
+	}
+
+	bad := strings.Replace(src, "E3", "E2", 1)
+	if _, err := Copy(&out, image.SRecord, strings.NewReader(bad), image.SRecord); err == nil || !strings.Contains(err.Error(), "line 2") {
+		fmt.Println("bad checksum passed through:", err)
+		t.Fail()
+	}
+}
+
+func TestCopyWidth(t *testing.T) {
+	fmt.Println("TestCopyWidth()")
+
+	// 16-byte Intel Hex records, then a gap
+	m := image.Generate(image.Incrementing, 0x1000, 1001)
+	m.Write(0x2000, []byte{1, 2, 3})
+	var hex bytes.Buffer
+	if err := ihex.Encode(&hex, m); err != nil {
+		fmt.Println(err)
+		t.FailNow()
+	}
+
+	for _, c := range []struct {
+		opts  []CopyOption
+		width int
+	}{
+		{nil, 10},
+		{[]CopyOption{WithWidth(32)}, 32},
+		{[]CopyOption{WithWidth(32), WithConcurrency(2)}, 32},
+	} {
+		var out bytes.Buffer
+		if _, err := Copy(&out, image.SRecord, bytes.NewReader(hex.Bytes()), image.IntelHex, c.opts...); err != nil {
+			fmt.Println(err)
+			t.FailNow()
+		}
+		recs, err := srec.ReadAll(bytes.NewReader(out.Bytes()))
+		if err != nil {
+			fmt.Println(err)
+			t.FailNow()
+		}
+
+		// Every record is full but the last of each run
+		var widths []int
+		for _, r := range recs {
+			if r.RecordType.IsData() {
+				widths = append(widths, len(r.Data))
+			}
+		}
+		full := 1001 / c.width
+		if len(widths) != full+2 || widths[full] != 1001%c.width || widths[full+1] != 3 {
+			fmt.Printf("width %d: records of %v bytes\n", c.width, widths)
+			t.Fail()
+		}
+		for _, n := range widths[:full] {
+			if n != c.width {
+				fmt.Printf("width %d: records of %v bytes\n", c.width, widths)
+				t.Fail()
+				break
+			}
+		}
+	}
+}
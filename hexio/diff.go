@@ -0,0 +1,78 @@
+package hexio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Diff compares images a and b, such as two builds of a firmware,
+// returning in ascending order the runs of addresses at which they
+// differ along with the bytes of each.  An address holding data in one
+// image only differs as well.
+func Diff(a, b *image.Image) []image.Difference {
+	return image.Compare(a, b)
+}
+
+// DiffFiles is Diff of the named files, in any formats, detected from
+// their content
+func DiffFiles(a, b string) ([]image.Difference, error) {
+	ma, _, err := Open(a)
+	if err != nil {
+		return nil, err
+	}
+	mb, _, err := Open(b)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(ma, mb), nil
+}
+
+// WriteDiff renders diffs to w in the manner of a hexdump: each run is
+// headed by its address range, followed by rows of the old bytes, from
+// a, marked '-' and the new bytes, from b, marked '+', with an ASCII
+// gutter.  Rows cover 16 byte aligned addresses, so they line up with a
+// hexdump of the binary; bytes outside the run are left blank and bytes
+// without data show as "--".
+func WriteDiff(w io.Writer, diffs []image.Difference) error {
+	bw := bufio.NewWriter(w)
+	for _, d := range diffs {
+		end := uint64(d.Address) + uint64(d.Len())
+		fmt.Fprintf(bw, "0x%08X-0x%08X (%d bytes)\n", d.Address, end-1, d.Len())
+
+		for row := uint64(d.Address) &^ 15; row < end; row += 16 {
+			lo := max(row, uint64(d.Address)) - uint64(d.Address)
+			hi := min(row+16, end) - uint64(d.Address)
+			pad := int(max(row, uint64(d.Address)) - row)
+			fmt.Fprintf(bw, "-%08X  %s\n", row, diffRow(pad, d.A[lo:hi], d.InA[lo:hi]))
+			fmt.Fprintf(bw, "+%08X  %s\n", row, diffRow(pad, d.B[lo:hi], d.InB[lo:hi]))
+		}
+	}
+	return bw.Flush()
+}
+
+// diffRow renders the bytes of one row, the first pad positions of which
+// lie before the run, in hex followed by an ASCII gutter
+func diffRow(pad int, b []byte, present []bool) string {
+	var hx, asc strings.Builder
+
+	hx.WriteString(strings.Repeat("   ", pad))
+	asc.WriteString(strings.Repeat(" ", pad))
+	for i, v := range b {
+		switch {
+		case !present[i]:
+			hx.WriteString("-- ")
+			asc.WriteByte(' ')
+		case v >= 0x20 && v < 0x7F:
+			fmt.Fprintf(&hx, "%02X ", v)
+			asc.WriteByte(v)
+		default:
+			fmt.Fprintf(&hx, "%02X ", v)
+			asc.WriteByte('.')
+		}
+	}
+	return fmt.Sprintf("%-48s |%-16s|", hx.String(), asc.String())
+}
@@ -0,0 +1,60 @@
+package hexio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestDiff(t *testing.T) {
+	fmt.Println("TestDiff()")
+
+	dir := t.TempDir()
+	a, b := image.New(), image.New()
+	a.Write(0x100, []byte("firmware v1.0 build"))
+	b.Write(0x100, []byte("firmware v1.1 build"))
+	b.Write(0x200, []byte{0xEE})
+
+	fa, fb := filepath.Join(dir, "a.hex"), filepath.Join(dir, "b.s19")
+	for fn, m := range map[string]*image.Image{fa: a, fb: b} {
+		f := image.IntelHex
+		if strings.HasSuffix(fn, ".s19") {
+			f = image.SRecord
+		}
+		var sb strings.Builder
+		if err := m.Encode(&sb, f); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fn, []byte(sb.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diffs, err := DiffFiles(fa, fb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 2 || diffs[0].Address != 0x10C || diffs[0].Len() != 1 || diffs[1].InA[0] {
+		fmt.Printf("failure: differences %v\n", diffs)
+		t.Fail()
+	}
+
+	var sb strings.Builder
+	if err := WriteDiff(&sb, diffs); err != nil {
+		t.Fatal(err)
+	}
+	want := "0x0000010C-0x0000010C (1 bytes)\n" +
+		"-00000100                                      30           |            0   |\n" +
+		"+00000100                                      31           |            1   |\n" +
+		"0x00000200-0x00000200 (1 bytes)\n" +
+		"-00000200  --                                               |                |\n" +
+		"+00000200  EE                                               |.               |\n"
+	if sb.String() != want {
+		fmt.Printf("failure: rendered\n%s", sb.String())
+		t.Fail()
+	}
+}
@@ -0,0 +1,76 @@
+package hexio
+
+import (
+	"debug/elf"
+	"fmt"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// ELFSymbol returns the range of memory the named symbol of the ELF file
+// fn occupies in the images built from it, such as a calibration table.
+// Symbols of initialized data living at a run address other than their
+// load address, as .data copied to RAM at startup, are translated to the
+// load address through the program headers, that being where an image
+// holds their content.
+func ELFSymbol(fn, symbol string) (image.Region, error) {
+	f, err := elf.Open(fn)
+	if err != nil {
+		return image.Region{}, err
+	}
+	defer f.Close()
+
+	r, err := elfSymbol(f, symbol)
+	if err != nil {
+		return image.Region{}, fmt.Errorf("%s: %v", fn, err)
+	}
+	return r, nil
+}
+
+func elfSymbol(f *elf.File, symbol string) (image.Region, error) {
+	syms, err := f.Symbols()
+	if err != nil {
+		return image.Region{}, err
+	}
+
+	var found []elf.Symbol
+	for _, s := range syms {
+		if s.Name == symbol && s.Section != elf.SHN_UNDEF {
+			found = append(found, s)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		return image.Region{}, fmt.Errorf("no symbol %q", symbol)
+	case len(found) > 1:
+		return image.Region{}, fmt.Errorf("%d symbols named %q", len(found), symbol)
+	case found[0].Size == 0:
+		return image.Region{}, fmt.Errorf("symbol %q has no size", symbol)
+	}
+
+	addr, size := found[0].Value, found[0].Size
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_LOAD && addr >= p.Vaddr && addr-p.Vaddr < p.Filesz {
+			addr = addr - p.Vaddr + p.Paddr
+			break
+		}
+	}
+	if addr >= 1<<32 || size > 1<<32-addr || size >= 1<<32 {
+		return image.Region{}, fmt.Errorf("symbol %q at 0x%X exceeds the 32-bit address space", symbol, addr)
+	}
+	return image.Region{Name: symbol, Start: uint32(addr), Size: uint32(size)}, nil
+}
+
+// ExtractSymbol returns the data of m covering the named symbol of the
+// ELF file fn, located as by ELFSymbol.  It fails unless m provides
+// every byte of it.
+func ExtractSymbol(m *image.Image, fn, symbol string) ([]byte, error) {
+	r, err := ELFSymbol(fn, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if n := m.Crop(r.Start, r.Size).Len(); n != int(r.Size) {
+		return nil, fmt.Errorf("image holds %d of the %d bytes of %s at 0x%08X", n, r.Size, symbol, r.Start)
+	}
+	return m.Extract(r.Start, int(r.Size), 0), nil
+}
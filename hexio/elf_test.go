@@ -0,0 +1,95 @@
+package hexio
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Minimal 32-bit ELF executable with a symbol table holding cal, an
+// 8 byte object run at 0x20000010 and loaded at 0x08000010, and tiny, a
+// symbol without size
+func testELF() []byte {
+	const (
+		ehsize = 52
+		phsize = 32
+		shsize = 40
+		symOff = ehsize + phsize
+		strOff = symOff + 3*16
+		strtab = "\x00cal\x00tiny\x00"
+		shstr  = "\x00.symtab\x00.strtab\x00.shstrtab\x00"
+		shOff  = strOff + len(strtab) + len(shstr)
+	)
+
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	binary.Write(&b, le, elf.Header32{
+		Ident:     [16]byte{0x7F, 'E', 'L', 'F', byte(elf.ELFCLASS32), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT)},
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(elf.EM_ARM),
+		Version:   uint32(elf.EV_CURRENT),
+		Phoff:     ehsize,
+		Shoff:     uint32(shOff),
+		Ehsize:    ehsize,
+		Phentsize: phsize,
+		Phnum:     1,
+		Shentsize: shsize,
+		Shnum:     4,
+		Shstrndx:  3,
+	})
+	binary.Write(&b, le, elf.Prog32{Type: uint32(elf.PT_LOAD), Vaddr: 0x20000000, Paddr: 0x08000000,
+		Filesz: 0x100, Memsz: 0x100})
+	binary.Write(&b, le, []elf.Sym32{
+		{},
+		{Name: 1, Value: 0x20000010, Size: 8, Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_OBJECT), Shndx: uint16(elf.SHN_ABS)},
+		{Name: 5, Value: 0x20000020, Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_OBJECT), Shndx: uint16(elf.SHN_ABS)},
+	})
+	b.WriteString(strtab)
+	b.WriteString(shstr)
+	binary.Write(&b, le, []elf.Section32{
+		{},
+		{Name: 1, Type: uint32(elf.SHT_SYMTAB), Off: symOff, Size: 3 * 16, Link: 2, Info: 1, Entsize: 16},
+		{Name: 9, Type: uint32(elf.SHT_STRTAB), Off: strOff, Size: uint32(len(strtab))},
+		{Name: 17, Type: uint32(elf.SHT_STRTAB), Off: uint32(strOff + len(strtab)), Size: uint32(len(shstr))},
+	})
+	return b.Bytes()
+}
+
+func TestExtractSymbol(t *testing.T) {
+	fmt.Println("TestExtractSymbol()")
+
+	fn := filepath.Join(t.TempDir(), "app.elf")
+	if err := os.WriteFile(fn, testELF(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := ELFSymbol(fn, "cal")
+	if err != nil || r != (image.Region{Name: "cal", Start: 0x08000010, Size: 8}) {
+		fmt.Println("failure:", r, err)
+		t.FailNow()
+	}
+
+	m := image.Generate(image.Incrementing, 0x08000000, 0x18)
+	data, err := ExtractSymbol(m, fn, "cal")
+	if err != nil || !bytes.Equal(data, []byte{16, 17, 18, 19, 20, 21, 22, 23}) {
+		fmt.Printf("failure: % X, %v\n", data, err)
+		t.Fail()
+	}
+
+	for _, sym := range []string{"tiny", "missing"} {
+		if _, err := ExtractSymbol(m, fn, sym); err == nil {
+			fmt.Println("failure: extracted", sym)
+			t.Fail()
+		}
+	}
+	if _, err := ExtractSymbol(image.Generate(image.Incrementing, 0x08000000, 0x14), fn, "cal"); err == nil {
+		fmt.Println("failure: partly covered symbol extracted")
+		t.Fail()
+	}
+}
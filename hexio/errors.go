@@ -0,0 +1,63 @@
+package hexio
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// ParseError reports malformed input
+type ParseError struct {
+	File string // Name of the input, "" if unknown
+	Line int    // Line of the offending record, 0 if unknown
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+	case e.File != "":
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// OverlapError reports data provided for the same address twice
+type OverlapError struct {
+	File    string // Name of the input providing the data again
+	Address uint32 // First address concerned
+}
+
+func (e *OverlapError) Error() string {
+	return fmt.Sprintf("%s: data at 0x%08X overlaps an earlier input", e.File, e.Address)
+}
+
+// inFile attributes err, which arose reading the input name, to it
+func inFile(err error, name string) error {
+	var pe *ParseError
+	if errors.As(err, &pe) && pe.File == "" {
+		pe.File = name
+		return err
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// ProtectedError reports data provided for a protected region of the
+// device
+type ProtectedError struct {
+	File    string       // Name of the input providing the data
+	Region  image.Region // Region concerned
+	Address uint32       // First address concerned
+}
+
+func (e *ProtectedError) Error() string {
+	return fmt.Sprintf("%s: data at 0x%08X falls within protected region %s", e.File, e.Address, e.Region.Name)
+}
@@ -0,0 +1,39 @@
+package hexio
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Follow returns a reader of r that, like tail -f, waits for more data at
+// the end of the input instead of returning io.EOF, checking every
+// interval.  It suits files still being written by another process, such
+// as hex captures from a data logger.  Once ctx is done, reads fail with
+// ctx.Err().
+func Follow(ctx context.Context, r io.Reader, interval time.Duration) io.Reader {
+	return &follower{ctx: ctx, r: r, interval: interval}
+}
+
+type follower struct {
+	ctx      context.Context
+	r        io.Reader
+	interval time.Duration
+}
+
+func (f *follower) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		if n > 0 || err != nil && err != io.EOF {
+			return n, err
+		}
+
+		t := time.NewTimer(f.interval)
+		select {
+		case <-f.ctx.Done():
+			t.Stop()
+			return 0, f.ctx.Err()
+		case <-t.C:
+		}
+	}
+}
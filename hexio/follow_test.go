@@ -0,0 +1,54 @@
+package hexio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// growingFile is a file another goroutine appends to
+type growingFile struct {
+	mu   sync.Mutex
+	data []byte
+	off  int
+}
+
+func (f *growingFile) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.off == len(f.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[f.off:])
+	f.off += n
+	return n, nil
+}
+
+func (f *growingFile) append(s string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data = append(f.data, s...)
+}
+
+func TestFollow(t *testing.T) {
+	fmt.Println("TestFollow()")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &growingFile{data: []byte("first ")}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		f.append("second")
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	b, err := io.ReadAll(Follow(ctx, f, time.Millisecond))
+	if string(b) != "first second" || !errors.Is(err, context.Canceled) {
+		fmt.Printf("read %q, %v\n", b, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,7 @@
+// Package hexio ties the individual format packages together: it offers
+// the operations that span formats, such as sizing, copying and
+// converting memory images between Intel Hex, S-Records and the other
+// supported formats.  Importing hexio makes every format of the GoHexIO
+// packages available to image.Encode, and through RegisterCodec further
+// formats can be plugged in.
+package hexio
@@ -0,0 +1,77 @@
+package hexio
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// OpenInput opens the named input for reading.  Besides plain files it
+// accepts members of zip archives, the way firmware releases are commonly
+// distributed, named as "release.zip!firmware.hex".  The member name is
+// the path within the archive; if no member has that exact path, a
+// unique member with that base name is used.
+func OpenInput(name string) (io.ReadCloser, error) {
+	i := strings.Index(strings.ToLower(name), ".zip!")
+	if i < 0 {
+		return os.Open(name)
+	}
+	archive, member := name[:i+4], name[i+5:]
+
+	z, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := findMember(z.File, member)
+	if err != nil {
+		z.Close()
+		return nil, fmt.Errorf("%s: %v", archive, err)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		z.Close()
+		return nil, err
+	}
+	return &zipMember{rc, z}, nil
+}
+
+func findMember(files []*zip.File, name string) (*zip.File, error) {
+	for _, f := range files {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+
+	var match *zip.File
+	for _, f := range files {
+		if f.Name[strings.LastIndex(f.Name, "/")+1:] == name {
+			if match != nil {
+				return nil, fmt.Errorf("member name %s is ambiguous", name)
+			}
+			match = f
+		}
+	}
+
+	if match == nil {
+		return nil, fmt.Errorf("no member %s", name)
+	}
+	return match, nil
+}
+
+// zipMember closes the archive along with the member
+type zipMember struct {
+	io.ReadCloser
+	z *zip.ReadCloser
+}
+
+func (m *zipMember) Close() error {
+	err := m.ReadCloser.Close()
+	if zerr := m.z.Close(); err == nil {
+		err = zerr
+	}
+	return err
+}
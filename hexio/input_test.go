@@ -0,0 +1,54 @@
+package hexio
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenInput(t *testing.T) {
+	fmt.Println("TestOpenInput()")
+
+	archive := filepath.Join(t.TempDir(), "release.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	for _, name := range []string{"bin/app.hex", "boot.hex", "a/dup.hex", "b/dup.hex", "a/fw.hex", "b/fw.hex", "fw.hex"} {
+		w, _ := z.Create(name)
+		io.WriteString(w, name)
+	}
+	z.Close()
+	f.Close()
+
+	cases := []struct {
+		member, want string
+	}{
+		{"bin/app.hex", "bin/app.hex"},
+		{"app.hex", "bin/app.hex"},
+		{"boot.hex", "boot.hex"},
+		{"dup.hex", ""},
+		{"fw.hex", "fw.hex"}, // Exact match after ambiguous base names
+		{"missing.hex", ""},
+	}
+	for _, c := range cases {
+		rc, err := OpenInput(archive + "!" + c.member)
+		if err != nil {
+			if c.want != "" {
+				fmt.Println(c.member, err)
+				t.Fail()
+			}
+			continue
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(b) != c.want {
+			fmt.Printf("%s: got %q, want %q\n", c.member, b, c.want)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package hexio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+	"github.com/peteArnt/GoHexIO/internal/atomicfile"
+)
+
+// Job is a batch description in the manner of srec_cat: its inputs are
+// merged into one image, which passes through the filters in order and
+// is then written to every output.
+type Job struct {
+	Inputs  []Input
+	Filters []Filter
+	Outputs []Output
+
+	// Profile describes the target device, nil for none.  Inputs must
+	// not provide data within its protected regions, and whatever the
+	// filters place there, such as fill bytes, is dropped.
+	Profile *DeviceProfile
+
+	// RejectOverlaps makes inputs providing data for the same address
+	// fail with an OverlapError; by default later inputs overwrite
+	// earlier ones
+	RejectOverlaps bool
+
+	// Dir is the directory relative file names are resolved against,
+	// "" for the working directory
+	Dir string
+}
+
+// Input is a file read by a Job
+type Input struct {
+	File   string       // File name, possibly a zip member as for OpenInput
+	Format image.Format // Format of the file, "" to detect it
+	Offset int64        // Distance the data is moved by when loaded
+}
+
+// Output is a file written by a Job
+type Output struct {
+	File   string       // File name
+	Format image.Format // Format of the file, "" to derive it from the extension
+	Width  int          // Data bytes per record, 0 for the format default
+
+	// DataOnly leaves out headers, start addresses and the like, as
+	// image.WithDataOnly does
+	DataOnly bool
+}
+
+// Filter is one transformation step of a Job
+type Filter interface {
+	Apply(m *image.Image) (*image.Image, error)
+}
+
+// CropFilter keeps only the data within [Start, Start+Size)
+type CropFilter struct {
+	Start, Size uint32
+}
+
+func (f CropFilter) Apply(m *image.Image) (*image.Image, error) {
+	return m.Crop(f.Start, f.Size), nil
+}
+
+// FillFilter sets the bytes within [Start, Start+Size) not covered by
+// data to Value, or to the values of Pattern if given
+type FillFilter struct {
+	Start, Size uint32
+	Value       byte
+	Pattern     image.Pattern
+}
+
+func (f FillFilter) Apply(m *image.Image) (*image.Image, error) {
+	if uint64(f.Start)+uint64(f.Size) > 1<<32 {
+		return nil, fmt.Errorf("fill range at 0x%X exceeds the 32-bit address space", f.Start)
+	}
+	p := f.Pattern
+	if p == nil {
+		p = image.Repeat(f.Value)
+	}
+	m.Fill(f.Start, f.Size, p)
+	return m, nil
+}
+
+// OffsetFilter moves all data by Delta bytes
+type OffsetFilter struct {
+	Delta int64
+}
+
+func (f OffsetFilter) Apply(m *image.Image) (*image.Image, error) {
+	return offset(m, f.Delta)
+}
+
+// offset returns a copy of m with all data moved by delta bytes
+func offset(m *image.Image, delta int64) (*image.Image, error) {
+	out := image.New()
+	for s := range m.Regions() {
+		addr := int64(s.Address) + delta
+		if addr < 0 || addr+int64(len(s.Data)) > 1<<32 {
+			return nil, fmt.Errorf("offset %d moves data at 0x%X out of the 32-bit address space",
+				delta, s.Address)
+		}
+		out.Write(uint32(addr), s.Data)
+	}
+	return out, nil
+}
+
+// CRC32Filter stores the IEEE CRC-32 of [Start, Start+Size) at Address,
+// least significant byte first, as srec_cat's -crc32-l-e does.  Bytes of
+// the range not covered by data count as 0xFF.
+type CRC32Filter struct {
+	Address     uint32
+	Start, Size uint32
+}
+
+func (f CRC32Filter) Apply(m *image.Image) (*image.Image, error) {
+	sum := crc32.ChecksumIEEE(m.Extract(f.Start, int(f.Size), 0xFF))
+	m.Write(f.Address, binary.LittleEndian.AppendUint32(nil, sum))
+	return m, nil
+}
+
+// NewJob returns an empty job, to be assembled step by step in the
+// manner of an srec_cat command line:
+//
+//	err := hexio.NewJob().
+//		Input("boot.hex").
+//		InputFormat("app.bin", image.Binary).
+//		Crop(0x08000000, 512<<10).
+//		CRC32(0x0807FFFC, 0x08000000, 0x7FFFC).
+//		Output("flash.srec", "").
+//		Run()
+func NewJob() *Job {
+	return new(Job)
+}
+
+// Input adds an input file whose format is detected from its content
+func (j *Job) Input(file string) *Job {
+	return j.InputFormat(file, "")
+}
+
+// InputFormat adds an input file in format f
+func (j *Job) InputFormat(file string, f image.Format) *Job {
+	j.Inputs = append(j.Inputs, Input{File: file, Format: f})
+	return j
+}
+
+// Filter appends a filter step, such as one of a custom type
+func (j *Job) Filter(f Filter) *Job {
+	j.Filters = append(j.Filters, f)
+	return j
+}
+
+// Crop appends a CropFilter step
+func (j *Job) Crop(start, size uint32) *Job {
+	return j.Filter(CropFilter{Start: start, Size: size})
+}
+
+// Fill appends a FillFilter step
+func (j *Job) Fill(start, size uint32, value byte) *Job {
+	return j.Filter(FillFilter{Start: start, Size: size, Value: value})
+}
+
+// FillPattern appends a FillFilter step filling with pattern p
+func (j *Job) FillPattern(start, size uint32, p image.Pattern) *Job {
+	return j.Filter(FillFilter{Start: start, Size: size, Pattern: p})
+}
+
+// Offset appends an OffsetFilter step
+func (j *Job) Offset(delta int64) *Job {
+	return j.Filter(OffsetFilter{Delta: delta})
+}
+
+// CRC32 appends a CRC32Filter step
+func (j *Job) CRC32(addr, start, size uint32) *Job {
+	return j.Filter(CRC32Filter{Address: addr, Start: start, Size: size})
+}
+
+// Protect marks [start, start+size) as a protected region of the job's
+// device profile
+func (j *Job) Protect(name string, start, size uint32) *Job {
+	if j.Profile == nil {
+		j.Profile = new(DeviceProfile)
+	}
+	j.Profile.Protect(name, start, size)
+	return j
+}
+
+// Output adds an output file in format f, "" to derive it from the
+// file name extension
+func (j *Job) Output(file string, f image.Format) *Job {
+	j.Outputs = append(j.Outputs, Output{File: file, Format: f})
+	return j
+}
+
+// Image merges the inputs and passes the result through the filters,
+// returning the image the outputs would be written from
+func (j *Job) Image() (*image.Image, error) {
+	if len(j.Inputs) == 0 {
+		return nil, errors.New("job has no inputs")
+	}
+
+	m := image.New()
+	for _, in := range j.Inputs {
+		if err := j.load(m, in); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, f := range j.Filters {
+		var err error
+		if m, err = f.Apply(m); err != nil {
+			return nil, fmt.Errorf("filter %d: %w", i+1, err)
+		}
+	}
+	return j.Profile.Mask(m), nil
+}
+
+// Run executes the job
+func (j *Job) Run() error {
+	m, err := j.Image()
+	if err != nil {
+		return err
+	}
+
+	for _, out := range j.Outputs {
+		if err := j.store(m, out); err != nil {
+			return fmt.Errorf("%s: %w", out.File, err)
+		}
+	}
+	return nil
+}
+
+// load merges the data of in into m; later inputs overwrite earlier
+// ones unless RejectOverlaps is set.  Inputs must not provide data for
+// protected regions.
+func (j *Job) load(m *image.Image, in Input) error {
+	var (
+		data *image.Image
+		err  error
+	)
+	if in.Format == "" {
+		data, _, err = Open(j.path(in.File))
+	} else {
+		data, err = readImage(j.path(in.File), in.Format)
+	}
+	if err != nil {
+		return inFile(err, in.File)
+	}
+
+	if in.Offset != 0 {
+		if data, err = offset(data, in.Offset); err != nil {
+			return inFile(err, in.File)
+		}
+	}
+	if err := j.Profile.Check(data, in.File); err != nil {
+		return err
+	}
+	if j.RejectOverlaps {
+		for s := range data.Regions() {
+			if segs := m.Crop(s.Address, uint32(len(s.Data))).Segments(); len(segs) > 0 {
+				return &OverlapError{File: in.File, Address: segs[0].Address}
+			}
+		}
+	}
+	for s := range data.Regions() {
+		m.Write(s.Address, s.Data)
+	}
+	return nil
+}
+
+// readImage reads the named input in format f
+func readImage(name string, f image.Format) (*image.Image, error) {
+	c, err := CodecFor(f)
+	if err != nil {
+		return nil, err
+	}
+	in, err := OpenInput(name)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	return readSegments(c.NewReader(in))
+}
+
+// store writes m to out, replacing the file atomically
+func (j *Job) store(m *image.Image, out Output) error {
+	f := out.Format
+	if f == "" {
+		var ok bool
+		if f, ok = FormatForName(out.File); !ok {
+			return fmt.Errorf("no format given for extension %q", filepath.Ext(out.File))
+		}
+	}
+	if _, err := CodecFor(f); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(j.path(out.File), 0644, func(w io.Writer) error {
+		opts := []image.Option{image.WithWidth(out.Width)}
+		if out.DataOnly {
+			opts = append(opts, image.WithDataOnly())
+		}
+		return m.Encode(w, f, opts...)
+	})
+}
+
+func (j *Job) path(name string) string {
+	if j.Dir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(j.Dir, name)
+}
+
+// Customary file name extensions of the formats
+var extFormats = map[string]image.Format{
+	".hex":  image.IntelHex,
+	".ihex": image.IntelHex,
+	".ihx":  image.IntelHex,
+	".srec": image.SRecord,
+	".s19":  image.SRecord,
+	".s28":  image.SRecord,
+	".s37":  image.SRecord,
+	".mot":  image.SRecord,
+	".bin":  image.Binary,
+	".tek":  image.TekHex,
+	".mem":  image.Verilog,
+	".vmem": image.Verilog,
+}
+
+// FormatForName returns the format customarily stored in files named
+// like name, judging by its extension
+func FormatForName(name string) (image.Format, bool) {
+	f, ok := extFormats[strings.ToLower(filepath.Ext(name))]
+	return f, ok
+}
@@ -0,0 +1,254 @@
+package hexio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+const testJob = `
+# Boot loader and application into one padded, checksummed image
+inputs:
+  - boot.hex
+  - file: app.bin   # no address of its own
+    format: binary
+    offset: 0x100
+filters:
+  - fill: 0 0x200 0xFF
+  - offset: +0x1000
+  - crc32: 0x11FC 0x1000 0x1FC
+outputs:
+  - out.srec
+  - file: out.hex
+    width: 32
+`
+
+func TestJob(t *testing.T) {
+	fmt.Println("TestJob()")
+
+	dir := t.TempDir()
+	boot := image.New()
+	boot.Write(0, []byte("boot"))
+	f, _ := os.Create(filepath.Join(dir, "boot.hex"))
+	boot.Encode(f, image.IntelHex)
+	f.Close()
+	os.WriteFile(filepath.Join(dir, "app.bin"), []byte("app"), 0644)
+	os.WriteFile(filepath.Join(dir, "job.yaml"), []byte(testJob), 0644)
+
+	j, err := LoadJob(filepath.Join(dir, "job.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.Inputs) != 2 || len(j.Filters) != 3 || len(j.Outputs) != 2 ||
+		j.Inputs[1] != (Input{File: "app.bin", Format: image.Binary, Offset: 0x100}) ||
+		j.Outputs[1].Width != 32 {
+		fmt.Printf("unexpected job: %+v\n", j)
+		t.FailNow()
+	}
+	if err := j.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := image.New()
+	want.Write(0x1000, []byte("boot"))
+	want.Write(0x1100, []byte("app"))
+	want.Write(0x1000, want.Extract(0x1000, 0x200, 0xFF))
+	want.Write(0x11FC, binary.LittleEndian.AppendUint32(nil,
+		crc32.ChecksumIEEE(want.Extract(0x1000, 0x1FC, 0xFF))))
+
+	for _, out := range []string{"out.srec", "out.hex"} {
+		m, _, err := Open(filepath.Join(dir, out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := image.RequireEqual(m, want); err != nil {
+			fmt.Println(out, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestParseJobErrors(t *testing.T) {
+	fmt.Println("TestParseJobErrors()")
+
+	for _, job := range []string{
+		"inputs:\n  - a.hex\n    format: ihex\n",
+		"filters:\n  - crop: 0x100\n",
+		"filters:\n  - shrink: 1\n",
+		"filters:\n  - fill: 0 1 ramp\n",
+		"outputs:\n  - width: 16\n",
+		"inputs:\n  - file: a.hex\n      format: ihex\n",
+		"  - a.hex\n",
+		"input:\n",
+	} {
+		if _, err := ParseJob(strings.NewReader(job)); err == nil {
+			fmt.Printf("no error for %q\n", job)
+			t.Fail()
+		}
+	}
+}
+
+func TestJobBuilder(t *testing.T) {
+	fmt.Println("TestJobBuilder()")
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.bin"), []byte("application"), 0644)
+
+	j := NewJob().
+		InputFormat(filepath.Join(dir, "app.bin"), image.Binary).
+		Crop(0, 3).
+		Offset(0x8000).
+		Fill(0x8000, 4, 0).
+		CRC32(0x8004, 0x8000, 4)
+
+	parsed, err := ParseJob(strings.NewReader(`
+inputs:
+  - file: ` + filepath.Join(dir, "app.bin") + `
+    format: binary
+filters:
+  - crop: 0 3
+  - offset: 0x8000
+  - fill: 0x8000 4 0
+  - crc32: 0x8004 0x8000 4
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(j, parsed) {
+		fmt.Printf("built %+v\nparsed %+v\n", j, parsed)
+		t.Fail()
+	}
+
+	m, err := j.Image()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte("app\x00")
+	want = binary.LittleEndian.AppendUint32(want, crc32.ChecksumIEEE(want))
+	if got := m.Extract(0x8000, 8, 0xFF); string(got) != string(want) {
+		fmt.Printf("got % X, want % X\n", got, want)
+		t.Fail()
+	}
+}
+
+func TestJobProtect(t *testing.T) {
+	fmt.Println("TestJobProtect()")
+
+	dir := t.TempDir()
+	app := filepath.Join(dir, "app.bin")
+	os.WriteFile(app, []byte("application"), 0644)
+
+	parsed, err := ParseJob(strings.NewReader(`
+inputs:
+  - file: ` + app + `
+    format: binary
+    offset: 0x100
+filters:
+  - fill: 0 0x200 0xFF
+protect:
+  - boot: 0 0x100
+  - otp: 0x1F0 16
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := NewJob().InputFormat(app, image.Binary).Fill(0, 0x200, 0xFF).
+		Protect("boot", 0, 0x100).Protect("otp", 0x1F0, 16)
+	j.Inputs[0].Offset = 0x100
+	if !reflect.DeepEqual(j, parsed) {
+		fmt.Printf("built %+v\nparsed %+v\n", j, parsed)
+		t.Fail()
+	}
+
+	// Fill bytes within protected regions are dropped
+	m, err := j.Image()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if segs := m.Segments(); len(segs) != 1 || segs[0].Address != 0x100 || len(segs[0].Data) != 0xF0 {
+		fmt.Println("unexpected segments", segs)
+		t.Fail()
+	}
+
+	// Inputs must keep out of them
+	j.Inputs[0].Offset = 0xF8
+	var pe *ProtectedError
+	if _, err := j.Image(); !errors.As(err, &pe) || pe.Region.Name != "boot" || pe.Address != 0xF8 {
+		fmt.Println("unexpected error", err)
+		t.Fail()
+	}
+
+	// and verification ignores them
+	want, got := image.New(), image.New()
+	want.Write(0xFC, []byte("bootapp"))
+	got.Write(0xFC, []byte("XXXXapp"))
+	if d := j.Profile.Verify(want, got); len(d) != 0 {
+		fmt.Println("unexpected differences", d)
+		t.Fail()
+	}
+	got.Write(0x100, []byte("b"))
+	if d := j.Profile.Verify(want, got); len(d) != 1 {
+		fmt.Println("missed difference", d)
+		t.Fail()
+	}
+}
+
+func TestFillPattern(t *testing.T) {
+	fmt.Println("TestFillPattern()")
+
+	j, err := ParseJob(strings.NewReader(`
+filters:
+  - fill: 0x10 8 0x00FF
+  - fill: 0x18 4 0xDEADBEEF
+  - fill: 0x1C 4 incrementing
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := image.New()
+	for _, f := range j.Filters {
+		if m, err = f.Apply(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []byte{0, 0xFF, 0, 0xFF, 0, 0xFF, 0, 0xFF, 0xDE, 0xAD, 0xBE, 0xEF, 0x1C, 0x1D, 0x1E, 0x1F}
+	if got := m.Extract(0x10, 16, 0); string(got) != string(want) {
+		fmt.Printf("got % X, want % X\n", got, want)
+		t.Fail()
+	}
+}
+
+func TestJobOverlap(t *testing.T) {
+	fmt.Println("TestJobOverlap()")
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.bin"), []byte("aaaa"), 0644)
+	os.WriteFile(filepath.Join(dir, "b.bin"), []byte("bb"), 0644)
+
+	j := NewJob().
+		InputFormat(filepath.Join(dir, "a.bin"), image.Binary).
+		InputFormat(filepath.Join(dir, "b.bin"), image.Binary)
+	j.Inputs[1].Offset = 1
+
+	// Later inputs win by default
+	m, err := j.Image()
+	if err != nil || string(m.Extract(0, 4, 0)) != "abba" {
+		fmt.Println(m, err)
+		t.Fail()
+	}
+
+	j.RejectOverlaps = true
+	var oe *OverlapError
+	if _, err := j.Image(); !errors.As(err, &oe) || oe.Address != 1 || oe.File != j.Inputs[1].File {
+		fmt.Println(err)
+		t.Fail()
+	}
+}
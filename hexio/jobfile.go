@@ -0,0 +1,342 @@
+package hexio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// ParseJob reads a job file from r.  Job files are written in a small
+// subset of YAML: lists named inputs, filters, outputs and protect, whose
+// items are either a plain file name or a mapping of settings.
+//
+//	# Merge the boot loader and application, pad the flash, add a CRC
+//	inputs:
+//	  - boot.hex
+//	  - file: release.zip!app.bin
+//	    format: binary
+//	    offset: 0x08004000
+//	filters:
+//	  - crop: 0x08000000 512K
+//	  - fill: 0x08000000 512K 0xFF
+//	  - crc32: 0x0807FFFC 0x08000000 0x7FFFC
+//	protect:
+//	  - calibration: 0x0807F000 4K
+//	outputs:
+//	  - flash.srec
+//	  - file: flash.hex
+//	    width: 32
+//	  - file: loader.srec
+//	    data-only: true
+//
+// Each filter names its kind followed by its arguments:
+//
+//	crop:   start size
+//	fill:   start size pattern
+//	offset: delta
+//	crc32:  address start size
+//
+// Each protect item names a protected region of the device profile,
+// followed by its start and size.
+//
+// Numbers may be decimal, or hex with a 0x prefix, and sizes may carry a
+// K, M or G suffix; offsets may be negative.  A fill pattern is a byte
+// value, or anything else image.ParsePattern accepts, e.g. 0xDEADBEEF.
+// Input formats are detected from the content unless given, output
+// formats derived from the file name extension.  An output with
+// data-only set leaves out headers, start addresses and the like.
+func ParseJob(r io.Reader) (*Job, error) {
+	var (
+		j       = new(Job)
+		section string
+		item    map[string]string // Mapping item being collected
+		itemAt  int               // Line of the item
+		indent  int               // Indentation of the item's keys
+	)
+
+	finish := func() error {
+		if item == nil {
+			return nil
+		}
+		err := j.addItem(section, item)
+		item = nil
+		if err != nil {
+			return fmt.Errorf("line %d: %v", itemAt, err)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := stripComment(scanner.Text())
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		depth := len(line) - len(strings.TrimLeft(line, " "))
+		line = strings.TrimSpace(line)
+
+		switch {
+		case depth == 0 && !strings.HasPrefix(line, "-"):
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			key, rest, ok := strings.Cut(line, ":")
+			if !ok || strings.TrimSpace(rest) != "" {
+				return nil, fmt.Errorf("line %d: expected a section name, found %q", n, line)
+			}
+			switch section = strings.TrimSpace(key); section {
+			case "inputs", "filters", "outputs", "protect":
+			default:
+				return nil, fmt.Errorf("line %d: unknown section %q", n, section)
+			}
+
+		case section == "":
+			return nil, fmt.Errorf("line %d: item outside of any section", n)
+
+		case strings.HasPrefix(line, "-"):
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			body := strings.TrimSpace(line[1:])
+			item, itemAt = map[string]string{}, n
+			indent = depth + len(line) - len(body)
+			if key, val, ok := cutKey(body); ok {
+				item[key] = val
+			} else {
+				item[""] = unquote(body)
+			}
+
+		case item != nil && depth == indent:
+			key, val, ok := cutKey(line)
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected a setting, found %q", n, line)
+			}
+			if _, dup := item[key]; dup {
+				return nil, fmt.Errorf("line %d: duplicate setting %q", n, key)
+			}
+			item[key] = val
+
+		default:
+			return nil, fmt.Errorf("line %d: unexpected indentation", n)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// LoadJob reads the job file named fn.  File names within the job are
+// relative to the directory holding it.
+func LoadJob(fn string) (*Job, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	j, err := ParseJob(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	j.Dir = filepath.Dir(fn)
+	return j, nil
+}
+
+// addItem adds the list item of section described by the settings kv.
+// A plain scalar item is held under the empty key.
+func (j *Job) addItem(section string, kv map[string]string) error {
+	if name, ok := kv[""]; ok {
+		if len(kv) > 1 {
+			return errors.New("item mixes a plain value with settings")
+		}
+		kv = map[string]string{"file": name}
+	}
+
+	switch section {
+	case "inputs":
+		var in Input
+		for k, v := range kv {
+			var err error
+			switch k {
+			case "file":
+				in.File = v
+			case "format":
+				in.Format = image.Format(v)
+			case "offset":
+				in.Offset, err = parseOffset(v)
+			default:
+				return fmt.Errorf("unknown input setting %q", k)
+			}
+			if err != nil {
+				return fmt.Errorf("bad %s: %v", k, err)
+			}
+		}
+		if in.File == "" {
+			return errors.New("input without a file")
+		}
+		j.Inputs = append(j.Inputs, in)
+
+	case "outputs":
+		var out Output
+		for k, v := range kv {
+			var err error
+			switch k {
+			case "file":
+				out.File = v
+			case "format":
+				out.Format = image.Format(v)
+			case "width":
+				out.Width, err = strconv.Atoi(v)
+			case "data-only":
+				out.DataOnly, err = strconv.ParseBool(v)
+			default:
+				return fmt.Errorf("unknown output setting %q", k)
+			}
+			if err != nil {
+				return fmt.Errorf("bad %s: %v", k, err)
+			}
+		}
+		if out.File == "" {
+			return errors.New("output without a file")
+		}
+		j.Outputs = append(j.Outputs, out)
+
+	case "protect":
+		if _, ok := kv["file"]; ok || len(kv) != 1 {
+			return errors.New(`a protected region takes the form "name: start size"`)
+		}
+		for name, args := range kv {
+			f := strings.Fields(args)
+			if len(f) != 2 {
+				return fmt.Errorf("protected region %s takes a start and a size", name)
+			}
+			var v [2]uint64
+			for i, a := range f {
+				n, err := image.ParseSize(a)
+				if err != nil || n >= 1<<32 {
+					return fmt.Errorf("%s: bad argument %q", name, a)
+				}
+				v[i] = n
+			}
+			if v[0]+v[1] > 1<<32 {
+				return fmt.Errorf("protected region %s exceeds the 32-bit address space", name)
+			}
+			j.Protect(name, uint32(v[0]), uint32(v[1]))
+		}
+
+	case "filters":
+		if _, ok := kv["file"]; ok || len(kv) != 1 {
+			return errors.New(`a filter takes the form "kind: arguments"`)
+		}
+		for kind, args := range kv {
+			f, err := parseFilter(kind, strings.Fields(args))
+			if err != nil {
+				return err
+			}
+			j.Filters = append(j.Filters, f)
+		}
+	}
+	return nil
+}
+
+// parseFilter builds the filter kind from its arguments
+func parseFilter(kind string, args []string) (Filter, error) {
+	want := map[string]int{"crop": 2, "fill": 3, "offset": 1, "crc32": 3}[kind]
+	if want == 0 {
+		return nil, fmt.Errorf("unknown filter %q", kind)
+	}
+	if len(args) != want {
+		return nil, fmt.Errorf("filter %s takes %d arguments, not %d", kind, want, len(args))
+	}
+
+	if kind == "offset" {
+		d, err := parseOffset(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("offset: %v", err)
+		}
+		return OffsetFilter{d}, nil
+	}
+
+	nums := args
+	if kind == "fill" {
+		nums = args[:2] // The last is a pattern
+	}
+	var v [3]uint32
+	for i, a := range nums {
+		n, err := image.ParseSize(a)
+		if err != nil || n >= 1<<32 {
+			return nil, fmt.Errorf("%s: bad argument %q", kind, a)
+		}
+		v[i] = uint32(n)
+	}
+
+	switch kind {
+	case "crop":
+		return CropFilter{Start: v[0], Size: v[1]}, nil
+	case "fill":
+		f := FillFilter{Start: v[0], Size: v[1]}
+		if n, err := image.ParseSize(args[2]); err == nil && n <= 0xFF && len(args[2]) <= 4 {
+			f.Value = byte(n)
+		} else if f.Pattern, err = image.ParsePattern(args[2]); err != nil {
+			return nil, fmt.Errorf("fill: %v", err)
+		}
+		return f, nil
+	}
+	return CRC32Filter{Address: v[0], Start: v[1], Size: v[2]}, nil
+}
+
+// parseOffset parses a signed address distance
+func parseOffset(s string) (int64, error) {
+	neg := strings.HasPrefix(s, "-")
+	n, err := image.ParseSize(strings.TrimLeft(s, "+-"))
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		return -int64(n), nil
+	}
+	return int64(n), nil
+}
+
+// cutKey splits a "key: value" setting
+func cutKey(s string) (key, val string, ok bool) {
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, `'`) {
+		return "", "", false
+	}
+	key, val, ok = strings.Cut(s, ":")
+	if !ok || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+	return key, unquote(strings.TrimSpace(val)), true
+}
+
+// unquote strips the quotes off a quoted scalar
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// stripComment removes a '#' comment, which must start the line or
+// follow white space so names like "fw#2.hex" survive
+func stripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,85 @@
+package hexio
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// DeviceProfile describes the memory of a target device as far as
+// building and checking its images is concerned
+type DeviceProfile struct {
+	Name string
+
+	// Protected lists the regions that must be left alone, such as
+	// one-time programmable memory, factory calibration data or a boot
+	// loader programmed separately.  A Job refuses inputs providing
+	// data within them, and Verify ignores them.
+	Protected []image.Region
+}
+
+// LoadProfile reads a device profile whose protected regions are listed
+// in the file fn, as for LoadRegions
+func LoadProfile(fn string) (*DeviceProfile, error) {
+	regions, err := LoadRegions(fn)
+	if err != nil {
+		return nil, err
+	}
+	return &DeviceProfile{Name: fn, Protected: regions}, nil
+}
+
+// LoadRegions reads the regions listed in the file fn: the memory
+// regions of a GNU ld map file, or those of a region map in either of
+// the layouts ParseRegions understands
+func LoadRegions(fn string) ([]image.Region, error) {
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	parse := image.ParseRegions
+	if bytes.Contains(b, []byte("Memory Configuration")) {
+		parse = image.ParseLinkerMap
+	}
+	regions, err := parse(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	return regions, nil
+}
+
+// Protect adds a protected region
+func (p *DeviceProfile) Protect(name string, start, size uint32) *DeviceProfile {
+	p.Protected = append(p.Protected, image.Region{Name: name, Start: start, Size: size})
+	return p
+}
+
+// Check returns a ProtectedError for the first data of m, which was read
+// from the input file, falling within a protected region
+func (p *DeviceProfile) Check(m *image.Image, file string) error {
+	if p == nil {
+		return nil
+	}
+	for _, r := range p.Protected {
+		if segs := m.Crop(r.Start, r.Size).Segments(); len(segs) > 0 {
+			return &ProtectedError{File: file, Region: r, Address: segs[0].Address}
+		}
+	}
+	return nil
+}
+
+// Mask returns a copy of m without the data within protected regions
+func (p *DeviceProfile) Mask(m *image.Image) *image.Image {
+	if p == nil {
+		return m
+	}
+	return m.Mask(p.Protected)
+}
+
+// Verify returns the differences between want and got outside of the
+// protected regions
+func (p *DeviceProfile) Verify(want, got *image.Image) []image.Difference {
+	return image.Compare(p.Mask(want), p.Mask(got))
+}
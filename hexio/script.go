@@ -0,0 +1,203 @@
+package hexio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/template"
+
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+// Script is the plan of a programming session for an image, the data a
+// script template is executed with
+type Script struct {
+	File     string         // Name of the image file, for tools that load it themselves
+	Erase    []image.Region // Ranges to erase: the sectors holding data
+	Program  []image.Region // Ranges holding data, to program and verify
+	Skipped  []image.Region // Ranges holding data within protected regions, left out of Program
+	Start    uint32         // Execution start address
+	HasStart bool           // Whether Start is known
+}
+
+// NewScript plans the programming of m, read from file, on a device
+// whose flash erases in sectors of the given size; sector 0 erases
+// exactly the ranges programmed.  Data within the protected regions of
+// p, which may be nil, is skipped, and sectors to erase must keep clear
+// of them.
+func NewScript(file string, m *image.Image, sector uint32, p *DeviceProfile) (*Script, error) {
+	s := &Script{File: file}
+	for seg := range p.Mask(m).Regions() {
+		r := image.Region{Start: seg.Address, Size: uint32(len(seg.Data))}
+		s.Program = append(s.Program, r)
+		if sector > 0 {
+			start := uint64(r.Start) / uint64(sector) * uint64(sector)
+			end := (r.End() + uint64(sector) - 1) / uint64(sector) * uint64(sector)
+			r = image.Region{Start: uint32(start), Size: uint32(min(end, 1<<32) - start)}
+		}
+		s.Erase = appendRange(s.Erase, r)
+	}
+
+	if p != nil {
+		var skipped []image.Region
+		for _, pr := range p.Protected {
+			for seg := range m.Crop(pr.Start, pr.Size).Regions() {
+				skipped = append(skipped, image.Region{Start: seg.Address, Size: uint32(len(seg.Data))})
+			}
+		}
+		sort.Slice(skipped, func(i, j int) bool { return skipped[i].Start < skipped[j].Start })
+		for _, r := range skipped {
+			s.Skipped = appendRange(s.Skipped, r)
+		}
+
+		for _, r := range s.Erase {
+			for _, pr := range p.Protected {
+				if uint64(pr.Start) < r.End() && uint64(r.Start) < pr.End() {
+					return nil, fmt.Errorf("erase sector 0x%08X-0x%08X overlaps protected region %s",
+						r.Start, r.End()-1, pr.Name)
+				}
+			}
+		}
+	}
+	return s, nil
+}
+
+// appendRange adds r to the ascending ranges rs, merging it with the last
+// one if they touch
+func appendRange(rs []image.Region, r image.Region) []image.Region {
+	if n := len(rs); n > 0 && uint64(r.Start) <= rs[n-1].End() {
+		last := &rs[n-1]
+		last.Size = uint32(max(last.End(), r.End()) - uint64(last.Start))
+		return rs
+	}
+	return append(rs, r)
+}
+
+// SetStart records the execution start address
+func (s *Script) SetStart(addr uint32) {
+	s.Start, s.HasStart = addr, true
+}
+
+// Functions available to script templates
+var scriptFuncs = template.FuncMap{
+	// hex formats an address or size as 0x%08X
+	"hex": func(v any) string { return fmt.Sprintf("0x%08X", v) },
+	// last returns the address of the last byte of a region
+	"last": func(r image.Region) uint64 { return r.End() - 1 },
+	// fail stops the script with an error
+	"fail": func(msg string) (string, error) { return "", errors.New(msg) },
+}
+
+// Built-in script templates, by tool name
+var scriptTemplates = map[string]string{
+	// A neutral listing, one step per line
+	"plain": `# {{.File}}
+{{range .Erase}}erase   {{hex .Start}} {{hex .Size}}
+{{end}}{{range .Program}}program {{hex .Start}} {{hex .Size}}
+{{end}}{{range .Program}}verify  {{hex .Start}} {{hex .Size}}
+{{end}}{{if .HasStart}}run     {{hex .Start}}
+{{end}}`,
+
+	// An OpenOCD command script, for openocd -f board.cfg -f script.
+	// flash write_image takes the whole file, so it cannot skip the
+	// data of protected regions.
+	"openocd": `{{if .Skipped}}{{fail "the openocd script programs the whole file and cannot skip protected data"}}{{end -}}
+# Programs {{.File}}
+init
+reset halt
+{{range .Erase}}flash erase_address {{hex .Start}} {{hex .Size}}
+{{end}}flash write_image {{printf "%q" .File}}
+verify_image {{printf "%q" .File}}
+{{if .HasStart}}resume {{hex .Start}}
+{{else}}reset run
+{{end}}shutdown
+`,
+}
+
+// ScriptTools returns the names of the built-in script templates
+func ScriptTools() []string {
+	var names []string
+	for name := range scriptTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ScriptTemplate returns the built-in script template for the named tool
+func ScriptTemplate(tool string) (*template.Template, error) {
+	text, ok := scriptTemplates[tool]
+	if !ok {
+		return nil, fmt.Errorf("no script template for tool %q", tool)
+	}
+	return ParseScriptTemplate(text)
+}
+
+// ParseScriptTemplate parses a custom script template.  Templates are
+// executed with a *Script and may use the functions hex, which formats
+// a number as 0x%08X, last, which returns the address of the last byte
+// of a region, and fail, which stops the script with the given error
+// message, e.g. for a tool unable to handle Skipped data.
+func ParseScriptTemplate(text string) (*template.Template, error) {
+	return template.New("script").Funcs(scriptFuncs).Parse(text)
+}
+
+// Execute writes the script rendered by template t to w.  Nothing is
+// written if rendering fails.
+func (s *Script) Execute(w io.Writer, t *template.Template) error {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, s); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// EntryPoint returns the execution start address recorded in the named
+// input, which may be a zip archive member as for OpenInput.  Only Intel
+// Hex and S-Record files carry one; ok is false for other formats and
+// files without a start record.  For an Intel Hex start segment record
+// the physical address (CS << 4) + IP is returned.
+func EntryPoint(name string) (addr uint32, ok bool, err error) {
+	in, err := OpenInput(name)
+	if err != nil {
+		return 0, false, err
+	}
+	defer in.Close()
+
+	br := bufio.NewReaderSize(in, DetectSize)
+	head, err := br.Peek(DetectSize)
+	if err != nil && err != io.EOF {
+		return 0, false, err
+	}
+	f, err := Detect(head)
+	if err != nil {
+		return 0, false, &ParseError{File: name, Err: err}
+	}
+
+	switch f {
+	case image.IntelHex:
+		recs, err := ihex.ReadAll(br)
+		if err != nil {
+			return 0, false, &ParseError{File: name, Err: err}
+		}
+		addr, kind, ok := ihex.NewFile(recs).EntryPoint()
+		if kind == ihex.StartSegment {
+			addr = addr>>16<<4 + addr&0xFFFF
+		}
+		return addr, ok, nil
+	case image.SRecord:
+		recs, err := srec.ReadAll(br)
+		if err != nil {
+			return 0, false, &ParseError{File: name, Err: err}
+		}
+		addr, ok := srec.NewFile(recs).StartAddress()
+		return addr, ok, nil
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,100 @@
+package hexio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+)
+
+func TestScript(t *testing.T) {
+	fmt.Println("TestScript()")
+
+	m := image.New()
+	m.Write(0x08000100, []byte("vectors"))
+	m.Write(0x08000F00, []byte("code"))   // Same 4K sector
+	m.Write(0x08003000, []byte("config")) // Next but two
+
+	s, err := NewScript("fw.hex", m, 4<<10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetStart(0x08000101)
+
+	tm, err := ScriptTemplate("plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := s.Execute(&sb, tm); err != nil {
+		t.Fatal(err)
+	}
+	want := `# fw.hex
+erase   0x08000000 0x00001000
+erase   0x08003000 0x00001000
+program 0x08000100 0x00000007
+program 0x08000F00 0x00000004
+program 0x08003000 0x00000006
+verify  0x08000100 0x00000007
+verify  0x08000F00 0x00000004
+verify  0x08003000 0x00000006
+run     0x08000101
+`
+	if sb.String() != want {
+		fmt.Printf("got:\n%s", sb.String())
+		t.Fail()
+	}
+
+	// Protected data is skipped, but its sector must not be erased
+	p := new(DeviceProfile).Protect("config", 0x08003000, 0x1000)
+	if s, err = NewScript("fw.hex", m, 0, p); err != nil || len(s.Program) != 2 ||
+		len(s.Skipped) != 1 || s.Skipped[0] != (image.Region{Start: 0x08003000, Size: 6}) {
+		fmt.Println("unexpected script", s, err)
+		t.Fail()
+	}
+
+	// OpenOCD programs the whole file, so it must refuse to skip data
+	tm, _ = ScriptTemplate("openocd")
+	sb.Reset()
+	if err := s.Execute(&sb, tm); err == nil || sb.Len() > 0 {
+		fmt.Printf("openocd script written despite protected data: %q\n", sb.String())
+		t.Fail()
+	}
+	s.Skipped = nil
+	if err := s.Execute(&sb, tm); err != nil || !strings.HasPrefix(sb.String(), "# Programs fw.hex\ninit\n") {
+		fmt.Printf("openocd script %q, %v\n", sb.String(), err)
+		t.Fail()
+	}
+	p = new(DeviceProfile).Protect("otp", 0x08001000, 0x10)
+	if _, err := NewScript("fw.hex", m, 8<<10, p); err == nil {
+		fmt.Println("erase of a protected sector accepted")
+		t.Fail()
+	}
+
+	for _, tool := range ScriptTools() {
+		if _, err := ScriptTemplate(tool); err != nil {
+			fmt.Println(tool, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestEntryPoint(t *testing.T) {
+	fmt.Println("TestEntryPoint()")
+
+	var sb strings.Builder
+	x := ihex.NewWriter(&sb)
+	x.WriteStartSegAddr(0x1234, 0x0010)
+	x.Close()
+	fn := filepath.Join(t.TempDir(), "start.hex")
+	os.WriteFile(fn, []byte(sb.String()), 0644)
+
+	if addr, ok, err := EntryPoint(fn); err != nil || !ok || addr != 0x12350 {
+		fmt.Printf("got 0x%X %v %v\n", addr, ok, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,108 @@
+package hexio
+
+import (
+	"fmt"
+
+	"github.com/peteArnt/GoHexIO/fairbug"
+	"github.com/peteArnt/GoHexIO/image"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+// EstimateEncodedSize returns the number of bytes that encoding a single
+// contiguous run of dataLen bytes in format would produce, as image.Encode
+// writes it, with width data bytes per record or line (0 for the format's
+// default).  addrMode selects the S1/S2/S3 record type of S-Records; 0
+// picks the smallest one able to address dataLen bytes.  The estimate
+// assumes the run starts at address 0 and is exact then.  Elsewhere it
+// is close: Intel Hex for instance needs another Extended Linear Address
+// record if the run starts above 64K.  It is meant for preallocating
+// buffers and as the denominator of progress reports.
+func EstimateEncodedSize(dataLen int64, width int, format image.Format, addrMode srec.AddrMode) (int64, error) {
+	if dataLen < 0 || width < 0 {
+		return 0, fmt.Errorf("invalid length %d or width %d", dataLen, width)
+	}
+
+	// records returns the number of records needed for n bytes
+	records := func(n int64, w int) int64 {
+		return (n + int64(w) - 1) / int64(w)
+	}
+
+	switch format {
+	case image.IntelHex:
+		if width == 0 {
+			width = 16
+		}
+
+		// Records never straddle a 64K page; each page after the
+		// first starts with an Extended Linear Address record
+		var n, ela int64 = 0, -1
+		for left := dataLen; left > 0; left -= 0x10000 {
+			n += records(min(left, 0x10000), width)
+			ela++
+		}
+		ela = max(ela, 0)
+
+		// ":LLAAAATT" + data + "CC\n" per record, then the EOF record
+		return 2*dataLen + 12*n + 16*ela + 12, nil
+
+	case image.SRecord:
+		if width == 0 {
+			width = 10
+		}
+		if addrMode == 0 {
+			addrMode = srec.Addr16
+			if dataLen > 1<<16 {
+				addrMode = srec.Addr24
+			}
+			if dataLen > 1<<24 {
+				addrMode = srec.Addr32
+			}
+		}
+		a := int64(addrMode) / 8
+
+		// "SnLL" + address + data + "CC\n" per record, then the start
+		// record
+		return 2*dataLen + (2*a+7)*records(dataLen, width) + 2*a + 7, nil
+
+	case image.Signetics:
+		if width == 0 {
+			width = 16
+		}
+
+		// ":AAAALLHH" + data + "CC\n" per record, then the EOF record
+		return 2*dataLen + 12*records(dataLen, width) + 10, nil
+
+	case image.PlainHex, image.Verilog:
+		// "@AAAAAAAA\n", then every byte takes two digits and a
+		// separator or line end
+		if dataLen == 0 {
+			return 0, nil
+		}
+		return 10 + 3*dataLen, nil
+
+	case image.TekHex:
+		if width == 0 {
+			width = 16
+		}
+
+		// "%LL6CC" + address + data + "\n" per record, the address
+		// taking a length digit and a digit for every nybble, then the
+		// termination record "%0781010\n"
+		n := records(dataLen, width)
+		digits := n
+		for top := int64(16); top < dataLen; top *= 16 {
+			digits += n - records(top, width)
+		}
+		return 2*dataLen + 8*n + digits + 9, nil
+
+	case image.Fairbug:
+		// "SAAAA\n", then "X" + block + checksum digits + "\n" per
+		// block, then "*\n"
+		return 6 + (2*fairbug.BlockSize+4)*records(dataLen, fairbug.BlockSize) + 2, nil
+
+	case image.Binary:
+		return dataLen, nil
+	}
+
+	return 0, fmt.Errorf("unknown format %q", format)
+}
@@ -0,0 +1,37 @@
+package hexio
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+func TestEstimateEncodedSize(t *testing.T) {
+	fmt.Println("TestEstimateEncodedSize()")
+
+	for _, n := range []int{1, 16, 1000, 0x10000, 0x23456} {
+		m := image.Generate(image.Incrementing, 0, n)
+
+		formats := []image.Format{image.IntelHex, image.SRecord, image.Binary, image.PlainHex, image.TekHex}
+		if n < 0x10000 {
+			formats = append(formats, image.Signetics)
+		}
+
+		for _, f := range formats {
+			var buf bytes.Buffer
+			if err := m.Encode(&buf, f); err != nil {
+				fmt.Println(f, err)
+				t.FailNow()
+			}
+
+			est, err := EstimateEncodedSize(int64(n), 0, f, srec.AddrModeFor(m))
+			if err != nil || est != int64(buf.Len()) {
+				fmt.Printf("%s, %d bytes: estimated %d, encoded %d (%v)\n", f, n, est, buf.Len(), err)
+				t.Fail()
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package hexio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// SplitLimits bounds the files a SplitWriter writes, for programmers and
+// transfer protocols with per-file limits
+type SplitLimits struct {
+	MaxBytes   int64 // Encoded bytes per file, 0 for no limit
+	MaxRecords int   // Records, that is lines, per file, 0 for no limit
+}
+
+// Default record widths of the formats whose writers emit the records
+// of a segment as it is written, those a SplitWriter handles
+var recordWidths = map[image.Format]int{
+	image.IntelHex: 16,
+	image.SRecord:  10,
+}
+
+// SplitWriter is a SegmentWriter breaking its output into numbered
+// files: once the next record would take the file being written past
+// the limits, it completes that file and starts the next one.  Every
+// file is complete in itself, with the address and start records its
+// format calls for.  Data is written record by record as it comes, so
+// the cost of splitting is linear in the size of the output.
+type SplitWriter struct {
+	c      Codec
+	o      image.EncodeOptions
+	l      SplitLimits
+	create func(n int) (io.WriteCloser, error)
+
+	n     int            // Number of the file being written, 0 before the first
+	out   io.WriteCloser // The file being written
+	x     SegmentWriter  // Encoder of that file, writing to stage
+	stage bytes.Buffer   // Output of x not yet committed to out
+	bytes int64          // Bytes committed to out
+	lines int            // Lines committed to out
+	data  bool           // Data has been committed to out
+
+	trailBytes int64 // Bytes written by Close of an empty file
+	trailLines int   // Lines written by it
+}
+
+// NewSplitWriter returns a writer encoding segments in format f, with
+// opts applied, to files within the limits l.  create opens file n,
+// counting from 1; it is called as the data reaches the file, so empty
+// input creates no files.  Only formats whose records are written as
+// the data comes, Intel Hex and S-Records, can be split.
+func NewSplitWriter(f image.Format, l SplitLimits, create func(n int) (io.WriteCloser, error), opts ...image.Option) (*SplitWriter, error) {
+	if l.MaxBytes < 0 || l.MaxRecords < 0 {
+		return nil, fmt.Errorf("invalid split limits %+v", l)
+	}
+	width, ok := recordWidths[f]
+	if !ok {
+		return nil, fmt.Errorf("format %q cannot be split", f)
+	}
+	c, err := CodecFor(f)
+	if err != nil {
+		return nil, err
+	}
+
+	o := image.EncodeOptions{Fill: 0xFF}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Width < 0 || o.Width > 255 {
+		return nil, fmt.Errorf("width %d out of range 1..255", o.Width)
+	}
+	if o.Width == 0 {
+		o.Width = width
+	}
+	return &SplitWriter{c: c, o: o, l: l, create: create}, nil
+}
+
+// SplitImage writes m in format f to files within the limits l, as a
+// SplitWriter does, and returns the number of files created
+func SplitImage(m *image.Image, f image.Format, l SplitLimits, create func(n int) (io.WriteCloser, error), opts ...image.Option) (int, error) {
+	x, err := NewSplitWriter(f, l, create, opts...)
+	if err != nil {
+		return 0, err
+	}
+	if segs := m.Segments(); len(segs) > 0 {
+		x.o.End = segs[len(segs)-1].End()
+	}
+	for s := range m.Regions() {
+		if err := x.WriteSegment(s); err != nil {
+			x.Close()
+			return x.n, err
+		}
+	}
+	return x.n, x.Close()
+}
+
+// Files returns the number of files created so far
+func (x *SplitWriter) Files() int {
+	return x.n
+}
+
+// WriteSegment writes s, a record at a time, starting new files as the
+// limits demand
+func (x *SplitWriter) WriteSegment(s image.Segment) error {
+	for len(s.Data) > 0 {
+		n := min(len(s.Data), x.o.Width)
+		if err := x.writeRecord(image.Segment{Address: s.Address, Data: s.Data[:n]}); err != nil {
+			return err
+		}
+		s.Address += uint32(n)
+		s.Data = s.Data[n:]
+	}
+	return nil
+}
+
+// writeRecord writes the data of one record to the current file, or to
+// the next one if it does not fit
+func (x *SplitWriter) writeRecord(s image.Segment) error {
+	if x.out == nil {
+		if err := x.next(); err != nil {
+			return err
+		}
+	}
+	if err := x.x.WriteSegment(s); err != nil {
+		return err
+	}
+	if x.fits() {
+		return x.commit()
+	}
+	if !x.data {
+		return fmt.Errorf("limits %+v too tight for the data at 0x%X", x.l, s.Address)
+	}
+
+	// Drop the record, complete the file and write it to the next one
+	x.stage.Reset()
+	if err := x.finish(); err != nil {
+		return err
+	}
+	return x.writeRecord(s)
+}
+
+// fits reports whether the staged output and the records completing the
+// file stay within the limits
+func (x *SplitWriter) fits() bool {
+	b := x.bytes + int64(x.stage.Len()) + x.trailBytes
+	n := x.lines + bytes.Count(x.stage.Bytes(), []byte("\n")) + x.trailLines
+	return (x.l.MaxBytes == 0 || b <= x.l.MaxBytes) && (x.l.MaxRecords == 0 || n <= x.l.MaxRecords)
+}
+
+// commit writes the staged output to the current file
+func (x *SplitWriter) commit() error {
+	x.bytes += int64(x.stage.Len())
+	x.lines += bytes.Count(x.stage.Bytes(), []byte("\n"))
+	x.data = true
+	_, err := x.stage.WriteTo(x.out)
+	return err
+}
+
+// next creates the next file and its encoder
+func (x *SplitWriter) next() error {
+	if x.n == 0 {
+		// Measure the records completing each file
+		var b bytes.Buffer
+		if err := x.c.NewWriter(&b, x.o).Close(); err != nil {
+			return err
+		}
+		x.trailBytes, x.trailLines = int64(b.Len()), bytes.Count(b.Bytes(), []byte("\n"))
+	}
+
+	out, err := x.create(x.n + 1)
+	if err != nil {
+		return err
+	}
+	x.n++
+	x.out, x.x = out, x.c.NewWriter(&x.stage, x.o)
+	x.bytes, x.lines, x.data = 0, 0, false
+	return nil
+}
+
+// finish completes and closes the current file
+func (x *SplitWriter) finish() error {
+	err := x.x.Close()
+	if err == nil {
+		_, err = x.stage.WriteTo(x.out)
+	}
+	if cerr := x.out.Close(); err == nil {
+		err = cerr
+	}
+	x.out = nil
+	return err
+}
+
+// Close completes and closes the last file; the files before it are
+// closed as they are completed
+func (x *SplitWriter) Close() error {
+	if x.out == nil {
+		return nil
+	}
+	return x.finish()
+}
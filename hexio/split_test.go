@@ -0,0 +1,91 @@
+package hexio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// memFile is an in-memory file for SplitWriter
+type memFile struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (f *memFile) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestSplitImage(t *testing.T) {
+	fmt.Println("TestSplitImage()")
+
+	m := image.Generate(image.Incrementing, 0xFFF0, 1000) // Crosses a 64K page
+	m.Write(0x20000, []byte{1, 2, 3})
+
+	for _, f := range []image.Format{image.IntelHex, image.SRecord} {
+		for _, l := range []SplitLimits{{MaxBytes: 600}, {MaxRecords: 10}, {MaxBytes: 1000, MaxRecords: 20}} {
+			var files []*memFile
+			n, err := SplitImage(m, f, l, func(n int) (io.WriteCloser, error) {
+				if n != len(files)+1 {
+					return nil, fmt.Errorf("file %d created after %d", n, len(files))
+				}
+				files = append(files, &memFile{})
+				return files[n-1], nil
+			})
+			if err != nil {
+				fmt.Println(f, l, err)
+				t.FailNow()
+			}
+
+			joined := image.New()
+			for _, file := range files {
+				b := file.Bytes()
+				if !file.closed || l.MaxBytes > 0 && int64(len(b)) > l.MaxBytes ||
+					l.MaxRecords > 0 && bytes.Count(b, []byte("\n")) > l.MaxRecords {
+					fmt.Printf("%s %+v: file of %d bytes, %d lines, closed %v\n", f, l, len(b), bytes.Count(b, []byte("\n")), file.closed)
+					t.Fail()
+				}
+				part, _, err := Decode(bytes.NewReader(b))
+				if err != nil {
+					fmt.Println(f, l, err)
+					t.FailNow()
+				}
+				for _, s := range part.Segments() {
+					joined.Write(s.Address, s.Data)
+				}
+			}
+			if n != len(files) || n < 2 || len(image.Compare(joined, m)) > 0 {
+				fmt.Printf("%s %+v: %d files, rejoined %d bytes\n", f, l, n, joined.Len())
+				t.Fail()
+			}
+		}
+	}
+
+	discard := func(int) (io.WriteCloser, error) { return &memFile{}, nil }
+	if _, err := SplitImage(m, image.IntelHex, SplitLimits{MaxBytes: 20}, discard); err == nil {
+		fmt.Println("limit below a single record accepted")
+		t.Fail()
+	}
+	if _, err := SplitImage(m, image.TekHex, SplitLimits{MaxBytes: 600}, discard); err == nil {
+		fmt.Println("split of a format written as a whole accepted")
+		t.Fail()
+	}
+	if n, err := SplitImage(image.New(), image.IntelHex, SplitLimits{MaxBytes: 600}, discard); n != 0 || err != nil {
+		fmt.Println("empty image:", n, err)
+		t.Fail()
+	}
+}
+
+func BenchmarkSplitImage(b *testing.B) {
+	m := image.Generate(image.Incrementing, 0, 1<<20)
+	discard := func(int) (io.WriteCloser, error) { return &memFile{}, nil }
+	for b.Loop() {
+		if _, err := SplitImage(m, image.IntelHex, SplitLimits{MaxBytes: 64 << 10}, discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
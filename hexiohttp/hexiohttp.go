@@ -0,0 +1,179 @@
+// Package hexiohttp provides HTTP handlers for firmware services: one
+// accepting Intel Hex or S-Record uploads and one serving memory images
+// in a chosen format.
+package hexiohttp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+
+	_ "github.com/peteArnt/GoHexIO/hexio" // registers every output format
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+// DefaultMaxBytes is the upload size limit used when none is configured
+const DefaultMaxBytes = 16 << 20
+
+// UploadHandler accepts Intel Hex or S-Record uploads in the body of POST
+// and PUT requests.  The body is decoded as it streams in, never beyond
+// MaxBytes, and each record is validated.  Malformed uploads are answered
+// with 400 Bad Request, oversized ones with 413; a successfully parsed
+// image is handed to Handle, which writes the response.
+type UploadHandler struct {
+	MaxBytes int64        // Upload size limit; 0 selects DefaultMaxBytes
+	Format   image.Format // Expected format; "" detects it from the first record
+	Handle   func(w http.ResponseWriter, r *http.Request, m *image.Image)
+}
+
+func (h *UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := h.MaxBytes
+	if limit <= 0 {
+		limit = DefaultMaxBytes
+	}
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, limit))
+
+	m, err := decode(body, h.Format)
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			http.Error(w, fmt.Sprintf("upload exceeds %d bytes", limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.Handle(w, r, m)
+}
+
+// decode parses r in format f, detecting Intel Hex or S-Records from the
+// first non-blank character if f is ""
+func decode(r *bufio.Reader, f image.Format) (*image.Image, error) {
+	if f == "" {
+		for {
+			c, err := r.Peek(1)
+			if err != nil {
+				if err == io.EOF {
+					return nil, errors.New("empty upload")
+				}
+				return nil, err
+			}
+			switch c[0] {
+			case ' ', '\t', '\r', '\n':
+				r.ReadByte()
+				continue
+			case ':':
+				f = image.IntelHex
+			case 'S':
+				f = image.SRecord
+			default:
+				return nil, fmt.Errorf("unrecognized format, starting with %q", c[0])
+			}
+			break
+		}
+	}
+
+	switch f {
+	case image.IntelHex:
+		return ihex.Decode(r)
+	case image.SRecord:
+		return srec.Decode(r)
+	}
+	return nil, fmt.Errorf("unsupported upload format %q", f)
+}
+
+// ContentType returns the MIME type images in format f are served with
+func ContentType(f image.Format) string {
+	if f == image.Binary {
+		return "application/octet-stream"
+	}
+	return "text/plain; charset=us-ascii"
+}
+
+// ServeImage writes m to w in format f, with the matching Content-Type
+// and, if name is not empty, a Content-Disposition offering it as a file
+// download of that name.  The image is encoded before anything is sent,
+// so an encoding failure still yields a clean 500 Internal Server Error.
+func ServeImage(w http.ResponseWriter, m *image.Image, f image.Format, name string) {
+	var buf bytes.Buffer
+	if err := m.Encode(&buf, f); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType(f))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	if name != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	}
+	w.Write(buf.Bytes())
+}
+
+// ErrNotFound is returned by the Image callback of a DownloadHandler for
+// requests naming no image; it is answered with 404 Not Found
+var ErrNotFound = errors.New("image not found")
+
+// Formats a DownloadHandler offers by default: those whose output grows
+// with the data alone, unlike binary output, which fills every gap and
+// so can take hundreds of megabytes for an image with far apart data
+var recordFormats = []image.Format{image.IntelHex, image.SRecord}
+
+// DownloadHandler serves the image returned by Image in the format named
+// by the request's "format" query parameter, or Format if there is none.
+// A format neither Format nor listed in Formats is answered with 400 Bad
+// Request.  An error from Image is answered with 404 Not Found if it is
+// ErrNotFound, or wraps it, and 500 Internal Server Error otherwise.
+type DownloadHandler struct {
+	Image   func(r *http.Request) (*image.Image, error)
+	Format  image.Format   // Default format
+	Formats []image.Format // Formats clients may ask for; nil for Intel Hex and S-Records
+	Name    string         // File name offered to the client, "" for none
+}
+
+func (h *DownloadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	f := h.Format
+	if q := r.URL.Query().Get("format"); q != "" && image.Format(q) != h.Format {
+		allowed := h.Formats
+		if allowed == nil {
+			allowed = recordFormats
+		}
+		if !slices.Contains(allowed, image.Format(q)) {
+			http.Error(w, fmt.Sprintf("unsupported format %q", q), http.StatusBadRequest)
+			return
+		}
+		f = image.Format(q)
+	}
+
+	m, err := h.Image(r)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrNotFound) {
+			code = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	ServeImage(w, m, f, h.Name)
+}
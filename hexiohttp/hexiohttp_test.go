@@ -0,0 +1,134 @@
+package hexiohttp
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestUpload(t *testing.T) {
+	fmt.Println("TestUpload()")
+
+	var got *image.Image
+	h := &UploadHandler{
+		MaxBytes: 64,
+		Handle: func(w http.ResponseWriter, r *http.Request, m *image.Image) {
+			got = m
+		},
+	}
+
+	cases := []struct {
+		body   string
+		status int
+	}{
+		{"\r\n:0500100048656C6C6FF7\r\n:00000001FF\r\n", http.StatusOK},
+		{"S1060010010203E3\nS9030000FC\n", http.StatusOK},
+		{":0500100048656C6C6FF0\n", http.StatusBadRequest},
+		{strings.Repeat(":00000001FF\n", 10), http.StatusRequestEntityTooLarge},
+	}
+	for _, c := range cases {
+		got = nil
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(c.body)))
+		if rec.Code != c.status || (c.status == http.StatusOK) != (got != nil) {
+			fmt.Printf("%q: status %d, %s\n", c.body, rec.Code, rec.Body)
+			t.Fail()
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	fmt.Println("TestDownload()")
+
+	m := image.New()
+	m.Write(0x10, []byte("Hello"))
+	h := &DownloadHandler{
+		Image:  func(*http.Request) (*image.Image, error) { return m, nil },
+		Format: image.IntelHex,
+		Name:   "fw.hex",
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != ":0500100048656C6C6FF7\n:00000001FF\n" ||
+		!strings.Contains(rec.Header().Get("Content-Disposition"), "fw.hex") {
+		fmt.Println(rec.Code, rec.Body, rec.Header())
+		t.Fail()
+	}
+
+	// Binary output fills gaps, so clients get it only when offered
+	for _, c := range []struct {
+		formats []image.Format
+		query   string
+		code    int
+	}{
+		{nil, "binary", http.StatusBadRequest},
+		{nil, "nonesuch", http.StatusBadRequest},
+		{nil, "srec", http.StatusOK},
+		{[]image.Format{image.Binary}, "binary", http.StatusOK},
+		{[]image.Format{image.Binary}, "srec", http.StatusBadRequest},
+		{[]image.Format{image.Binary}, "ihex", http.StatusOK},
+	} {
+		h.Formats = c.formats
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/?format="+c.query, nil))
+		if rec.Code != c.code {
+			fmt.Println(c.formats, c.query, rec.Code, rec.Body)
+			t.Fail()
+		}
+	}
+
+	h.Formats = []image.Format{image.Binary}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/?format=binary", nil))
+	if rec.Body.String() != "Hello" || rec.Header().Get("Content-Type") != "application/octet-stream" {
+		fmt.Println(rec.Code, rec.Body, rec.Header())
+		t.Fail()
+	}
+}
+
+func TestDownloadErrors(t *testing.T) {
+	fmt.Println("TestDownloadErrors()")
+
+	for _, c := range []struct {
+		err  error
+		code int
+	}{
+		{ErrNotFound, http.StatusNotFound},
+		{fmt.Errorf("image 7: %w", ErrNotFound), http.StatusNotFound},
+		{errors.New("database down"), http.StatusInternalServerError},
+	} {
+		h := &DownloadHandler{
+			Image:  func(*http.Request) (*image.Image, error) { return nil, c.err },
+			Format: image.IntelHex,
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Code != c.code {
+			fmt.Println(c.err, rec.Code)
+			t.Fail()
+		}
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	fmt.Println("TestContentDisposition()")
+
+	m := image.New()
+	m.Write(0, []byte{1})
+	for _, name := range []string{"fw.hex", `a "quoted" name.hex`, "firmwäre.hex"} {
+		rec := httptest.NewRecorder()
+		ServeImage(rec, m, image.IntelHex, name)
+		_, params, err := mime.ParseMediaType(rec.Header().Get("Content-Disposition"))
+		if err != nil || params["filename"] != name {
+			fmt.Printf("%q: %q, %v\n", name, rec.Header().Get("Content-Disposition"), err)
+			t.Fail()
+		}
+	}
+}
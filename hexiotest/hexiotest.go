@@ -0,0 +1,71 @@
+// Package hexiotest provides helpers for testing code that produces or
+// consumes hex files, so downstream projects need not copy boilerplate
+// from this repository's own tests.
+package hexiotest
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+)
+
+// Options controls a RoundTrip.  Encode and Decode select the codec the
+// data is pushed through; when nil, Intel Hex is used.
+type Options struct {
+	Address uint32                                // Load address of the data
+	Encode  func(io.Writer, *image.Image) error   // Codec encoder, e.g. srec.Encode
+	Decode  func(io.Reader) (*image.Image, error) // Codec decoder, e.g. srec.Decode
+}
+
+// RoundTrip encodes data loaded at opts.Address, decodes the result and
+// fails the test if what comes back differs from what went in.  The
+// decoded image is returned for further checks.
+func RoundTrip(t testing.TB, data []byte, opts Options) *image.Image {
+	t.Helper()
+
+	enc, dec := opts.Encode, opts.Decode
+	if enc == nil {
+		enc = ihex.Encode
+	}
+	if dec == nil {
+		dec = ihex.Decode
+	}
+
+	want := image.New()
+	want.Write(opts.Address, data)
+
+	var buf bytes.Buffer
+	if err := enc(&buf, want); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	got, err := dec(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	AssertImagesEqual(t, want, got)
+	return got
+}
+
+// AssertImagesEqual fails the test if the two images do not hold the same
+// data at the same addresses, listing the differing address ranges.
+func AssertImagesEqual(t testing.TB, want, got *image.Image) {
+	t.Helper()
+
+	if err := image.RequireEqual(want, got); err != nil {
+		t.Errorf("%v\n(a: want, b: got)", err)
+	}
+}
+
+// Diff returns a readable description of how got differs from want, or
+// the empty string if the images are identical.
+func Diff(want, got *image.Image) string {
+	if err := image.RequireEqual(want, got); err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package hexiotest
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+	ihex "github.com/peteArnt/GoHexIO/intel"
+	"github.com/peteArnt/GoHexIO/plainhex"
+	"github.com/peteArnt/GoHexIO/signetics"
+	"github.com/peteArnt/GoHexIO/srec"
+)
+
+func TestRoundTrip(t *testing.T) {
+	fmt.Println("TestRoundTrip()")
+
+	data := make([]byte, 100000)
+	rand.Read(data)
+
+	RoundTrip(t, data, Options{Address: 0x0800FF00})
+	RoundTrip(t, data, Options{Address: 0x0800FF00, Encode: srec.Encode, Decode: srec.Decode})
+}
+
+func TestImageEncode(t *testing.T) {
+	fmt.Println("TestImageEncode()")
+
+	data := make([]byte, 1000)
+	rand.Read(data)
+
+	formats := []struct {
+		f      image.Format
+		decode func(io.Reader) (*image.Image, error)
+	}{
+		{image.IntelHex, ihex.Decode},
+		{image.SRecord, srec.Decode},
+		{image.Signetics, signetics.Decode},
+		{image.PlainHex, plainhex.Decode},
+	}
+	for _, c := range formats {
+		encode := func(w io.Writer, m *image.Image) error {
+			return m.Encode(w, c.f, image.WithWidth(20))
+		}
+		RoundTrip(t, data, Options{Address: 0x1234, Encode: encode, Decode: c.decode})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	fmt.Println("TestDiff()")
+
+	a, b := image.New(), image.New()
+	a.Write(0x100, []byte{1, 2, 3, 4})
+	b.Write(0x100, []byte{1, 9, 9, 4, 5})
+
+	d := Diff(a, b)
+	if !strings.Contains(d, "0x00000101-0x00000102") || !strings.Contains(d, "a 00000104  -- ") {
+		fmt.Printf("unexpected diff:\n%s", d)
+		t.Fail()
+	}
+	if Diff(a, a) != "" {
+		fmt.Println("image differs from itself")
+		t.Fail()
+	}
+}
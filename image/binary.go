@@ -0,0 +1,149 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteBinary writes the image to w as one contiguous raw binary running
+// from the lowest to the highest populated address, with gaps set to
+// fill.  The address of the first byte written is returned.
+func (m *Image) WriteBinary(w io.Writer, fill byte) (base uint32, err error) {
+	if len(m.segs) == 0 {
+		return 0, nil
+	}
+
+	base = m.segs[0].Address
+	next := uint64(base)
+
+	for _, s := range m.segs {
+		if gap := uint64(s.Address) - next; gap > 0 {
+			if err := writeFill(w, fill, gap); err != nil {
+				return base, err
+			}
+		}
+		if _, err := w.Write(s.Data); err != nil {
+			return base, err
+		}
+		next = s.End()
+	}
+
+	return base, nil
+}
+
+// WriteSparseBinary is WriteBinary leaving the gaps as holes: w is
+// seeked past them rather than written, so a file gets no blocks
+// allocated for large gaps on file systems supporting sparse files, and
+// they read back as zeros.  Gaps are skipped relative to the current
+// offset of w.
+func (m *Image) WriteSparseBinary(w io.WriteSeeker) (base uint32, err error) {
+	if len(m.segs) == 0 {
+		return 0, nil
+	}
+
+	base = m.segs[0].Address
+	next := uint64(base)
+
+	for _, s := range m.segs {
+		if gap := uint64(s.Address) - next; gap > 0 {
+			if _, err := w.Seek(int64(gap), io.SeekCurrent); err != nil {
+				return base, err
+			}
+		}
+		if _, err := w.Write(s.Data); err != nil {
+			return base, err
+		}
+		next = s.End()
+	}
+
+	return base, nil
+}
+
+// Write n copies of b without materializing a buffer of size n
+func writeFill(w io.Writer, b byte, n uint64) error {
+	chunk := bytes.Repeat([]byte{b}, int(min(n, 32*1024)))
+	for n > 0 {
+		k := min(n, uint64(len(chunk)))
+		if _, err := w.Write(chunk[:k]); err != nil {
+			return err
+		}
+		n -= k
+	}
+	return nil
+}
+
+// WriteBinaryFiles writes each contiguous segment of the image to its own
+// raw binary file in dir, named <prefix>_<ADDRESS>.bin with the base
+// address in hex.  This avoids enormous padded outputs for images whose
+// regions are far apart.  The names of the files created are returned.
+func (m *Image) WriteBinaryFiles(dir, prefix string) ([]string, error) {
+	var names []string
+
+	for _, s := range m.segs {
+		fn := filepath.Join(dir, fmt.Sprintf("%s_%08X.bin", prefix, s.Address))
+		if err := os.WriteFile(fn, s.Data, 0644); err != nil {
+			return names, err
+		}
+		names = append(names, fn)
+	}
+
+	return names, nil
+}
+
+// LoadBinaryAt reads raw binary data from r until EOF and stores it in
+// the image starting at addr, returning the number of bytes loaded.
+func (m *Image) LoadBinaryAt(addr uint32, r io.Reader) (int64, error) {
+	var (
+		buf  = make([]byte, 32*1024)
+		next = uint64(addr)
+	)
+
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if next+uint64(n) > 1<<32 {
+				return int64(next - uint64(addr)), errors.New("binary data exceeds the 32-bit address space")
+			}
+			m.Write(uint32(next), buf[:n])
+			next += uint64(n)
+		}
+
+		if err == io.EOF {
+			return int64(next - uint64(addr)), nil
+		}
+		if err != nil {
+			return int64(next - uint64(addr)), err
+		}
+	}
+}
+
+// BinaryFile names a raw binary file and the address it is to be loaded at
+type BinaryFile struct {
+	Name    string
+	Address uint32
+}
+
+// LoadBinaryFiles loads each of files into the image in turn, so images
+// can be composed from separately built binaries, e.g. a bootloader at
+// 0x0 and an application at 0x8000.  Where files overlap the later one
+// wins.
+func (m *Image) LoadBinaryFiles(files ...BinaryFile) error {
+	for _, bf := range files {
+		f, err := os.Open(bf.Name)
+		if err != nil {
+			return err
+		}
+
+		_, err = m.LoadBinaryAt(bf.Address, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", bf.Name, err)
+		}
+	}
+
+	return nil
+}
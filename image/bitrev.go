@@ -0,0 +1,56 @@
+package image
+
+import (
+	"io"
+	"math/bits"
+)
+
+// ReverseBits reverses the bit order of every data byte in the image, bit
+// 7 becoming bit 0 and so on.  Xilinx SPI flash (MCS) images, for one,
+// expect the opposite bit ordering to what most toolchains produce.
+func (m *Image) ReverseBits() {
+	for _, s := range m.segs {
+		reverseBits(s.Data)
+	}
+}
+
+func reverseBits(p []byte) {
+	for i, b := range p {
+		p[i] = bits.Reverse8(b)
+	}
+}
+
+type bitReverseReader struct {
+	r io.Reader
+}
+
+// NewBitReverseReader returns a reader that yields the bytes of r with
+// their bit order reversed
+func NewBitReverseReader(r io.Reader) io.Reader {
+	return &bitReverseReader{r: r}
+}
+
+func (x *bitReverseReader) Read(p []byte) (int, error) {
+	n, err := x.r.Read(p)
+	reverseBits(p[:n])
+	return n, err
+}
+
+type bitReverseWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewBitReverseWriter returns a writer that reverses the bit order of
+// each byte before passing it on to w.  Placed in front of a hex record
+// writer it produces bit reversed images; the caller's buffer is left
+// untouched.
+func NewBitReverseWriter(w io.Writer) io.Writer {
+	return &bitReverseWriter{w: w}
+}
+
+func (x *bitReverseWriter) Write(p []byte) (int, error) {
+	x.buf = append(x.buf[:0], p...)
+	reverseBits(x.buf)
+	return x.w.Write(x.buf)
+}
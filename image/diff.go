@@ -0,0 +1,174 @@
+package image
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Difference is a run of consecutive addresses at which two images
+// disagree, either in value or in whether the address is populated
+type Difference struct {
+	Address  uint32
+	A, B     []byte // Bytes of each image over the run
+	InA, InB []bool // Whether each byte is populated in each image
+}
+
+// Len returns the number of addresses in the run
+func (d Difference) Len() int {
+	return len(d.A)
+}
+
+// Compare returns the runs of addresses at which images a and b differ,
+// in ascending address order
+func Compare(a, b *Image) []Difference {
+	var diffs []Difference
+
+	// Every address populated in either image
+	union := New()
+	for _, m := range []*Image{a, b} {
+		for _, s := range m.segs {
+			union.Write(s.Address, s.Data)
+		}
+	}
+
+	for _, u := range union.segs {
+		av, aOK := a.view(u.Address, len(u.Data))
+		bv, bOK := b.view(u.Address, len(u.Data))
+		same := func(i int) bool { return aOK[i] == bOK[i] && av[i] == bv[i] }
+
+		for i := 0; i < len(u.Data); {
+			if same(i) {
+				i++
+				continue
+			}
+
+			j := i + 1
+			for j < len(u.Data) && !same(j) {
+				j++
+			}
+
+			diffs = append(diffs, Difference{
+				Address: u.Address + uint32(i),
+				A:       av[i:j], B: bv[i:j],
+				InA: aOK[i:j], InB: bOK[i:j],
+			})
+			i = j
+		}
+	}
+
+	return diffs
+}
+
+// Bytes of m in [addr, addr+n) along with which of them are populated
+func (m *Image) view(addr uint32, n int) ([]byte, []bool) {
+	var (
+		data    = make([]byte, n)
+		present = make([]bool, n)
+		start   = uint64(addr)
+		end     = start + uint64(n)
+	)
+
+	i := sort.Search(len(m.segs), func(k int) bool { return m.segs[k].End() > start })
+	for ; i < len(m.segs) && uint64(m.segs[i].Address) < end; i++ {
+		s := m.segs[i]
+		lo := max(uint64(s.Address), start)
+		hi := min(s.End(), end)
+		copy(data[lo-start:hi-start], s.Data[lo-uint64(s.Address):])
+		for k := lo; k < hi; k++ {
+			present[k-start] = true
+		}
+	}
+
+	return data, present
+}
+
+// Limits on the size of a RequireEqual report
+const (
+	maxReportDiffs    = 10
+	maxReportSegments = 8
+	bytesPerLine      = 16
+)
+
+// RequireEqual returns nil if a and b hold the same data at the same
+// addresses.  Otherwise the error message summarizes the segments of
+// both images and lists the differing ranges, each rendered in hex with
+// an ASCII gutter; unpopulated bytes show as "--".  It is intended for
+// tests and CI gates.
+func RequireEqual(a, b *Image) error {
+	diffs := Compare(a, b)
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "images differ in %d range(s)\n", len(diffs))
+	fmt.Fprintf(&sb, "a: %s\n", summarize(a))
+	fmt.Fprintf(&sb, "b: %s\n", summarize(b))
+
+	for n, d := range diffs {
+		if n == maxReportDiffs {
+			fmt.Fprintf(&sb, "... %d more\n", len(diffs)-n)
+			break
+		}
+
+		fmt.Fprintf(&sb, "0x%08X-0x%08X (%d bytes):\n",
+			d.Address, d.Address+uint32(d.Len()-1), d.Len())
+		for off := 0; off < d.Len(); off += bytesPerLine {
+			if off == 4*bytesPerLine {
+				sb.WriteString("  ...\n")
+				break
+			}
+			end := min(off+bytesPerLine, d.Len())
+			fmt.Fprintf(&sb, "  a %08X  %s\n", d.Address+uint32(off), hexLine(d.A[off:end], d.InA[off:end]))
+			fmt.Fprintf(&sb, "  b %08X  %s\n", d.Address+uint32(off), hexLine(d.B[off:end], d.InB[off:end]))
+		}
+	}
+
+	return fmt.Errorf("%s", strings.TrimSuffix(sb.String(), "\n"))
+}
+
+// HexASCII renders up to 16 bytes as one hexdump style line: the bytes in
+// hex, padded to a fixed width, followed by an ASCII gutter in which
+// non-printable bytes show as '.'.
+func HexASCII(b []byte) string {
+	present := make([]bool, len(b))
+	for i := range present {
+		present[i] = true
+	}
+	return hexLine(b, present)
+}
+
+// One line of hex bytes followed by an ASCII gutter
+func hexLine(b []byte, present []bool) string {
+	var hx, asc strings.Builder
+
+	for i, v := range b {
+		switch {
+		case !present[i]:
+			hx.WriteString("-- ")
+			asc.WriteByte(' ')
+		case v >= 0x20 && v < 0x7F:
+			fmt.Fprintf(&hx, "%02X ", v)
+			asc.WriteByte(v)
+		default:
+			fmt.Fprintf(&hx, "%02X ", v)
+			asc.WriteByte('.')
+		}
+	}
+
+	return fmt.Sprintf("%-*s |%s|", 3*bytesPerLine, hx.String(), asc.String())
+}
+
+// Segment count, total size and the first few segment ranges
+func summarize(m *Image) string {
+	parts := []string{fmt.Sprintf("%d segment(s), %d bytes", len(m.segs), m.Len())}
+	for i, s := range m.segs {
+		if i == maxReportSegments {
+			parts = append(parts, "...")
+			break
+		}
+		parts = append(parts, fmt.Sprintf("[0x%08X-0x%08X]", s.Address, s.End()-1))
+	}
+	return strings.Join(parts, " ")
+}
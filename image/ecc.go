@@ -0,0 +1,158 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math/bits"
+)
+
+// ECC computes the check bytes protecting one block of data.  It must
+// return the same number of bytes for every block of a given size.
+type ECC func(block []byte) []byte
+
+// Provided ECC algorithms
+var (
+	// XORParity is one byte, the exclusive-or of the block
+	XORParity ECC = func(b []byte) []byte {
+		var p byte
+		for _, v := range b {
+			p ^= v
+		}
+		return []byte{p}
+	}
+
+	// SumParity is one byte, the 8-bit sum of the block
+	SumParity ECC = func(b []byte) []byte {
+		var p byte
+		for _, v := range b {
+			p += v
+		}
+		return []byte{p}
+	}
+
+	// ByteParity is one even parity bit per data byte, packed eight to a
+	// check byte with the first data byte's bit in bit 0
+	ByteParity ECC = func(b []byte) []byte {
+		p := make([]byte, (len(b)+7)/8)
+		for i, v := range b {
+			p[i/8] |= byte(bits.OnesCount8(v)&1) << (i % 8)
+		}
+		return p
+	}
+
+	// Hamming is a (72,64) SEC-DED Hamming code, as used by ECC memory:
+	// one check byte per 8 data bytes, correcting single and detecting
+	// double bit errors.  A short last group is padded with zeros.
+	Hamming ECC = func(b []byte) []byte {
+		p := make([]byte, (len(b)+7)/8)
+		for i := range p {
+			var group [8]byte
+			copy(group[:], b[8*i:])
+			p[i] = hamming72(group)
+		}
+		return p
+	}
+)
+
+// hamming72 returns the check byte of a 64-bit group: bits 0-6 are the
+// Hamming check bits, bit 7 the parity over all data and check bits.
+// Data bit k occupies the k-th position, counting from 1, that is not a
+// power of two; check bit j covers the positions with bit j set.
+func hamming72(group [8]byte) byte {
+	var check, pos, all byte
+	pos = 1
+	for _, v := range group {
+		for k := 0; k < 8; k++ {
+			pos++
+			for pos&(pos-1) == 0 {
+				pos++
+			}
+			if v>>k&1 != 0 {
+				check ^= pos
+				all ^= 1
+			}
+		}
+	}
+	all ^= byte(bits.OnesCount8(check) & 1)
+	return check | all<<7
+}
+
+// ECCOptions configures AddECC
+type ECCOptions struct {
+	Block int  // Data bytes per block; blocks are aligned to multiples of it
+	Code  ECC  // Check byte algorithm
+	Fill  byte // Value of the unpopulated bytes of a partly populated block
+
+	// Interleave places the check bytes of each block right after it,
+	// moving the data up to make room: block k starts at k times the
+	// block size plus check bytes.  Otherwise the check bytes of block k
+	// are collected in a table at Table plus k times their size, as for a
+	// separate parity memory.
+	Interleave bool
+	Table      uint32
+}
+
+// AddECC returns a copy of the image with the check bytes of every block
+// holding data added as configured by o.  Blocks without data get no
+// check bytes.
+func (m *Image) AddECC(o ECCOptions) (*Image, error) {
+	if o.Block < 1 || o.Code == nil {
+		return nil, errors.New("ECC needs a block size and an algorithm")
+	}
+	var (
+		n     = len(o.Code(make([]byte, o.Block)))
+		block = uint64(o.Block)
+		out   = New()
+	)
+	if !o.Interleave {
+		// A copy of the data, not sharing it with m
+		for s := range m.Regions() {
+			out.Write(s.Address, s.Data)
+		}
+	}
+
+	for k := range m.blocks(block) {
+		data := m.Extract(uint32(k*block), o.Block, o.Fill)
+		check := o.Code(data)
+		if len(check) != n {
+			return nil, fmt.Errorf("ECC returned %d check bytes for a block, not %d", len(check), n)
+		}
+
+		if o.Interleave {
+			at := k * (block + uint64(n))
+			if at+block+uint64(n) > 1<<32 {
+				return nil, fmt.Errorf("block at 0x%X moves out of the 32-bit address space", k*block)
+			}
+			out.Write(uint32(at), data)
+			out.Write(uint32(at+block), check)
+			continue
+		}
+
+		at := uint64(o.Table) + k*uint64(n)
+		if at+uint64(n) > 1<<32 {
+			return nil, fmt.Errorf("check bytes of block at 0x%X exceed the 32-bit address space", k*block)
+		}
+		if segs := m.Crop(uint32(at), uint32(n)).Segments(); len(segs) > 0 {
+			return nil, fmt.Errorf("check byte table overlaps data at 0x%X", segs[0].Address)
+		}
+		out.Write(uint32(at), check)
+	}
+	return out, nil
+}
+
+// blocks yields, in ascending order, the numbers of the blocks of the
+// given size holding data
+func (m *Image) blocks(size uint64) iter.Seq[uint64] {
+	return func(yield func(uint64) bool) {
+		next := uint64(0) // Lowest block number not yet yielded
+		for _, s := range m.segs {
+			for k := max(uint64(s.Address)/size, next); k*size < s.End(); k++ {
+				if !yield(k) {
+					return
+				}
+				next = k + 1
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package image
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Format names a text or binary serialization of memory images
+type Format string
+
+// Formats known to the GoHexIO packages.  Apart from Binary, which the
+// image package handles itself, a format is only available to Encode once
+// the package implementing it has been imported, if need be for its side
+// effect alone:
+//
+//	import _ "github.com/peteArnt/GoHexIO/srec"
+const (
+	IntelHex  Format = "ihex"
+	SRecord   Format = "srec"
+	Signetics Format = "signetics"
+	Fairbug   Format = "fairbug"
+	TekHex    Format = "tekhex"
+	PlainHex  Format = "plainhex"
+	Verilog   Format = "verilog"
+	Binary    Format = "binary"
+)
+
+// EncodeOptions carries the format independent settings of Encode.
+// Formats ignore settings that do not apply to them.
+type EncodeOptions struct {
+	Width int  // Data bytes per record or line, up to 255; zero selects the format's default
+	Fill  byte // Value of gap bytes in formats without addresses; 0xFF by default
+	Trim  bool // Drop runs of erased bytes, as Image.Trim does
+	Erase byte // Value of erased bytes for Trim
+
+	// DataOnly leaves out the records carrying no data, such as headers,
+	// counts and start addresses, where the format allows it
+	DataOnly bool
+
+	// Start is the execution start address, written by the formats
+	// carrying one if HasStart is set.  Images carry none of their own;
+	// without it only S-Records, which end in a start record, write one,
+	// for address 0.
+	Start    uint32
+	HasStart bool
+
+	// End is the address past the last data byte, 0 if not known up
+	// front.  Formats with several address sizes pick the smallest one
+	// reaching it.  Encode sets it from the image.
+	End uint64
+}
+
+// Option adjusts the EncodeOptions of a single Encode call
+type Option func(*EncodeOptions)
+
+// WithWidth sets the number of data bytes per record or line
+func WithWidth(n int) Option {
+	return func(o *EncodeOptions) { o.Width = n }
+}
+
+// WithFill sets the value of gap bytes in formats without addresses
+func WithFill(b byte) Option {
+	return func(o *EncodeOptions) { o.Fill = b }
+}
+
+// WithTrim drops the runs of bytes holding erase, as Image.Trim does,
+// from the output
+func WithTrim(erase byte) Option {
+	return func(o *EncodeOptions) { o.Trim, o.Erase = true, erase }
+}
+
+// WithDataOnly leaves out the records carrying no data, for loaders that
+// accept nothing else and for scrubbing build metadata
+func WithDataOnly() Option {
+	return func(o *EncodeOptions) { o.DataOnly = true }
+}
+
+// WithStart sets the execution start address written by the formats
+// carrying one
+func WithStart(addr uint32) Option {
+	return func(o *EncodeOptions) { o.Start, o.HasStart = addr, true }
+}
+
+// EncodeFunc serializes m to w in one particular format
+type EncodeFunc func(w io.Writer, m *Image, o EncodeOptions) error
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[Format]EncodeFunc{
+		Binary: func(w io.Writer, m *Image, o EncodeOptions) error {
+			_, err := m.WriteBinary(w, o.Fill)
+			return err
+		},
+	}
+)
+
+// RegisterEncoder makes format f available to Encode.  It is intended to
+// be called from the init function of the package implementing f;
+// registering a format a second time replaces the earlier encoder.
+func RegisterEncoder(f Format, enc EncodeFunc) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[f] = enc
+}
+
+// Encode writes the image to w in format f, so callers need not learn
+// the writer API of each format.  The output is complete, including any
+// terminating records the format requires.
+func (m *Image) Encode(w io.Writer, f Format, opts ...Option) error {
+	encodersMu.RLock()
+	enc, ok := encoders[f]
+	encodersMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown format %q", f)
+	}
+
+	o := EncodeOptions{Fill: 0xFF}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Width < 0 || o.Width > 255 {
+		return fmt.Errorf("width %d out of range 1..255", o.Width)
+	}
+	if o.Trim {
+		m = &Image{segs: m.Segments()}
+		m.Trim(o.Erase)
+	}
+	if segs := m.Segments(); len(segs) > 0 {
+		o.End = segs[len(segs)-1].End()
+	}
+
+	return enc(w, m, o)
+}
@@ -0,0 +1,153 @@
+// Package image provides a format independent model of a sparse memory
+// image: a set of non-overlapping, address ordered segments of data.  It
+// is the common ground the individual hex record formats read into and
+// write out of.
+package image
+
+import (
+	"iter"
+	"sort"
+)
+
+// Segment is a contiguous run of data bytes starting at Address
+type Segment struct {
+	Address uint32
+	Data    []byte
+}
+
+// End returns the address one past the last byte of the segment.  The
+// result is 64-bit so a segment reaching the top of the 32-bit address
+// space does not wrap.
+func (s Segment) End() uint64 {
+	return uint64(s.Address) + uint64(len(s.Data))
+}
+
+// Image is a sparse memory image.  Segments are kept sorted by address,
+// never overlap, and adjacent segments are merged.
+type Image struct {
+	segs []Segment
+}
+
+// New creates an empty memory image
+func New() *Image {
+	return new(Image)
+}
+
+// Write stores p at address addr.  Data already present at overlapping
+// addresses is overwritten; segments that end up touching are merged.
+func (m *Image) Write(addr uint32, p []byte) {
+	if len(p) == 0 {
+		return
+	}
+
+	var (
+		start = uint64(addr)
+		end   = start + uint64(len(p))
+	)
+
+	// Range [i, j) of segments overlapping or adjacent to the new data
+	i := sort.Search(len(m.segs), func(k int) bool { return m.segs[k].End() >= start })
+	j := i
+	for j < len(m.segs) && uint64(m.segs[j].Address) <= end {
+		j++
+	}
+
+	// Nothing touched; insert a fresh segment
+	if i == j {
+		seg := Segment{Address: addr, Data: append([]byte(nil), p...)}
+		m.segs = append(m.segs, Segment{})
+		copy(m.segs[i+1:], m.segs[i:])
+		m.segs[i] = seg
+		return
+	}
+
+	// Common case when loading records in order: a straight append
+	if j == i+1 && m.segs[i].End() == start {
+		m.segs[i].Data = append(m.segs[i].Data, p...)
+		return
+	}
+
+	// General case; merge everything in [i, j) with the new data
+	lo := min(uint64(m.segs[i].Address), start)
+	hi := max(m.segs[j-1].End(), end)
+	buf := make([]byte, hi-lo)
+	for _, s := range m.segs[i:j] {
+		copy(buf[uint64(s.Address)-lo:], s.Data)
+	}
+	copy(buf[start-lo:], p)
+
+	m.segs[i] = Segment{Address: uint32(lo), Data: buf}
+	m.segs = append(m.segs[:i+1], m.segs[j:]...)
+}
+
+// Segments returns the segments of the image in ascending address order.
+// The data slices are shared with the image.
+func (m *Image) Segments() []Segment {
+	return append([]Segment(nil), m.segs...)
+}
+
+// Regions returns an iterator over the populated regions of the image,
+// as segments in ascending address order.  As with Segments, the data
+// is shared with the image.
+func (m *Image) Regions() iter.Seq[Segment] {
+	return func(yield func(Segment) bool) {
+		for _, s := range m.segs {
+			if !yield(s) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the total number of data bytes held in the image
+func (m *Image) Len() int {
+	var n int
+	for _, s := range m.segs {
+		n += len(s.Data)
+	}
+	return n
+}
+
+// Flatten renders the image into a single contiguous buffer spanning the
+// lowest to the highest populated address, with gaps set to fill.  An
+// empty image yields a nil buffer.
+func (m *Image) Flatten(fill byte) (base uint32, data []byte) {
+	if len(m.segs) == 0 {
+		return 0, nil
+	}
+
+	base = m.segs[0].Address
+	data = make([]byte, m.segs[len(m.segs)-1].End()-uint64(base))
+	for i := range data {
+		data[i] = fill
+	}
+	for _, s := range m.segs {
+		copy(data[s.Address-base:], s.Data)
+	}
+
+	return base, data
+}
+
+// Extract returns length bytes of the image starting at addr.  Addresses
+// not covered by any segment are set to fill.
+func (m *Image) Extract(addr uint32, length int, fill byte) []byte {
+	var (
+		buf   = make([]byte, length)
+		start = uint64(addr)
+		end   = start + uint64(length)
+	)
+
+	for i := range buf {
+		buf[i] = fill
+	}
+
+	i := sort.Search(len(m.segs), func(k int) bool { return m.segs[k].End() > start })
+	for ; i < len(m.segs) && uint64(m.segs[i].Address) < end; i++ {
+		s := m.segs[i]
+		lo := max(uint64(s.Address), start)
+		hi := min(s.End(), end)
+		copy(buf[lo-start:hi-start], s.Data[lo-uint64(s.Address):])
+	}
+
+	return buf
+}
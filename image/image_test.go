@@ -0,0 +1,436 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteMerge(t *testing.T) {
+	fmt.Println("TestWriteMerge()")
+
+	m := New()
+	m.Write(0x10, []byte{1, 2, 3, 4})
+	m.Write(0x14, []byte{5, 6}) // append
+	m.Write(0x00, []byte{9})    // separate segment below
+	m.Write(0x20, []byte{7})    // separate segment above
+	m.Write(0x12, []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88})
+
+	segs := m.Segments()
+	if len(segs) != 2 {
+		fmt.Printf("expected 2 segments, got %v\n", segs)
+		t.FailNow()
+	}
+
+	want := []byte{1, 2, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 7}
+	if segs[1].Address != 0x10 || !bytes.Equal(segs[1].Data, want) {
+		fmt.Printf("bad merged segment: %v\n", segs[1])
+		t.Fail()
+	}
+
+	base, flat := m.Flatten(0xFF)
+	if base != 0 || len(flat) != 0x21 || flat[1] != 0xFF || flat[0x20] != 7 {
+		fmt.Printf("bad flattened image: base=%#x len=%d\n", base, len(flat))
+		t.Fail()
+	}
+}
+
+func TestReverseBits(t *testing.T) {
+	fmt.Println("TestReverseBits()")
+
+	m := New()
+	m.Write(0, []byte{0x01, 0x80, 0x0F, 0xA5})
+	m.ReverseBits()
+
+	if !bytes.Equal(m.Extract(0, 4, 0), []byte{0x80, 0x01, 0xF0, 0xA5}) {
+		fmt.Println("failure: bad bit reversal")
+		t.Fail()
+	}
+
+	var buf bytes.Buffer
+	w := NewBitReverseWriter(&buf)
+	src := []byte{0x01, 0x02}
+	w.Write(src)
+	if src[0] != 0x01 || !bytes.Equal(buf.Bytes(), []byte{0x80, 0x40}) {
+		fmt.Println("failure: bad bit reverse writer")
+		t.Fail()
+	}
+}
+
+func TestWriteBinary(t *testing.T) {
+	fmt.Println("TestWriteBinary()")
+
+	m := New()
+	m.Write(0x100, []byte{1, 2})
+	m.Write(0x104, []byte{3})
+
+	var buf bytes.Buffer
+	base, err := m.WriteBinary(&buf, 0xEE)
+	if err != nil || base != 0x100 || !bytes.Equal(buf.Bytes(), []byte{1, 2, 0xEE, 0xEE, 3}) {
+		fmt.Printf("failure: base=%#x data=%v err=%v\n", base, buf.Bytes(), err)
+		t.Fail()
+	}
+
+	names, err := m.WriteBinaryFiles(t.TempDir(), "fw")
+	if err != nil || len(names) != 2 {
+		fmt.Printf("failure: names=%v err=%v\n", names, err)
+		t.Fail()
+	}
+}
+
+func TestWriteSparseBinary(t *testing.T) {
+	fmt.Println("TestWriteSparseBinary()")
+
+	m := New()
+	m.Write(0x100, []byte{1, 2})
+	m.Write(0x10104, []byte{3})
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "fw.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	base, err := m.WriteSparseBinary(f)
+	if err != nil || base != 0x100 {
+		fmt.Printf("failure: base=%#x err=%v\n", base, err)
+		t.FailNow()
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil || len(b) != 0x10005 || b[0] != 1 || b[1] != 2 || b[0x10004] != 3 || b[0x8000] != 0 {
+		fmt.Printf("failure: %d bytes read back, err=%v\n", len(b), err)
+		t.Fail()
+	}
+}
+
+func TestLoadBinaryAt(t *testing.T) {
+	fmt.Println("TestLoadBinaryAt()")
+
+	m := New()
+	n, err := m.LoadBinaryAt(0x8000, bytes.NewReader([]byte{1, 2, 3}))
+	if err != nil || n != 3 || !bytes.Equal(m.Extract(0x8000, 3, 0), []byte{1, 2, 3}) {
+		fmt.Printf("failure: n=%d err=%v\n", n, err)
+		t.Fail()
+	}
+
+	if _, err := m.LoadBinaryAt(0xFFFFFFFF, bytes.NewReader([]byte{1, 2})); err == nil {
+		fmt.Println("failure: load past 4G accepted")
+		t.Fail()
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	fmt.Println("TestGenerate()")
+
+	m := Generate(AddressEcho, 0x12345678, 8)
+	if !bytes.Equal(m.Extract(0x12345678, 8, 0), []byte{0x78, 0x56, 0x34, 0x12, 0x7C, 0x56, 0x34, 0x12}) {
+		fmt.Println("failure: bad address echo pattern")
+		t.Fail()
+	}
+
+	// First SplitMix64 output for seed 0 is 0xE220A8397B1DCDAF
+	m = Generate(PRNG(0), 0, 8)
+	if !bytes.Equal(m.Extract(0, 8, 0), []byte{0xAF, 0xCD, 0x1D, 0x7B, 0x39, 0xA8, 0x20, 0xE2}) {
+		fmt.Printf("failure: bad PRNG pattern % X\n", m.Extract(0, 8, 0))
+		t.Fail()
+	}
+
+	if Generate(Incrementing, 0xFFFFFFF0, 0x100).Len() != 0x10 {
+		fmt.Println("failure: pattern ran past 4G")
+		t.Fail()
+	}
+}
+
+func TestRequireEqual(t *testing.T) {
+	fmt.Println("TestRequireEqual()")
+
+	a, b := New(), New()
+	a.Write(0x10, []byte("Hello"))
+	b.Write(0x10, []byte("Hallo"))
+
+	if err := RequireEqual(a, a); err != nil {
+		fmt.Println("failure: image differs from itself")
+		t.Fail()
+	}
+
+	diffs := Compare(a, b)
+	if len(diffs) != 1 || diffs[0].Address != 0x11 || diffs[0].Len() != 1 {
+		fmt.Printf("failure: bad differences %v\n", diffs)
+		t.Fail()
+	}
+
+	err := RequireEqual(a, b)
+	if err == nil || !strings.Contains(err.Error(), "|e|") || !strings.Contains(err.Error(), "|a|") {
+		fmt.Printf("failure: unexpected report %v\n", err)
+		t.Fail()
+	}
+}
+
+func TestEncodeBinary(t *testing.T) {
+	fmt.Println("TestEncodeBinary()")
+
+	m := New()
+	m.Write(0x100, []byte{1, 2})
+	m.Write(0x104, []byte{3})
+
+	var buf bytes.Buffer
+	if err := m.Encode(&buf, Binary, WithFill(0)); err != nil {
+		fmt.Println(err)
+		t.FailNow()
+	}
+	if !bytes.Equal(buf.Bytes(), []byte{1, 2, 0, 0, 3}) {
+		fmt.Printf("got % X\n", buf.Bytes())
+		t.Fail()
+	}
+
+	if m.Encode(&buf, Format("no such format")) == nil {
+		fmt.Println("unknown format accepted")
+		t.Fail()
+	}
+}
+
+func TestSplitLanes(t *testing.T) {
+	fmt.Println("TestSplitLanes()")
+
+	m := New()
+	m.Write(0x101, []byte{1, 2, 3, 4, 5})
+	m.Write(0x200, []byte{6, 7})
+
+	lanes := m.SplitLanes(2)
+	even, odd := lanes[0].Segments(), lanes[1].Segments()
+	if len(even) != 2 || even[0].Address != 0x81 || !bytes.Equal(even[0].Data, []byte{2, 4}) ||
+		even[1].Address != 0x100 || !bytes.Equal(even[1].Data, []byte{6}) {
+		fmt.Printf("even lane: %v\n", even)
+		t.Fail()
+	}
+	if len(odd) != 2 || odd[0].Address != 0x80 || !bytes.Equal(odd[0].Data, []byte{1, 3, 5}) {
+		fmt.Printf("odd lane: %v\n", odd)
+		t.Fail()
+	}
+
+	for _, n := range []int{1, 2, 3, 4} {
+		got, err := CombineLanes(m.SplitLanes(n)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := RequireEqual(m, got); err != nil {
+			fmt.Printf("%d lanes: %v\n", n, err)
+			t.Fail()
+		}
+	}
+
+	for _, stride := range []int{1, 2, 3, 16} {
+		for _, chips := range []int{1, 2, 4} {
+			got, err := Interleave(stride, m.Deinterleave(chips, stride)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := RequireEqual(m, got); err != nil {
+				fmt.Printf("%d chips, stride %d: %v\n", chips, stride, err)
+				t.Fail()
+			}
+		}
+	}
+
+	// Two 16-bit chips on a 32-bit bus
+	chips := m.Deinterleave(2, 2)
+	if got := chips[0].Extract(0x80, 4, 0); !bytes.Equal(got, []byte{0, 1, 4, 5}) {
+		fmt.Printf("chip 0: % X\n", got)
+		t.Fail()
+	}
+	if got := chips[1].Extract(0x80, 4, 0); !bytes.Equal(got, []byte{2, 3, 0, 0}) {
+		fmt.Printf("chip 1: % X\n", got)
+		t.Fail()
+	}
+
+	big := New()
+	big.Write(0x80000000, []byte{1})
+	if _, err := CombineLanes(big, New()); err == nil {
+		fmt.Println("overflow not detected")
+		t.Fail()
+	}
+}
+
+func TestFill(t *testing.T) {
+	fmt.Println("TestFill()")
+
+	m := New()
+	m.Write(0x11, []byte{1, 2})
+	p, err := ParsePattern("0xDEADBEEF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Fill(0x10, 8, p)
+	if got := m.Extract(0x10, 8, 0); !bytes.Equal(got, []byte{0xDE, 1, 2, 0xEF, 0xDE, 0xAD, 0xBE, 0xEF}) {
+		fmt.Printf("failure: bad repeated fill % X\n", got)
+		t.Fail()
+	}
+
+	m = New()
+	m.Fill(0xFFFFFFFE, 4, Incrementing)
+	if got := m.Extract(0xFFFFFFFE, 2, 0); m.Len() != 2 || !bytes.Equal(got, []byte{0xFE, 0xFF}) {
+		fmt.Printf("failure: bad computed fill % X\n", got)
+		t.Fail()
+	}
+
+	for _, s := range []string{"0xF", "incrementing", "address-echo", "prng:7"} {
+		if _, err := ParsePattern(s); err != nil {
+			fmt.Println("failure:", err)
+			t.Fail()
+		}
+	}
+	for _, s := range []string{"", "0x", "0xXY", "ramp", "prng:x"} {
+		if _, err := ParsePattern(s); err == nil {
+			fmt.Printf("failure: pattern %q accepted\n", s)
+			t.Fail()
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	fmt.Println("TestTrim()")
+
+	ff := func(n int) []byte { return bytes.Repeat([]byte{0xFF}, n) }
+
+	m := New()
+	m.Write(0x000, ff(64))                                      // Erased segment
+	m.Write(0x100, append(append(ff(3), 1, 0xFF, 2), ff(2)...)) // Erased ends, short run kept
+	m.Write(0x200, append(append([]byte{1}, ff(16)...), 2))     // Long run dropped
+	m.Trim(0xFF)
+
+	want := []Segment{
+		{0x103, []byte{1, 0xFF, 2}},
+		{0x200, []byte{1}},
+		{0x211, []byte{2}},
+	}
+	if fmt.Sprint(m.Segments()) != fmt.Sprint(want) {
+		fmt.Printf("failure: got %v\n", m.Segments())
+		t.Fail()
+	}
+
+	// Growing a trimmed segment leaves its neighbour alone
+	m.Write(0x201, []byte{3, 4})
+	if m.Extract(0x211, 1, 0)[0] != 2 {
+		fmt.Println("failure: trimmed segments share storage")
+		t.Fail()
+	}
+
+	// The encoder option leaves the image itself untouched
+	m = New()
+	m.Write(0, append(ff(20), 5))
+	var b bytes.Buffer
+	if err := m.Encode(&b, Binary, WithTrim(0xFF), WithFill(0)); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 1 || m.Len() != 21 {
+		fmt.Printf("failure: encoded % X from %d bytes\n", b.Bytes(), m.Len())
+		t.Fail()
+	}
+}
+
+func TestPad(t *testing.T) {
+	fmt.Println("TestPad()")
+
+	m := New()
+	m.Write(0x102, []byte{1, 2})
+	m.Write(0x10E, []byte{3, 4, 5}) // Spans two blocks
+	m.Write(0x140, []byte{6})
+
+	want := []Segment{
+		{0x100, []byte{
+			0xFF, 0xFF, 1, 2, 0xFF, 0xFF, 0xFF, 0xFF,
+			0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 3, 4,
+			5, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+		}},
+		{0x140, []byte{6, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+	}
+	got := m.Pad(8, 0xFF)
+	if fmt.Sprint(got.Segments()) != fmt.Sprint(want) {
+		fmt.Printf("failure: got %v\n", got.Segments())
+		t.Fail()
+	}
+	if m.Len() != 6 {
+		fmt.Println("failure: padding changed the image")
+		t.Fail()
+	}
+}
+
+func TestAddECC(t *testing.T) {
+	fmt.Println("TestAddECC()")
+
+	m := New()
+	m.Write(0x10, []byte{1, 2, 3, 4, 5, 6}) // Blocks 4 and 5, the latter partly
+	m.Write(0x40, []byte{0xFF})             // Block 16
+
+	out, err := m.AddECC(ECCOptions{Block: 4, Code: XORParity, Interleave: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Segment{
+		{0x14, []byte{1, 2, 3, 4, 4, 5, 6, 0, 0, 3}},
+		{0x50, []byte{0xFF, 0, 0, 0, 0xFF}},
+	}
+	if fmt.Sprint(out.Segments()) != fmt.Sprint(want) {
+		fmt.Printf("failure: interleaved %v\n", out.Segments())
+		t.Fail()
+	}
+
+	out, err = m.AddECC(ECCOptions{Block: 4, Code: SumParity, Fill: 0xFF, Table: 0x1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Extract(0x1004, 13, 0); !bytes.Equal(got, []byte{10, 9, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xFC}) ||
+		out.Len() != m.Len()+3 {
+		fmt.Printf("failure: table % X\n", got)
+		t.Fail()
+	}
+
+	// The result shares no data with the source
+	out.ReverseBits()
+	out.Write(0x16, []byte{0xAA})
+	if got := m.Extract(0x10, 8, 0); !bytes.Equal(got, []byte{1, 2, 3, 4, 5, 6, 0, 0}) {
+		fmt.Printf("failure: source changed to % X\n", got)
+		t.Fail()
+	}
+
+	if _, err := m.AddECC(ECCOptions{Block: 4, Code: XORParity, Table: 0x11}); err == nil {
+		fmt.Println("failure: table over data accepted")
+		t.Fail()
+	}
+
+	// A single bit error yields its position as the check bit syndrome
+	var group [8]byte
+	copy(group[:], "hamming!")
+	good := Hamming(group[:])[0]
+	group[0] ^= 1 // Data bit 0, position 3
+	if bad := Hamming(group[:])[0]; (good^bad)&0x7F != 3 || (good^bad)&0x80 == 0 {
+		fmt.Printf("failure: syndrome %02X\n", good^bad)
+		t.Fail()
+	}
+	// Known check bytes: a single data bit k yields the position of the
+	// k-th non-power of two as check bits, plus the overall parity
+	for _, c := range []struct {
+		data []byte
+		want []byte
+	}{
+		{make([]byte, 8), []byte{0x00}},
+		{[]byte{0x01}, []byte{0x83}},                      // Bit 0, position 3
+		{[]byte{0x02}, []byte{0x85}},                      // Bit 1, position 5
+		{[]byte{0, 0, 0, 0, 0, 0, 0, 0x80}, []byte{0xC7}}, // Bit 63, position 71
+		{[]byte{0x03}, []byte{0x06}},                      // Positions 3 and 5
+		{bytes.Repeat([]byte{0xFF}, 8), []byte{0xFF}},
+		{[]byte{0x01, 0, 0, 0, 0, 0, 0, 0, 0x02}, []byte{0x83, 0x85}},
+	} {
+		if got := Hamming(c.data); !bytes.Equal(got, c.want) {
+			fmt.Printf("failure: Hamming(% X) = % X, want % X\n", c.data, got, c.want)
+			t.Fail()
+		}
+	}
+	if got := ByteParity([]byte{1, 3, 7}); !bytes.Equal(got, []byte{0b101}) {
+		fmt.Printf("failure: byte parity % X\n", got)
+		t.Fail()
+	}
+}
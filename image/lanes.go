@@ -0,0 +1,140 @@
+package image
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SplitLanes splits the image into n byte lanes, as needed to program a
+// memory n bytes wide built from byte wide devices: lane k holds the
+// bytes at the addresses a with a%n == k, stored at a/n.  For a 16-bit
+// memory of two 8-bit chips, lane 0 holds the even and lane 1 the odd
+// bytes.  It is Deinterleave with a stride of 1.
+func (m *Image) SplitLanes(n int) []*Image {
+	return m.Deinterleave(n, 1)
+}
+
+// CombineLanes is the inverse of SplitLanes: it interleaves the bytes of
+// the lanes into one image, byte i of lane k going to address
+// i*len(lanes) + k.  It is Interleave with a stride of 1.
+func CombineLanes(lanes ...*Image) (*Image, error) {
+	return Interleave(1, lanes...)
+}
+
+// Deinterleave splits the image among chips devices that take turns
+// every stride bytes, as on boards whose ROM sockets each serve part of
+// a wider bus: the address space is cut into blocks of stride bytes, and
+// block b goes to chip b%chips, where it lands at (b/chips)*stride.  A
+// 32-bit bus built from two 16-bit EPROMs, for example, has 2 chips and
+// a stride of 2.  Deinterleave panics if chips or stride is less than 1.
+func (m *Image) Deinterleave(chips, stride int) []*Image {
+	if chips < 1 || stride < 1 {
+		panic("image: Deinterleave with fewer than 1 chip or stride")
+	}
+
+	var (
+		out     = make([]*Image, chips)
+		pending = make([]Segment, chips) // Run being collected per chip
+		n, st   = uint64(chips), uint64(stride)
+	)
+	for c := range out {
+		out[c] = New()
+	}
+
+	for _, s := range m.segs {
+		for a := uint64(s.Address); a < s.End(); {
+			var (
+				blk   = a / st
+				c     = blk % n
+				addr  = (blk/n)*st + a%st
+				chunk = min(st-a%st, s.End()-a)
+				data  = s.Data[a-uint64(s.Address) : a-uint64(s.Address)+chunk]
+				p     = &pending[c]
+			)
+
+			if p.Data != nil && p.End() != addr {
+				out[c].Write(p.Address, p.Data)
+				p.Data = nil
+			}
+			if p.Data == nil {
+				p.Address = uint32(addr)
+			}
+			p.Data = append(p.Data, data...)
+			a += chunk
+		}
+	}
+
+	for c, p := range pending {
+		out[c].Write(p.Address, p.Data)
+	}
+	return out
+}
+
+// Interleave is the inverse of Deinterleave: it merges the images of
+// chips taking turns every stride bytes into one.  It fails if data
+// would land beyond the 32-bit address space.  Interleave panics if
+// stride is less than 1.
+func Interleave(stride int, chips ...*Image) (*Image, error) {
+	if stride < 1 {
+		panic("image: Interleave with a stride less than 1")
+	}
+
+	var (
+		out   = New()
+		n, st = uint64(len(chips)), uint64(stride)
+		spans []Segment // Chip address ranges holding data in any chip
+	)
+
+	for c, chip := range chips {
+		for _, s := range chip.segs {
+			last := s.End() - 1
+			if ((last/st)*n+uint64(c))*st+last%st >= 1<<32 {
+				return nil, fmt.Errorf("data of chip %d at 0x%X exceeds the 32-bit address space", c, s.Address)
+			}
+			spans = append(spans, s)
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Address < spans[j].Address })
+
+	for len(spans) > 0 {
+		// Merge the overlapping or adjacent ranges starting the list,
+		// widened to whole blocks
+		lo, hi := uint64(spans[0].Address), spans[0].End()
+		for len(spans) > 0 && uint64(spans[0].Address) <= hi {
+			hi = max(hi, spans[0].End())
+			spans = spans[1:]
+		}
+		lo -= lo % st
+		hi += (st - hi%st) % st
+
+		var (
+			buf     = make([]byte, (hi-lo)*n)
+			present = make([]bool, len(buf))
+		)
+		for c, chip := range chips {
+			for _, s := range chip.Crop(uint32(lo), uint32(hi-lo)).segs {
+				for i, b := range s.Data {
+					x := uint64(s.Address) + uint64(i) - lo
+					at := ((x/st)*n+uint64(c))*st + x%st
+					buf[at], present[at] = b, true
+				}
+			}
+		}
+
+		// Emit the runs of present bytes
+		for i := 0; i < len(buf); {
+			if !present[i] {
+				i++
+				continue
+			}
+			j := i
+			for j < len(buf) && present[j] {
+				j++
+			}
+			out.Write(uint32(lo*n+uint64(i)), buf[i:j])
+			i = j
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,84 @@
+package image
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SpanKind classifies a Span found by Layout
+type SpanKind int
+
+// Kinds of Span
+const (
+	Gap     SpanKind = iota // Addresses without data between data
+	Overlap                 // Addresses provided by more than one run of data
+)
+
+// String is the idiomatic Go string-ize method
+func (k SpanKind) String() string {
+	switch k {
+	case Gap:
+		return "gap"
+	case Overlap:
+		return "overlap"
+	}
+	return fmt.Sprintf("SpanKind(%d)", int(k))
+}
+
+// Span is a range of the address space singled out by Layout
+type Span struct {
+	Start uint32
+	End   uint64 // Address one past the last byte
+	Kind  SpanKind
+}
+
+// Size returns the number of addresses the span covers
+func (s Span) Size() uint64 {
+	return s.End - uint64(s.Start)
+}
+
+// String is the idiomatic Go string-ize method
+func (s Span) String() string {
+	return fmt.Sprintf("%s 0x%08X-0x%08X (%d bytes)", s.Kind, s.Start, s.End-1, s.Size())
+}
+
+// Layout analyzes the address space covered by segs, such as the data
+// records of a file in file order, and returns in ascending order the
+// gaps between the lowest and highest address with data and the ranges
+// provided by more than one segment, however many.  Unlike an Image,
+// which lets later data win, segs may overlap; that is what Layout is
+// there to find.  Empty segments are ignored.
+func Layout(segs []Segment) []Span {
+	type edge struct {
+		addr  uint64
+		delta int // +1 where a segment starts, -1 past its end
+	}
+	var edges []edge
+	for _, s := range segs {
+		if len(s.Data) > 0 {
+			edges = append(edges, edge{uint64(s.Address), 1}, edge{s.End(), -1})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].addr < edges[j].addr })
+
+	var (
+		spans []Span
+		depth int // Segments covering the addresses from the previous edge on
+	)
+	for i, e := range edges {
+		if i > 0 && e.addr > edges[i-1].addr && depth != 1 {
+			kind := Gap
+			if depth > 1 {
+				kind = Overlap
+			}
+			from := edges[i-1].addr
+			if n := len(spans); n > 0 && spans[n-1].Kind == kind && spans[n-1].End == from {
+				spans[n-1].End = e.addr
+			} else {
+				spans = append(spans, Span{Start: uint32(from), End: e.addr, Kind: kind})
+			}
+		}
+		depth += e.delta
+	}
+	return spans
+}
@@ -0,0 +1,68 @@
+package image
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseLinkerMap reads the memory regions from a GNU ld map file, as
+// written by ld -Map, which lists them in a table following the
+// "Memory Configuration" heading:
+//
+//	Name             Origin             Length             Attributes
+//	FLASH            0x0000000008000000 0x0000000000080000 xr
+//	*default*        0x0000000000000000 0xffffffffffffffff
+//
+// The *default* catch-all region is left out.
+func ParseLinkerMap(r io.Reader) ([]Region, error) {
+	var (
+		regions []Region
+		found   bool // The Memory Configuration heading has been seen
+		table   bool // Within the region table
+	)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		f := strings.Fields(scanner.Text())
+		switch {
+		case !found:
+			found = len(f) == 2 && f[0] == "Memory" && f[1] == "Configuration"
+			continue
+		case !table:
+			table = len(f) > 0 && f[0] == "Name"
+			continue
+		case len(f) == 0 || f[0] == "Linker":
+			return regions, scanner.Err()
+		case f[0] == "*default*":
+			continue
+		case len(f) < 3:
+			return nil, fmt.Errorf("line %d: unrecognized region %q", n, scanner.Text())
+		}
+
+		start, err := strconv.ParseUint(f[1], 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad origin: %v", n, err)
+		}
+		size, err := strconv.ParseUint(f[2], 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad length: %v", n, err)
+		}
+		if start >= 1<<32 || size >= 1<<32 || size > 1<<32-start {
+			return nil, fmt.Errorf("line %d: region %s exceeds the 32-bit address space", n, f[0])
+		}
+
+		regions = append(regions, Region{Name: f[0], Start: uint32(start), Size: uint32(size)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, errors.New("no Memory Configuration in linker map")
+	}
+	return regions, nil
+}
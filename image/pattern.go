@@ -0,0 +1,115 @@
+package image
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pattern computes the value of the byte at a given address.  Patterns are
+// pure functions of the address, so any part of a pattern image can be
+// recomputed independently, e.g. by a hardware test rig checking readback.
+type Pattern func(addr uint32) byte
+
+// Incrementing yields the low byte of the address; an image starting on a
+// 256 byte boundary reads 00 01 02 ... FF 00 01 ...
+var Incrementing Pattern = func(addr uint32) byte {
+	return byte(addr)
+}
+
+// AddressEcho stores in each aligned 32-bit word its own address, little
+// endian.  Address line faults show up as words holding the wrong value.
+var AddressEcho Pattern = func(addr uint32) byte {
+	word := addr &^ 3
+	return byte(word >> (8 * (addr & 3)))
+}
+
+// PRNG returns a pseudo-random pattern determined by seed.  Each aligned
+// group of 8 bytes holds, little endian, output number addr/8 of the
+// SplitMix64 generator seeded with seed, so other tools can reproduce
+// the data without this package.
+func PRNG(seed uint64) Pattern {
+	return func(addr uint32) byte {
+		z := seed + (uint64(addr/8)+1)*0x9E3779B97F4A7C15
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		z ^= z >> 31
+		return byte(z >> (8 * (addr % 8)))
+	}
+}
+
+// Generate returns an image of size bytes starting at base, filled with
+// pattern p.  Anything that would lie beyond the top of the 32-bit
+// address space is dropped.
+func Generate(p Pattern, base uint32, size int) *Image {
+	n := min(uint64(size), 1<<32-uint64(base))
+
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = p(base + uint32(i))
+	}
+
+	m := New()
+	m.Write(base, data)
+	return m
+}
+
+// Repeat returns a pattern repeating b, aligned so that every address that
+// is a multiple of len(b) holds b[0].  Repeat(0xDE, 0xAD, 0xBE, 0xEF)
+// pads with DE AD BE EF in every aligned word.  It panics if b is empty.
+func Repeat(b ...byte) Pattern {
+	if len(b) == 0 {
+		panic("image: empty Repeat pattern")
+	}
+	b = append([]byte(nil), b...)
+	return func(addr uint32) byte {
+		return b[addr%uint32(len(b))]
+	}
+}
+
+// ParsePattern parses a fill pattern: a hex number, whose bytes repeat in
+// the order written, e.g. 0xFF or 0xDEADBEEF, or one of the names
+// incrementing, address-echo and prng:SEED.
+func ParsePattern(s string) (Pattern, error) {
+	switch name, arg, _ := strings.Cut(s, ":"); name {
+	case "incrementing":
+		return Incrementing, nil
+	case "address-echo":
+		return AddressEcho, nil
+	case "prng":
+		seed, err := strconv.ParseUint(arg, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad seed in pattern %q", s)
+		}
+		return PRNG(seed), nil
+	}
+
+	digits, ok := strings.CutPrefix(strings.ToLower(s), "0x")
+	if !ok {
+		return nil, fmt.Errorf("unknown pattern %q", s)
+	}
+	if len(digits)%2 != 0 {
+		digits = "0" + digits
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("bad pattern %q", s)
+	}
+	return Repeat(b...), nil
+}
+
+// Fill sets the bytes within [start, start+size) not covered by data to
+// the values of pattern p.  Anything that would lie beyond the top of
+// the 32-bit address space is dropped.
+func (m *Image) Fill(start, size uint32, p Pattern) {
+	n := int(min(uint64(size), 1<<32-uint64(start)))
+
+	data, present := m.view(start, n)
+	for i := range data {
+		if !present[i] {
+			data[i] = p(start + uint32(i))
+		}
+	}
+	m.Write(start, data)
+}
@@ -0,0 +1,242 @@
+package image
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Region is a named range of the address space, such as a flash bank or
+// a partition
+type Region struct {
+	Name  string
+	Start uint32
+	Size  uint32
+}
+
+// End returns the address one past the last byte of the region
+func (r Region) End() uint64 {
+	return uint64(r.Start) + uint64(r.Size)
+}
+
+// Contains reports whether addr falls within the region
+func (r Region) Contains(addr uint32) bool {
+	return addr >= r.Start && uint64(addr) < r.End()
+}
+
+// Linker script MEMORY command entry, e.g.
+//
+//	FLASH (rx) : ORIGIN = 0x08000000, LENGTH = 512K
+var ldRegion = regexp.MustCompile(`^(\w+)\s*(?:\([^)]*\))?\s*:\s*` +
+	`(?i:ORIGIN|ORG|o)\s*=\s*([^,\s]+)\s*,\s*(?i:LENGTH|LEN|l)\s*=\s*(\S+)$`)
+
+// ParseRegions reads a region map from r.  Two layouts are understood: the
+// entries of a GNU ld MEMORY command, as shown above, and a simpler one
+// region per line form
+//
+//	name  start  size
+//
+// Numbers may be decimal, or hex with a 0x prefix, and sizes may carry a
+// K, M or G suffix.  Blank lines, '#' comments, single line /* */
+// comments and the MEMORY { } wrapper are ignored.
+func ParseRegions(r io.Reader) ([]Region, error) {
+	var regions []Region
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "/*"); i >= 0 {
+			if j := strings.Index(line[i:], "*/"); j >= 0 {
+				line = line[:i] + line[i+j+2:]
+			}
+		}
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "MEMORY"))
+		line = strings.Trim(line, "{} \t")
+		if line == "" {
+			continue
+		}
+
+		var name, start, size string
+		if m := ldRegion.FindStringSubmatch(line); m != nil {
+			name, start, size = m[1], m[2], m[3]
+		} else if f := strings.Fields(line); len(f) == 3 {
+			name, start, size = f[0], f[1], f[2]
+		} else {
+			return nil, fmt.Errorf("line %d: unrecognized region %q", n, line)
+		}
+
+		s, err := ParseSize(start)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad start address: %v", n, err)
+		}
+		l, err := ParseSize(size)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad size: %v", n, err)
+		}
+		if s+l > 1<<32 {
+			return nil, fmt.Errorf("line %d: region %s exceeds the 32-bit address space", n, name)
+		}
+
+		regions = append(regions, Region{Name: name, Start: uint32(s), Size: uint32(l)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return regions, nil
+}
+
+// ParseSize parses a number with an optional K, M or G multiplier suffix.
+// The number may be decimal, or hex with a 0x prefix.
+func ParseSize(s string) (uint64, error) {
+	mult := uint64(1)
+	switch strings.ToUpper(s[max(len(s)-1, 0):]) {
+	case "K":
+		mult = 1 << 10
+	case "M":
+		mult = 1 << 20
+	case "G":
+		mult = 1 << 30
+	}
+	if mult != 1 {
+		s = s[:len(s)-1]
+	}
+
+	v, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, err
+	}
+	return v * mult, nil
+}
+
+// Crop returns a new image holding only the data within [start, start+size)
+func (m *Image) Crop(start uint32, size uint32) *Image {
+	var (
+		out = New()
+		end = uint64(start) + uint64(size)
+	)
+
+	i := sort.Search(len(m.segs), func(k int) bool { return m.segs[k].End() > uint64(start) })
+	for ; i < len(m.segs) && uint64(m.segs[i].Address) < end; i++ {
+		s := m.segs[i]
+		lo := max(uint64(s.Address), uint64(start))
+		hi := min(s.End(), end)
+		out.Write(uint32(lo), s.Data[lo-uint64(s.Address):hi-uint64(s.Address)])
+	}
+
+	return out
+}
+
+// SplitRegions returns one image per region holding the data that falls
+// within it, in the same order as regions.
+func (m *Image) SplitRegions(regions []Region) []*Image {
+	out := make([]*Image, len(regions))
+	for i, r := range regions {
+		out[i] = m.Crop(r.Start, r.Size)
+	}
+	return out
+}
+
+// SplitAtGaps returns one image per cluster of data, in ascending address
+// order.  A new cluster starts wherever at least minGap unpopulated bytes
+// separate two segments, so minGap 1 splits at every gap.
+func (m *Image) SplitAtGaps(minGap uint32) []*Image {
+	var out []*Image
+	for i, s := range m.segs {
+		if i == 0 || uint64(s.Address)-m.segs[i-1].End() >= uint64(minGap) {
+			out = append(out, New())
+		}
+		c := out[len(out)-1]
+		c.segs = append(c.segs, Segment{s.Address, append([]byte(nil), s.Data...)})
+	}
+	return out
+}
+
+// Unmapped returns the parts of the image not covered by any of regions
+func (m *Image) Unmapped(regions []Region) []Segment {
+	rest := &Image{segs: m.Segments()}
+	for _, r := range regions {
+		rest = rest.without(uint64(r.Start), r.End())
+	}
+	return rest.segs
+}
+
+// Copy of the image with the range [start, end) removed
+func (m *Image) without(start, end uint64) *Image {
+	out := New()
+	for _, s := range m.segs {
+		if s.End() <= start || uint64(s.Address) >= end {
+			out.segs = append(out.segs, s)
+			continue
+		}
+		if uint64(s.Address) < start {
+			out.segs = append(out.segs, Segment{s.Address, s.Data[:start-uint64(s.Address)]})
+		}
+		if s.End() > end {
+			out.segs = append(out.segs, Segment{uint32(end), s.Data[end-uint64(s.Address):]})
+		}
+	}
+	return out
+}
+
+// ValidateRegions returns an error describing any data that lies outside
+// all of regions
+func (m *Image) ValidateRegions(regions []Region) error {
+	stray := m.Unmapped(regions)
+	if len(stray) == 0 {
+		return nil
+	}
+
+	var ranges []string
+	for _, s := range stray {
+		ranges = append(ranges, fmt.Sprintf("0x%08X-0x%08X", s.Address, s.End()-1))
+	}
+	return fmt.Errorf("data outside of all regions: %s", strings.Join(ranges, ", "))
+}
+
+// Mask returns a copy of the image with the data within regions removed
+func (m *Image) Mask(regions []Region) *Image {
+	out := &Image{segs: m.Segments()}
+	for _, r := range regions {
+		out = out.without(uint64(r.Start), r.End())
+	}
+	return out
+}
+
+// RegionUsage is the occupancy of a region by the data of an image
+type RegionUsage struct {
+	Region
+	Used uint32 // Bytes of data within the region
+}
+
+// Free returns the number of bytes of the region without data
+func (u RegionUsage) Free() uint32 {
+	return u.Size - u.Used
+}
+
+// Percent returns the share of the region holding data, in percent
+func (u RegionUsage) Percent() float64 {
+	if u.Size == 0 {
+		return 0
+	}
+	return 100 * float64(u.Used) / float64(u.Size)
+}
+
+// Usage returns the occupancy of each of regions by the data of the
+// image, in the same order as regions.  Data outside all of them is
+// returned by Unmapped.
+func (m *Image) Usage(regions []Region) []RegionUsage {
+	out := make([]RegionUsage, len(regions))
+	for i, r := range regions {
+		out[i] = RegionUsage{Region: r, Used: uint32(m.Crop(r.Start, r.Size).Len())}
+	}
+	return out
+}
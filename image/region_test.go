@@ -0,0 +1,164 @@
+package image
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRegions(t *testing.T) {
+	fmt.Println("TestParseRegions()")
+
+	script := `MEMORY
+{
+  FLASH (rx)  : ORIGIN = 0x08000000, LENGTH = 512K /* main flash */
+  RAM (rwx)   : ORIGIN = 0x20000000, LENGTH = 128K
+}
+# simple form
+boot 0x0 0x8000
+`
+	regions, err := ParseRegions(strings.NewReader(script))
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	want := []Region{
+		{"FLASH", 0x08000000, 512 << 10},
+		{"RAM", 0x20000000, 128 << 10},
+		{"boot", 0, 0x8000},
+	}
+	if fmt.Sprint(regions) != fmt.Sprint(want) {
+		fmt.Printf("got %v\n", regions)
+		t.Fail()
+	}
+}
+
+func TestSplitRegions(t *testing.T) {
+	fmt.Println("TestSplitRegions()")
+
+	regions := []Region{{"boot", 0, 0x100}, {"app", 0x100, 0x100}}
+
+	m := New()
+	m.Write(0xF0, make([]byte, 0x20)) // straddles boot and app
+	m.Write(0x300, []byte{1})         // outside both
+
+	parts := m.SplitRegions(regions)
+	if parts[0].Len() != 0x10 || parts[1].Len() != 0x10 {
+		fmt.Println("failure: bad split")
+		t.Fail()
+	}
+
+	stray := m.Unmapped(regions)
+	if len(stray) != 1 || stray[0].Address != 0x300 {
+		fmt.Printf("failure: bad unmapped data %v\n", stray)
+		t.Fail()
+	}
+	if m.ValidateRegions(regions) == nil {
+		fmt.Println("failure: stray data not reported")
+		t.Fail()
+	}
+}
+
+func TestSplitAtGaps(t *testing.T) {
+	fmt.Println("TestSplitAtGaps()")
+
+	m := New()
+	m.Write(0x000, []byte{1, 2})
+	m.Write(0x010, []byte{3})      // 14 byte gap
+	m.Write(0x100, []byte{4, 5})   // 239 byte gap
+	m.Write(0xFFFFFFFF, []byte{6}) // far away
+
+	var sizes []int
+	for _, c := range m.SplitAtGaps(0x20) {
+		sizes = append(sizes, c.Len())
+	}
+	if fmt.Sprint(sizes) != "[3 2 1]" {
+		fmt.Printf("failure: cluster sizes %v\n", sizes)
+		t.Fail()
+	}
+
+	if n := len(m.SplitAtGaps(1)); n != 4 {
+		fmt.Printf("failure: %d clusters splitting at every gap\n", n)
+		t.Fail()
+	}
+	if n := len(New().SplitAtGaps(1)); n != 0 {
+		fmt.Printf("failure: %d clusters for an empty image\n", n)
+		t.Fail()
+	}
+}
+
+func TestParseLinkerMap(t *testing.T) {
+	fmt.Println("TestParseLinkerMap()")
+
+	const ldmap = `Archive member included to satisfy reference by file (symbol)
+
+Memory Configuration
+
+Name             Origin             Length             Attributes
+FLASH            0x0000000008000000 0x0000000000080000 xr
+RAM              0x0000000020000000 0x0000000000020000 xrw
+*default*        0x0000000000000000 0xffffffffffffffff
+
+Linker script and memory map
+
+ .text          0x0000000008000000      0x1a4
+`
+	regions, err := ParseLinkerMap(strings.NewReader(ldmap))
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+	want := []Region{{"FLASH", 0x08000000, 512 << 10}, {"RAM", 0x20000000, 128 << 10}}
+	if fmt.Sprint(regions) != fmt.Sprint(want) {
+		fmt.Printf("got %v\n", regions)
+		t.Fail()
+	}
+
+	if _, err := ParseLinkerMap(strings.NewReader("FLASH 0x0 0x100\n")); err == nil {
+		fmt.Println("failure: region list taken for a linker map")
+		t.Fail()
+	}
+
+	m := New()
+	m.Write(0x08000000, make([]byte, 0x2000))
+	m.Write(0x0807FFFF, make([]byte, 2)) // One byte beyond FLASH
+	u := m.Usage(regions)
+	if u[0].Used != 0x2001 || u[0].Free() != 0x80000-0x2001 || u[1].Used != 0 {
+		fmt.Printf("failure: usage %v\n", u)
+		t.Fail()
+	}
+	if p := m.Usage(regions[:1])[0].Percent(); p < 1.56 || p > 1.57 {
+		fmt.Printf("failure: %g%% used\n", p)
+		t.Fail()
+	}
+}
+
+func TestLayout(t *testing.T) {
+	fmt.Println("TestLayout()")
+
+	segs := []Segment{
+		{Address: 0x100, Data: make([]byte, 0x10)},
+		{Address: 0x108, Data: make([]byte, 0x10)}, // Overlaps the first
+		{Address: 0x10C, Data: make([]byte, 2)},    // Overlaps both
+		{Address: 0x200, Data: make([]byte, 4)},
+		{Address: 0x204, Data: make([]byte, 4)}, // Adjacent, no gap
+		{Address: 0x400, Data: nil},
+		{Address: 0xFFFFFFF0, Data: make([]byte, 0x10)},
+	}
+	want := []Span{
+		{Start: 0x108, End: 0x110, Kind: Overlap},
+		{Start: 0x118, End: 0x200, Kind: Gap},
+		{Start: 0x208, End: 0xFFFFFFF0, Kind: Gap},
+	}
+	if got := Layout(segs); !reflect.DeepEqual(got, want) {
+		fmt.Printf("failure: got %v\n", got)
+		t.Fail()
+	}
+
+	if got := Layout(segs[3:5]); got != nil {
+		fmt.Printf("failure: contiguous data gives %v\n", got)
+		t.Fail()
+	}
+}
@@ -0,0 +1,65 @@
+package image
+
+// Runs of erased bytes within a segment shorter than this are kept by
+// Trim: splitting the records around them would cost more than it saves
+const minTrimRun = 16
+
+// Trim drops the data runs consisting entirely of erase, the value of
+// erased flash memory, which need not be programmed.  Whole segments and
+// runs at either end of a segment are dropped whatever their length,
+// runs within a segment once they are at least 16 bytes long.
+func (m *Image) Trim(erase byte) {
+	var segs []Segment
+	for _, s := range m.segs {
+		segs = appendTrimmed(segs, s, erase)
+	}
+	m.segs = segs
+}
+
+// Append the parts of s that Trim keeps to segs
+func appendTrimmed(segs []Segment, s Segment, erase byte) []Segment {
+	kept := 0 // Start of the data not yet dropped or appended
+	for i := 0; i < len(s.Data); {
+		if s.Data[i] != erase {
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j < len(s.Data) && s.Data[j] == erase {
+			j++
+		}
+		if i == 0 || j == len(s.Data) || j-i >= minTrimRun {
+			if i > kept {
+				// Capped so appending to the segment cannot clobber
+				// the data following it
+				segs = append(segs, Segment{s.Address + uint32(kept), s.Data[kept:i:i]})
+			}
+			kept = j
+		}
+		i = j
+	}
+
+	if kept < len(s.Data) {
+		segs = append(segs, Segment{s.Address + uint32(kept), s.Data[kept:]})
+	}
+	return segs
+}
+
+// Pad returns a copy of the image in which every block of the given size
+// holding any data is fully populated, its missing bytes set to fill.
+// Blocks are aligned to multiples of their size, so the data of the copy
+// starts and ends on block boundaries, as for writers emitting records
+// of a fixed size.
+func (m *Image) Pad(block int, fill byte) *Image {
+	out := New()
+	if block < 1 {
+		return out
+	}
+	for k := range m.blocks(uint64(block)) {
+		start := k * uint64(block)
+		n := min(uint64(block), 1<<32-start)
+		out.Write(uint32(start), m.Extract(uint32(start), int(n), fill))
+	}
+	return out
+}
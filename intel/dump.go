@@ -0,0 +1,86 @@
+package ihex
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Pretty renders the record for human consumption: the absolute address
+// of the record, its type and length, then the data 16 bytes per line in
+// hex with an ASCII gutter.  base holds the upper address bits set by the
+// Extended Segment/Linear Address record in effect, or 0; it only applies
+// to Data records.
+func (r HexRec) Pretty(base uint32) string {
+	var (
+		sb   strings.Builder
+		addr = uint32(r.Address)
+	)
+
+	if r.RecordType == Data {
+		addr += base
+	}
+
+	fmt.Fprintf(&sb, "%08X  %-24s %3d", addr, recTypeStr[r.RecordType], len(r.Data))
+	for off := 0; off < len(r.Data); off += 16 {
+		if off > 0 {
+			fmt.Fprintf(&sb, "\n%08X  %-24s %3s", addr+uint32(off), "", "")
+		}
+		fmt.Fprintf(&sb, "  %s", image.HexASCII(r.Data[off:min(off+16, len(r.Data))]))
+	}
+
+	return sb.String()
+}
+
+// Dump writes the Pretty rendering of each of recs to w, resolving
+// absolute addresses as Extended Segment/Linear Address records go by.
+func Dump(w io.Writer, recs []*HexRec) error {
+	dd := NewDumper(w)
+	for _, r := range recs {
+		if err := dd.Dump(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dumper renders records one at a time as Dump does, carrying the
+// address state from one to the next, so a dump can be written as the
+// records arrive, e.g. from a file still being written
+type Dumper struct {
+	w     io.Writer
+	res   AddressResolver
+	page  int // Output lines between column headings, 0 for none
+	lines int // Output lines since the last headings
+}
+
+// NewDumper creates a Dumper writing to w
+func NewDumper(w io.Writer) *Dumper {
+	return &Dumper{w: w}
+}
+
+// SetPage makes the dumper write column headings before the first
+// record and again whenever n more lines have been written, so long
+// dumps remain readable in a terminal.  0, the default, writes none.
+func (d *Dumper) SetPage(n int) {
+	d.page = n
+	d.lines = n
+}
+
+// Dump writes the Pretty rendering of r
+func (d *Dumper) Dump(r *HexRec) error {
+	if d.page > 0 && d.lines >= d.page {
+		if _, err := fmt.Fprintf(d.w, "%-8s  %-24s %3s  %s\n", "Address", "Type", "Len", "Data"); err != nil {
+			return err
+		}
+		d.lines = 0
+	}
+
+	d.res.Resolve(r)
+	s := r.Pretty(d.res.Base())
+	d.lines += strings.Count(s, "\n") + 1
+	_, err := fmt.Fprintln(d.w, s)
+	return err
+}
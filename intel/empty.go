@@ -0,0 +1,30 @@
+package ihex
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrEmptyData reports a zero-length data record under EmptyReject
+var ErrEmptyData = errors.New("zero-length data record")
+
+// EmptyPolicy selects how zero-length data records, which some
+// generators emit as markers, are handled.  A Decoder applies it to the
+// records it reads, a Writer to the empty segments passed to
+// WriteSegment.
+type EmptyPolicy int
+
+// Empty data record policies
+const (
+	EmptyPreserve EmptyPolicy = iota // Pass them through like any other record
+	EmptyDrop                        // Leave them out
+	EmptyReject                      // Fail with ErrEmptyData
+)
+
+// validate reports whether p is a known policy
+func (p EmptyPolicy) validate() error {
+	if p < EmptyPreserve || p > EmptyReject {
+		return fmt.Errorf("invalid empty data record policy %d", p)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package ihex
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+)
+
+// Explain breaks a single Intel Hex record into labeled fields: start
+// code, byte count, address, record type, data, checksum and the checksum
+// computed from the other fields.  Malformed fields are flagged rather
+// than rejected, making it a teaching and debugging aid for investigating
+// bad records.
+func Explain(line string) string {
+	var sb strings.Builder
+	line = strings.TrimSpace(line)
+
+	field := func(label, value, note string) {
+		if note != "" {
+			value += " (" + note + ")"
+		}
+		fmt.Fprintf(&sb, "%-18s %s\n", label+":", value)
+	}
+
+	if line == "" {
+		field("Start code", "missing", "empty record")
+		return sb.String()
+	}
+
+	start := "':'"
+	if line[0] != ':' {
+		start = fmt.Sprintf("%q", line[0])
+		field("Start code", start, "expected ':'")
+	} else {
+		field("Start code", start, "")
+	}
+
+	body := line[1:]
+	if len(body) < 10 {
+		field("Record", body, "too short; at least 10 hex digits required")
+		return sb.String()
+	}
+
+	var (
+		countStr = body[0:2]
+		addrStr  = body[2:6]
+		typStr   = body[6:8]
+		dataStr  = body[8 : len(body)-2]
+		csStr    = body[len(body)-2:]
+	)
+
+	count, err := hex.DecodeString(countStr)
+	if err != nil {
+		field("Byte count", countStr, "invalid hex")
+	} else if int(count[0])*2 != len(dataStr) {
+		field("Byte count", fmt.Sprintf("%s (%d)", countStr, count[0]),
+			fmt.Sprintf("record holds %d data digits", len(dataStr)))
+	} else {
+		field("Byte count", fmt.Sprintf("%s (%d)", countStr, count[0]), "")
+	}
+
+	if _, err := hex.DecodeString(addrStr); err != nil {
+		field("Address", addrStr, "invalid hex")
+	} else {
+		field("Address", addrStr, "")
+	}
+
+	if t, err := hex.DecodeString(typStr); err != nil {
+		field("Record type", typStr, "invalid hex")
+	} else if name, ok := recTypeStr[RecTyp(t[0])]; ok {
+		field("Record type", fmt.Sprintf("%s (%s)", typStr, name), "")
+	} else {
+		field("Record type", typStr, "unknown record type")
+	}
+
+	data, err := hex.DecodeString(dataStr)
+	switch {
+	case err != nil:
+		field("Data", dataStr, "invalid hex")
+	case len(data) == 0:
+		field("Data", "none", "")
+	default:
+		field("Data", strings.ToUpper(hex.EncodeToString(data)), "")
+	}
+
+	cs, err := hex.DecodeString(csStr)
+	if err != nil {
+		field("Checksum", csStr, "invalid hex")
+	} else {
+		field("Checksum", strings.ToUpper(csStr), "")
+	}
+
+	bin, err := hex.DecodeString(body[:len(body)-2])
+	if err != nil {
+		field("Computed checksum", "n/a", "record contains invalid hex")
+	} else {
+		calc := checksum.TwosComplement.Sum(bin)
+		note := "ok"
+		if len(cs) == 1 && cs[0] != calc {
+			note = "MISMATCH"
+		}
+		field("Computed checksum", fmt.Sprintf("%02X", calc), note)
+	}
+
+	return sb.String()
+}
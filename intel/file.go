@@ -0,0 +1,206 @@
+package ihex
+
+import (
+	"encoding/binary"
+	"io"
+	"iter"
+
+	"github.com/peteArnt/GoHexIO/image"
+	"github.com/peteArnt/GoHexIO/internal/atomicfile"
+)
+
+// File is an in-memory representation of a complete Intel Hex file.  Its
+// fields sort the records by purpose, so consumers need not scan them
+// for what they need; Records still yields them in file order.
+type File struct {
+	Data  []image.Segment // Data records at their absolute addresses, merged into contiguous segments
+	Start *Entry          // Execution start address, nil if none
+	Other []*HexRec       // Records not reflected above, e.g. of unknown type
+
+	recs []*HexRec // Records in file order
+}
+
+// Entry is an execution start address and the kind of record carrying it
+type Entry struct {
+	Address uint32 // EIP, or CS<<16 | IP for StartSegment; see EntryPoint
+	Kind    StartKind
+}
+
+// NewFile wraps a slice of already decoded hex records in a File.  Data
+// record addresses are resolved against any preceding Extended Segment
+// or Extended Linear Address records.  Of several start records the last
+// one wins; the others, like malformed address records, end up in Other.
+func NewFile(recs []*HexRec) *File {
+	return NewFileScale(recs, 1)
+}
+
+// NewFileScale is NewFile for files whose addresses count units of scale
+// bytes, as written by a Writer with SetAddressScale.  The addresses of
+// Data are byte addresses; the start address is left as is.
+func NewFileScale(recs []*HexRec, scale uint32) *File {
+	var (
+		f     = &File{recs: recs}
+		m     = image.New()
+		res   AddressResolver
+		start = -1
+	)
+	res.SetScale(scale)
+
+	for i, r := range recs {
+		addr, isData := res.Resolve(r)
+		switch {
+		case isData:
+			m.Write(addr, r.Data)
+		case r.RecordType == StartSegAddr || r.RecordType == StartLinAddr:
+			if len(r.Data) == 4 {
+				start = i
+			}
+		}
+	}
+
+	for i, r := range recs {
+		switch {
+		case r.RecordType == Data || r.RecordType == EndOfFile:
+		case (r.RecordType == ExtSegAddr || r.RecordType == ExtLinAddr) && len(r.Data) == 2:
+		case i == start:
+			f.Start = &Entry{Address: binary.BigEndian.Uint32(r.Data), Kind: StartLinear}
+			if r.RecordType == StartSegAddr {
+				f.Start.Kind = StartSegment
+			}
+		default:
+			f.Other = append(f.Other, r)
+		}
+	}
+
+	f.Data = m.Segments()
+	return f
+}
+
+// Records returns an iterator over the records of the file in file
+// order.  The error is always nil; the signature matches
+// Decoder.Records so in-memory and streamed records can be consumed
+// alike.
+func (f *File) Records() iter.Seq2[*HexRec, error] {
+	return func(yield func(*HexRec, error) bool) {
+		for _, r := range f.recs {
+			if !yield(r, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Open reads and decodes the Intel Hex file named fn
+func Open(fn string, opts ...DecoderOption) (*File, error) {
+	recs, err := ReadFile(fn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewFile(recs), nil
+}
+
+// StartKind identifies which kind of start address record a file carries
+type StartKind int
+
+// Start address kinds
+const (
+	StartNone    StartKind = iota // No start address record present
+	StartSegment                  // Type 03, 80x86 CS:IP
+	StartLinear                   // Type 05, 32-bit EIP
+)
+
+// EntryPoint returns the execution start address recorded in the file.
+// For StartLinear the address is the EIP value.  For StartSegment the
+// address packs the two 16-bit values as CS<<16 | IP; the physical
+// address is then (CS << 4) + IP.  If the file holds more than one start
+// record the last one wins; ok is false if there are none.
+func (f *File) EntryPoint() (addr uint32, kind StartKind, ok bool) {
+	if f.Start == nil {
+		return 0, StartNone, false
+	}
+	return f.Start.Address, f.Start.Kind, true
+}
+
+// Image assembles the data of the file into a memory image
+func (f *File) Image() *image.Image {
+	m := image.New()
+	for _, s := range f.Data {
+		m.Write(s.Address, s.Data)
+	}
+	return m
+}
+
+// Segments returns the data payload of the file as contiguous runs of
+// bytes in ascending address order.
+func (f *File) Segments() []image.Segment {
+	return append([]image.Segment(nil), f.Data...)
+}
+
+// WriteBinary writes the data of recs to w as raw binary, as a flash
+// programmer takes it: from the lowest to the highest address with data,
+// gaps set to fill.  The address of the first byte written is returned.
+func WriteBinary(w io.Writer, recs []*HexRec, fill byte) (base uint32, err error) {
+	return NewFile(recs).Image().WriteBinary(w, fill)
+}
+
+// WriteSparseBinary is WriteBinary leaving the gaps as holes, as
+// image.Image.WriteSparseBinary does
+func WriteSparseBinary(w io.WriteSeeker, recs []*HexRec) (base uint32, err error) {
+	return NewFile(recs).Image().WriteSparseBinary(w)
+}
+
+// WriteBinaryFile is WriteBinary to the file named fn, which is
+// replaced atomically
+func WriteBinaryFile(fn string, recs []*HexRec, fill byte) (base uint32, err error) {
+	m := NewFile(recs).Image()
+	err = atomicfile.WriteFile(fn, 0644, func(w io.Writer) error {
+		base, err = m.WriteBinary(w, fill)
+		return err
+	})
+	return base, err
+}
+
+// DataBytes returns the data payload of the file as a single buffer
+// starting at base.  Gaps between data records are filled with 0xFF,
+// the erased state of most flash parts.
+func (f *File) DataBytes() (base uint32, data []byte) {
+	return f.Image().Flatten(0xFF)
+}
+
+// Extract renders the data records of recs into a flat buffer of length
+// bytes holding the data from the absolute address start on, as a
+// programmer takes it; addresses without data are set to fill, such as
+// 0xFF for flash.  Data record addresses are resolved as by NewFile, and
+// where records overlap the later one wins.
+func Extract(recs []*HexRec, start uint32, length int, fill byte) []byte {
+	return NewFile(recs).Image().Extract(start, length, fill)
+}
+
+// Layout returns the gaps between the data records of recs and the
+// ranges more than one of them provides, as image.Layout does, for
+// checking that linker output covers the expected flash sectors without
+// collisions.  Data record addresses are resolved as by NewFile.
+func Layout(recs []*HexRec) []image.Span {
+	var segs []image.Segment
+	for _, r := range ResolveAddresses(recs) {
+		segs = append(segs, image.Segment{Address: r.Address, Data: r.Data})
+	}
+	return image.Layout(segs)
+}
+
+// nextBase returns the upper address bits in effect after record r,
+// given those in effect before it.
+func nextBase(base uint32, r *HexRec) uint32 {
+	if len(r.Data) != 2 {
+		return base
+	}
+
+	v := uint32(binary.BigEndian.Uint16(r.Data))
+	switch r.RecordType {
+	case ExtSegAddr:
+		return v << 4
+	case ExtLinAddr:
+		return v << 16
+	}
+	return base
+}
@@ -0,0 +1,70 @@
+package ihex
+
+import (
+	"io"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// SplitINHX8S splits m into the low (even address) and high (odd
+// address) byte images of an INHX8S pair.  INHX8S is used for 16-bit
+// wide program memory built from two 8-bit PROMs; within each file the
+// byte at address N of the original image lives at address N/2.
+func SplitINHX8S(m *image.Image) (low, high *image.Image) {
+	low, high = image.New(), image.New()
+
+	for _, s := range m.Segments() {
+		for i, b := range s.Data {
+			a := s.Address + uint32(i)
+			if a&1 == 0 {
+				low.Write(a>>1, []byte{b})
+			} else {
+				high.Write(a>>1, []byte{b})
+			}
+		}
+	}
+
+	return low, high
+}
+
+// JoinINHX8S is the inverse of SplitINHX8S; it interleaves a low and a
+// high byte image back into a single image.
+func JoinINHX8S(low, high *image.Image) *image.Image {
+	m := image.New()
+
+	for lane, half := range []*image.Image{low, high} {
+		for _, s := range half.Segments() {
+			for i, b := range s.Data {
+				m.Write((s.Address+uint32(i))<<1|uint32(lane), []byte{b})
+			}
+		}
+	}
+
+	return m
+}
+
+// EncodeINHX8S writes m as an INHX8S pair, even address bytes to lo and
+// odd address bytes to hi.
+func EncodeINHX8S(lo, hi io.Writer, m *image.Image) error {
+	low, high := SplitINHX8S(m)
+
+	if err := Encode(lo, low); err != nil {
+		return err
+	}
+	return Encode(hi, high)
+}
+
+// DecodeINHX8S reads an INHX8S pair and recombines it into one image
+func DecodeINHX8S(lo, hi io.Reader) (*image.Image, error) {
+	low, err := Decode(lo)
+	if err != nil {
+		return nil, err
+	}
+
+	high, err := Decode(hi)
+	if err != nil {
+		return nil, err
+	}
+
+	return JoinINHX8S(low, high), nil
+}
@@ -0,0 +1,45 @@
+package ihex
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestINHX8SLoopback(t *testing.T) {
+	fmt.Println("TestINHX8SLoopback()")
+
+	m := image.New()
+	m.Write(0x0000, []byte{0x00, 0x01, 0x02, 0x03, 0x04})
+	m.Write(0x3FFFE, []byte{0xA0, 0xA1, 0xA2, 0xA3}) // halves straddle 64K
+
+	var lo, hi bytes.Buffer
+	if err := EncodeINHX8S(&lo, &hi, m); err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	low, err := Decode(bytes.NewReader(lo.Bytes()))
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+	if !bytes.Equal(low.Extract(0, 3, 0xFF), []byte{0x00, 0x02, 0x04}) {
+		fmt.Println("failure: bad low file content")
+		t.Fail()
+	}
+
+	m2, err := DecodeINHX8S(&lo, &hi)
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	if !reflect.DeepEqual(m.Segments(), m2.Segments()) {
+		fmt.Println("failure: images differ")
+		t.Fail()
+	}
+}
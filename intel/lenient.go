@@ -0,0 +1,48 @@
+package ihex
+
+import "bytes"
+
+// Leniency selects deviations from the Intel Hex specification, common
+// in hand-edited and tool-generated files, that a Decoder tolerates
+// rather than rejects.  Flags combine with |.  Blank lines and lower case
+// hex digits are always accepted.
+type Leniency uint
+
+// Leniency flags
+const (
+	AllowWhitespace Leniency = 1 << iota // White space around records, and lines holding nothing else
+	AllowComments                        // Lines starting with '#' or ';', skipped like blank lines
+	AllowMissingEOF                      // Input ending without an EOF record under SetStrictOrder
+
+	// Lenient accepts every deviation listed above
+	Lenient = AllowWhitespace | AllowComments | AllowMissingEOF
+)
+
+// SetLenient makes the decoder tolerate the deviations l selects, so
+// files that are correct in substance can be ingested.  Lines skipped
+// as comments or white space are logged at debug level.  0, the
+// default, restores strict parsing.
+func (d *Decoder) SetLenient(l Leniency) {
+	d.lenient = l
+}
+
+// WithLeniency makes the decoder tolerate the deviations l selects; see
+// Decoder.SetLenient
+func WithLeniency(l Leniency) DecoderOption {
+	return func(d *Decoder) { d.SetLenient(l) }
+}
+
+// trim returns the record held by line, nil for a line holding none
+// under the decoder's leniency
+func (d *Decoder) trim(line []byte) []byte {
+	if d.lenient&AllowWhitespace != 0 && len(line) > 0 {
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			d.logger().Debug("ihex: skipped white space line", "line", d.line)
+		}
+	}
+	if d.lenient&AllowComments != 0 && len(line) > 0 && (line[0] == '#' || line[0] == ';') {
+		d.logger().Debug("ihex: skipped comment line", "line", d.line)
+		return nil
+	}
+	return line
+}
@@ -0,0 +1,177 @@
+package ihex
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+)
+
+// Options is a snapshot of the configuration of a Writer
+type Options struct {
+	Width      int                // Bytes per data record
+	Checksum   checksum.Algorithm // Record checksum algorithm
+	Logger     *slog.Logger       // Diagnostics sink, nil for none
+	Trace      func(HexRec)       // Per-record callback, nil for none
+	Scale      int                // Bytes per address unit, 0 or 1 for byte addressing
+	Bank       uint32             // Bank size addresses wrap at, 0 for none
+	CheckClose bool               // Close fails on suspect output; see SetCloseChecks
+	EmptyData  EmptyPolicy        // Handling of empty segments; see SetEmptyData
+	StartCode  byte               // Character opening each record, normally ':'
+	Integrity  checksum.Digest    // Digest of the integrity trailer, "" for none; see SetIntegrity
+
+	FixedRecords bool   // Pad every data record to Width; see SetFixedRecords
+	Fill         byte   // Value of the padding bytes under FixedRecords
+	LineEnding   string // Record terminator, "" for LF; see SetLineEnding
+	StartAddress uint32 // Start linear address written at Close
+	EmitStart    bool   // Write StartAddress; see WithStartAddress
+}
+
+// Validate reports whether the options describe a usable writer
+func (o Options) Validate() error {
+	if o.Width < 1 || o.Width > 255 {
+		return fmt.Errorf("record width %d out of range 1..255", o.Width)
+	}
+	if o.Checksum == nil {
+		return errors.New("no checksum algorithm")
+	}
+	if o.StartCode <= ' ' || o.StartCode > '~' || strings.IndexByte("0123456789ABCDEFabcdef", o.StartCode) >= 0 {
+		return fmt.Errorf("invalid start code %q", o.StartCode)
+	}
+	if o.Scale < 0 || o.Width%max(o.Scale, 1) != 0 {
+		return fmt.Errorf("record width %d is not a multiple of the address scale %d", o.Width, o.Scale)
+	}
+	if o.Bank > 0x10000 {
+		return fmt.Errorf("bank size 0x%X exceeds 64K", o.Bank)
+	}
+	if err := validateEOL(o.LineEnding); err != nil {
+		return err
+	}
+	if o.Integrity != "" && o.Integrity.New() == nil {
+		return fmt.Errorf("unknown integrity digest %q", o.Integrity)
+	}
+	return o.EmptyData.validate()
+}
+
+// Options returns a snapshot of the writer's configuration
+func (x *Writer) Options() Options {
+	return Options{Width: x.width, Checksum: x.sum, Logger: x.log, Trace: x.trace, Scale: x.scale, Bank: x.bank,
+		CheckClose: x.check, EmptyData: x.empty, StartCode: x.code, Integrity: x.integrity,
+		FixedRecords: x.fixed, Fill: x.fill, LineEnding: x.eol, StartAddress: x.start, EmitStart: x.emitStart}
+}
+
+// apply replaces the configuration of x by o
+func (x *Writer) apply(o Options) {
+	x.width, x.sum, x.log, x.trace, x.scale, x.bank = o.Width, o.Checksum, o.Logger, o.Trace, o.Scale, o.Bank
+	x.check, x.empty, x.code = o.CheckClose, o.EmptyData, o.StartCode
+	x.fixed, x.fill, x.eol = o.FixedRecords, o.Fill, o.LineEnding
+	x.start, x.emitStart = o.StartAddress, o.EmitStart
+	x.SetIntegrity(o.Integrity)
+}
+
+// CloneTo creates a new writer for w configured identically to x.  None
+// of x's state, such as its address counter or buffered data, is copied.
+func (x *Writer) CloneTo(w io.Writer) *Writer {
+	c := &Writer{w: w, err: x.err}
+	c.apply(x.Options())
+	return c
+}
+
+// Option configures a Writer created by NewWriter, so its whole
+// configuration is given at once:
+//
+//	x := ihex.NewWriter(w, ihex.WithWidth(32), ihex.WithLineEnding(ihex.CRLF))
+type Option func(*Writer)
+
+// WithOptions configures the writer as o describes, as taken from
+// Writer.Options; options following it adjust the configuration further
+func WithOptions(o Options) Option {
+	return func(x *Writer) { x.apply(o) }
+}
+
+// WithWidth sets the number of data bytes per record, 1 to 255
+func WithWidth(n int) Option {
+	return func(x *Writer) { x.width = n }
+}
+
+// WithStartAddress makes Close write a Start Linear Address record for
+// eip before the EOF record, unless WriteStartLinAddr or
+// WriteStartSegAddr has been called
+func WithStartAddress(eip uint32) Option {
+	return func(x *Writer) { x.start, x.emitStart = eip, true }
+}
+
+// WithChecksum selects the record checksum algorithm; see SetChecksum
+func WithChecksum(a checksum.Algorithm) Option {
+	return func(x *Writer) { x.SetChecksum(a) }
+}
+
+// WithStartCode sets the character opening each record; see SetStartCode
+func WithStartCode(c byte) Option {
+	return func(x *Writer) { x.SetStartCode(c) }
+}
+
+// WithLineEnding sets the record terminator; see SetLineEnding
+func WithLineEnding(eol string) Option {
+	return func(x *Writer) { x.SetLineEnding(eol) }
+}
+
+// WithFixedRecords pads every data record to the record width; see
+// SetFixedRecords
+func WithFixedRecords(fill byte) Option {
+	return func(x *Writer) { x.SetFixedRecords(fill) }
+}
+
+// WithAddressScale makes addresses count units of n bytes; see
+// SetAddressScale
+func WithAddressScale(n int) Option {
+	return func(x *Writer) { x.SetAddressScale(n) }
+}
+
+// WithBankSize makes addresses wrap at banks of n address units; see
+// SetBankSize.  A bank size above 64K makes every write fail.
+func WithBankSize(n uint32) Option {
+	return func(x *Writer) { x.bank = n }
+}
+
+// WithCloseChecks makes Close fail on suspect output; see SetCloseChecks
+func WithCloseChecks() Option {
+	return func(x *Writer) { x.SetCloseChecks(true) }
+}
+
+// WithEmptyData selects the handling of empty segments; see SetEmptyData
+func WithEmptyData(p EmptyPolicy) Option {
+	return func(x *Writer) { x.SetEmptyData(p) }
+}
+
+// WithIntegrity appends an integrity trailer; see SetIntegrity
+func WithIntegrity(d checksum.Digest) Option {
+	return func(x *Writer) { x.SetIntegrity(d) }
+}
+
+// WithLogger routes the writer's diagnostics to l; see SetLogger
+func WithLogger(l *slog.Logger) Option {
+	return func(x *Writer) { x.SetLogger(l) }
+}
+
+// WithTrace calls fn with every record emitted; see SetTrace
+func WithTrace(fn func(r HexRec)) Option {
+	return func(x *Writer) { x.SetTrace(fn) }
+}
+
+// validateEOL reports whether eol is a usable record terminator, made of
+// control characters alone
+func validateEOL(eol string) error {
+	if eol == "" {
+		return nil
+	}
+	for i := 0; i < len(eol); i++ {
+		if eol[i] >= ' ' {
+			return fmt.Errorf("line ending %q holds other than control characters", eol)
+		}
+	}
+	return nil
+}
@@ -2,12 +2,22 @@ package ihex
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
-	"encoding/hex"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
 	"strings"
+	"unicode"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+	"github.com/peteArnt/GoHexIO/image"
+	"github.com/peteArnt/GoHexIO/internal/arena"
+	"github.com/peteArnt/GoHexIO/internal/coalesce"
+	"github.com/peteArnt/GoHexIO/internal/hexenc"
 )
 
 // RecTyp indicates the type of Intel Hex record
@@ -21,6 +31,11 @@ const (
 	StartSegAddr               // 03
 	ExtLinAddr                 // 04
 	StartLinAddr               // 05
+
+	// Integrity is the vendor record type of the integrity trailer
+	// written under Writer.SetIntegrity; it is no part of the Intel
+	// standard
+	Integrity RecTyp = 0x0F
 )
 
 var recTypeStr = map[RecTyp]string{
@@ -30,6 +45,60 @@ var recTypeStr = map[RecTyp]string{
 	StartSegAddr: "Start Segment Address",
 	ExtLinAddr:   "Extended Linear Address",
 	StartLinAddr: "Start Linear Address",
+	Integrity:    "Integrity Trailer",
+}
+
+// Go identifiers of the record types, also accepted by ParseRecTyp
+var recTypeIdent = map[RecTyp]string{
+	Data:         "Data",
+	EndOfFile:    "EndOfFile",
+	ExtSegAddr:   "ExtSegAddr",
+	StartSegAddr: "StartSegAddr",
+	ExtLinAddr:   "ExtLinAddr",
+	StartLinAddr: "StartLinAddr",
+	Integrity:    "Integrity",
+}
+
+// String returns the name of the record type, such as "Data" or
+// "Extended Linear Address"
+func (t RecTyp) String() string {
+	if s, ok := recTypeStr[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("RecTyp(0x%02X)", byte(t))
+}
+
+// ParseRecTyp returns the record type named s.  Besides the names
+// returned by String, it accepts the Go constant names (ExtLinAddr) and
+// the type number in hex ("04").  Names are matched regardless of case,
+// spaces, hyphens and underscores, so "extended-linear-address" works
+// too.
+func ParseRecTyp(s string) (RecTyp, error) {
+	key := normalizeName(s)
+	for t := range recTypeStr {
+		if key == normalizeName(recTypeStr[t]) || key == normalizeName(recTypeIdent[t]) {
+			return t, nil
+		}
+	}
+
+	if v, err := strconv.ParseUint(strings.TrimPrefix(key, "0x"), 16, 8); err == nil {
+		if _, ok := recTypeStr[RecTyp(v)]; ok {
+			return RecTyp(v), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown record type %q", s)
+}
+
+// normalizeName folds case and drops separators for name matching
+func normalizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
 }
 
 // HexRec is an abstract hex record
@@ -41,148 +110,219 @@ type HexRec struct {
 
 func (r HexRec) String() string {
 	return fmt.Sprintf("Address: 0x%04x, Type: %s, Data: %v",
-		r.Address, recTypeStr[r.RecTyp], r.Data)
+		r.Address, recTypeStr[r.RecordType], r.Data)
+}
+
+func decodeRecord(s string, sum checksum.Algorithm) (*HexRec, error) {
+	return decodeRecordIn(s, ':', sum, nil)
 }
 
-func decodeRecord(s string) (*HexRec, error) {
-	if s == "" {
-		return nil, errors.New("Empty record detected")
+// decodeRecordIn is decodeRecord for records opened by the start code
+// start, taking the record and its data from a, or from the heap if a is
+// nil.  s may be a string or, sparing the conversion, a byte slice.
+func decodeRecordIn[S ~string | ~[]byte](s S, start byte, sum checksum.Algorithm, a *arena.Arena[HexRec]) (*HexRec, error) {
+	var (
+		hr  *HexRec
+		buf []byte
+	)
+	if a != nil {
+		hr, buf = a.Item(), a.Bytes(len(s)/2)
+	} else {
+		hr, buf = new(HexRec), make([]byte, len(s)/2)
+	}
+
+	if err := decodeRecordInto(hr, s, start, sum, buf); err != nil {
+		return nil, err
+	}
+	hr.Data = hr.Data[:len(hr.Data):len(hr.Data)]
+
+	// Return a reference to the populated Hex Record
+	return hr, nil
+}
+
+// decodeRecordInto decodes s, opened by the start code start, into hr,
+// storing the data at the start of buf, which must hold at least
+// len(s)/2 bytes
+func decodeRecordInto[S ~string | ~[]byte](hr *HexRec, s S, start byte, sum checksum.Algorithm, buf []byte) error {
+	if len(s) == 0 {
+		return errors.New("Empty record detected")
 	}
 
-	// Remove the leading ':' character
+	if s[0] != start {
+		return fmt.Errorf("Missing '%c' start code", start)
+	}
+
+	// Remove the leading start code
 	s = s[1:]
 
 	// Convert the Hex-ASCII representation to binary
-	b, err := hex.DecodeString(s)
-	if err != nil {
-		return nil, fmt.Errorf("Unable to decode hex record: %s", err)
+	b := buf[:len(s)/2]
+	if _, err := hexenc.Decode(b, s); err != nil {
+		return fmt.Errorf("Unable to decode hex record: %s", err)
+	}
+
+	// Byte count, address, record type and checksum are mandatory
+	if len(b) < 5 {
+		return errors.New("Record too short")
 	}
 
 	// Pop the checksum byte off the end
 	checksum, b := b[len(b)-1], b[:len(b)-1]
 
 	// Compare calculated checksum with actual
-	if checksum != calcChecksum(b) {
-		return nil, errors.New("Bad checksum detected")
+	if checksum != sum.Sum(b) {
+		return errors.New("Bad checksum detected")
 	}
 
-	// Create a new Hex Record
-	hr := new(HexRec)
-	var recLen byte
+	// Byte count, then the data that follows the header fields
+	if len(b)-4 != int(b[0]) {
+		return errors.New("byte-count error")
+	}
+
+	hr.Address = binary.BigEndian.Uint16(b[1:3])
+	hr.RecordType = RecTyp(b[3])
+
+	// Move the data to the front of buf, so a recycled buffer keeps
+	// its full capacity
+	hr.Data = buf[:copy(buf, b[4:])]
+
+	return nil
+}
 
-	// Create a sort of lookup table for fields to decode
-	fields := []interface{}{
-		&recLen,
-		&hr.Address,
-		&hr.RecordType,
+// DecodeBatch decodes Intel Hex records from lines into the caller's
+// slice out, verifying them against the standard checksum, and returns
+// the number of records stored.  Blank lines are skipped.  The Data
+// slices already held by out are reused when large enough, so services
+// recycling out between files avoid per-record allocations.  Decoding
+// stops at the first bad record, and with io.ErrShortBuffer if out fills
+// up before lines run out.
+func DecodeBatch(lines []string, out []HexRec) (n int, err error) {
+	for i, s := range lines {
+		if s == "" {
+			continue
+		}
+		if n == len(out) {
+			return n, io.ErrShortBuffer
+		}
+
+		buf := out[n].Data
+		if cap(buf) < len(s)/2 {
+			buf = make([]byte, len(s)/2)
+		}
+		if err := decodeRecordInto(&out[n], s, ':', checksum.TwosComplement, buf[:cap(buf)]); err != nil {
+			return n, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		n++
 	}
+	return n, nil
+}
 
-	// Set up a reader object for the binary-Reader
-	buf := bytes.NewReader(b)
+// DecodeRecordString decodes a single Intel Hex record, verifying it
+// against the standard checksum.  It performs no I/O and returns an
+// error, never panics, on malformed input, making it suitable as a
+// fuzzing entry point and for handling untrusted data.
+func DecodeRecordString(s string) (*HexRec, error) {
+	return decodeRecord(s, checksum.TwosComplement)
+}
 
-	// Decode record header fields
-	for i, v := range fields {
-		err := binary.Read(buf, binary.BigEndian, v)
+// ParseBytes decodes the Intel Hex records held in b.  Like
+// DecodeRecordString it performs no file I/O and never panics.  The
+// records are decoded straight out of b, which suits images embedded
+// with go:embed, and their data shares a single allocation; b itself is
+// not retained.
+func ParseBytes(b []byte) ([]*HexRec, error) {
+	var (
+		hrecs []*HexRec
+		a     = arena.New[HexRec](len(b) / 2)
+	)
+
+	for line := range bytes.Lines(b) {
+		line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+		if len(line) == 0 {
+			continue
+		}
+		hr, err := decodeRecordIn(line, ':', checksum.TwosComplement, a)
 		if err != nil {
-			return nil, fmt.Errorf("Bad field #%d in hex record: %s", i+1, err)
+			return nil, err
 		}
+		hrecs = append(hrecs, hr)
 	}
 
-	// Allocate a slice for the data bytes
-	hr.Data = make([]byte, recLen)
+	return hrecs, nil
+}
 
-	// Read data bytes into the above slice
-	err = binary.Read(buf, binary.BigEndian, &hr.Data)
-	if err != nil {
-		return nil, fmt.Errorf("Bad data field in hex record: %s", err)
+// Parse decodes the Intel Hex records of f, such as a file opened from an
+// embed.FS.  The content is read in one go into a buffer sized from
+// f.Stat, then decoded by ParseBytes.
+func Parse(f fs.File) ([]*HexRec, error) {
+	var buf bytes.Buffer
+	if fi, err := f.Stat(); err == nil && fi.Size() > 0 {
+		buf.Grow(int(fi.Size()) + bytes.MinRead)
+	}
+	if _, err := buf.ReadFrom(f); err != nil {
+		return nil, err
 	}
+	return ParseBytes(buf.Bytes())
+}
 
-	// Return a reference to the populated Hex Record
-	return hr, nil
+// ReadAll reads Intel Hex records from r until EOF and returns a slice
+// of pointers to HexRec, decoded by a Decoder configured by opts.
+func ReadAll(r io.Reader, opts ...DecoderOption) ([]*HexRec, error) {
+	return ReadAllContext(context.Background(), r, opts...)
+}
+
+// ReadAllContext is like ReadAll but stops and returns ctx.Err() if
+// ctx is cancelled or its deadline expires while reading.
+func ReadAllContext(ctx context.Context, r io.Reader, opts ...DecoderOption) ([]*HexRec, error) {
+	return NewDecoder(r, opts...).decodeAll(ctx)
 }
 
 // ReadFile reads a hex file specified by fn and returns a slice of
-// pointers to HexRec. If error is non-nil, it will indicate an
-// issue reading the hex file or parsing a hex record.
-func ReadFile(fn string) ([]*HexRec, error) {
-	content, err := ioutil.ReadFile(fn)
+// pointers to HexRec, decoded as configured by opts. If error is
+// non-nil, it will indicate an issue reading the hex file or parsing a
+// hex record.
+func ReadFile(fn string, opts ...DecoderOption) ([]*HexRec, error) {
+	return ReadFileContext(context.Background(), fn, opts...)
+}
+
+// ReadFileContext is like ReadFile but honors cancellation and
+// deadlines of ctx.
+func ReadFileContext(ctx context.Context, fn string, opts ...DecoderOption) ([]*HexRec, error) {
+	f, err := os.Open(fn)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 
-	records := strings.Split(string(content), "\n")
+	return ReadAllContext(ctx, f, opts...)
+}
 
-	var hrecs []*HexRec
-	for _, rec := range records {
-		if len(rec) > 0 {
-			hr, err := decodeRecord(rec)
-			if err != nil {
-				return nil, err
-			}
-			hrecs = append(hrecs, hr)
-		}
+// Decode reads Intel Hex records from r into a memory image
+func Decode(r io.Reader) (*image.Image, error) {
+	recs, err := ReadAll(r)
+	if err != nil {
+		return nil, err
 	}
-
-	return hrecs, nil
+	return NewFile(recs).Image(), nil
 }
 
+// CoalesceOptions tune how CoalesceDataRecsOptions merges data records
+type CoalesceOptions = coalesce.Options
+
 // CoalesceDataRecs merges contiguous runs of data records
 func CoalesceDataRecs(list []*HexRec) []*HexRec {
-	type handler func(r *HexRec)
-	var (
-		dataRecGroup   bool
-		addressCounter uint16
-		outList        []*HexRec
-		data           bytes.Buffer
-		dataBaseAddr   uint16
-		processDataRec handler
-	)
-
-	emitJumboDataRec := func() {
-		var newRec HexRec
-		newRec.Address = dataBaseAddr      // Set Base Address
-		newRec.RecordType = Data           // Set Record Tyoe == Data
-		newRec.Data = data.Bytes()         // Set Data slice within record
-		data.Reset()                       // Clear accumulation buffer
-		outList = append(outList, &newRec) // Append record to output slice
-	}
-
-	processDataRec = func(r *HexRec) {
-		if !dataRecGroup { // have we hit a new run of data recs?
-			dataRecGroup = true
-			dataBaseAddr = r.Address
-			addressCounter = r.Address + uint16(len(r.Data))
-			data.Reset()
-			data.Write(r.Data)
-		} else {
-			if r.Address == addressCounter { // Contiguous with previous?
-				data.Write(r.Data)
-				addressCounter += uint16(len(r.Data))
-			} else { // else, data records are not contiguous
-				// Emit a Jumbo Data Record; reset temp buffer
-				emitJumboDataRec()
-				dataRecGroup = false
-				processDataRec(r)
-			}
-		}
-	}
-
-	for _, rec := range list {
-		if rec.RecordType == Data {
-			processDataRec(rec)
-		} else {
-			if data.Len() > 0 {
-				emitJumboDataRec()
-			}
-			dataRecGroup = false
-			outList = append(outList, rec)
-		}
-	}
-
-	// Check if we need to emit one last jumbo data record
-	if data.Len() > 0 {
-		emitJumboDataRec()
-	}
+	return CoalesceDataRecsOptions(list, CoalesceOptions{})
+}
 
-	return outList
+// CoalesceDataRecsOptions is like CoalesceDataRecs but can additionally
+// bridge small gaps between runs and cap the size of the merged records.
+func CoalesceDataRecsOptions(list []*HexRec, opts CoalesceOptions) []*HexRec {
+	return coalesce.Records(list, coalesce.Adapter[*HexRec, uint16]{
+		IsData:  func(r *HexRec) bool { return r.RecordType == Data },
+		Address: func(r *HexRec) uint16 { return r.Address },
+		Data:    func(r *HexRec) []byte { return r.Data },
+		Make: func(addr uint16, b []byte) *HexRec {
+			return &HexRec{Address: addr, RecordType: Data, Data: b}
+		},
+	}, opts)
 }
@@ -0,0 +1,1139 @@
+package ihex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestDecodeRecordString(t *testing.T) {
+	fmt.Println("TestDecodeRecordString()")
+
+	hr, err := DecodeRecordString(":0300300002337A1E")
+	if err != nil || hr.Address != 0x30 || len(hr.Data) != 3 {
+		fmt.Printf("failure: %v %v\n", hr, err)
+		t.Fail()
+	}
+
+	for _, s := range []string{":", ":00", "0300300002337A1E", ":0400300002337A1D"} {
+		if _, err := DecodeRecordString(s); err == nil {
+			fmt.Printf("failure: %q accepted\n", s)
+			t.Fail()
+		}
+	}
+}
+
+func FuzzDecodeRecordString(f *testing.F) {
+	f.Add(":0300300002337A1E")
+	f.Add(":00000001FF")
+	f.Add(":020000040800F2")
+	f.Fuzz(func(t *testing.T, s string) {
+		DecodeRecordString(s)
+	})
+}
+
+func FuzzParseBytes(f *testing.F) {
+	f.Add([]byte(":0300300002337A1E\n:00000001FF\n"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		ParseBytes(b)
+	})
+}
+
+func TestDump(t *testing.T) {
+	fmt.Println("TestDump()")
+
+	recs, err := ParseBytes([]byte(":020000040800F2\n:0500100048656C6C6FF7\n:00000001FF\n"))
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	var sb strings.Builder
+	Dump(&sb, recs)
+	if !strings.Contains(sb.String(), "08000010  Data") || !strings.Contains(sb.String(), "|Hello|") {
+		fmt.Printf("unexpected dump:\n%s", sb.String())
+		t.Fail()
+	}
+}
+
+func TestExplain(t *testing.T) {
+	fmt.Println("TestExplain()")
+
+	s := Explain(":0500100048656C6C6FF0")
+	for _, want := range []string{"00 (Data)", "48656C6C6F", "F7 (MISMATCH)"} {
+		if !strings.Contains(s, want) {
+			fmt.Printf("explanation lacks %q:\n%s", want, s)
+			t.Fail()
+		}
+	}
+}
+
+func TestParseRecTyp(t *testing.T) {
+	fmt.Println("TestParseRecTyp()")
+
+	for _, s := range []string{"Extended Linear Address", "extended-linear-address", "ExtLinAddr", "04", "0x4"} {
+		if typ, err := ParseRecTyp(s); err != nil || typ != ExtLinAddr {
+			fmt.Printf("%q: %v, %v\n", s, typ, err)
+			t.Fail()
+		}
+	}
+	if _, err := ParseRecTyp("06"); err == nil {
+		fmt.Println("type 06 accepted")
+		t.Fail()
+	}
+	if s := fmt.Sprint(EndOfFile); s != "EOF" {
+		fmt.Printf("EndOfFile prints as %q\n", s)
+		t.Fail()
+	}
+}
+
+func TestNewRecs(t *testing.T) {
+	fmt.Println("TestNewRecs()")
+
+	if _, err := NewDataRec(0, make([]byte, 256)); err == nil {
+		fmt.Println("256 byte data record accepted")
+		t.Fail()
+	}
+	for _, r := range []*HexRec{NewEOFRec(), NewExtLinAddrRec(0x0800),
+		NewStartLinAddrRec(0x08000000), NewStartSegAddrRec(0xF000, 0xFFF0)} {
+		if err := r.Validate(); err != nil {
+			fmt.Println(r, err)
+			t.Fail()
+		}
+	}
+	if (&HexRec{RecordType: ExtLinAddr, Data: []byte{1}}).Validate() == nil {
+		fmt.Println("short ELA record accepted")
+		t.Fail()
+	}
+}
+
+func TestAddressResolver(t *testing.T) {
+	fmt.Println("TestAddressResolver()")
+
+	var (
+		res  AddressResolver
+		data = &HexRec{Address: 0x1234, RecordType: Data, Data: []byte{0}}
+	)
+	steps := []struct {
+		r      *HexRec
+		addr   uint32
+		isData bool
+	}{
+		{data, 0x1234, true},
+		{NewExtLinAddrRec(0x0800), 0, false},
+		{data, 0x08001234, true},
+		{NewExtSegAddrRec(0x1000), 0, false},
+		{data, 0x00011234, true},
+	}
+	for i, s := range steps {
+		if addr, isData := res.Resolve(s.r); addr != s.addr || isData != s.isData {
+			fmt.Printf("step %d: 0x%X %v\n", i, addr, isData)
+			t.Fail()
+		}
+	}
+}
+
+func TestResolveAddresses(t *testing.T) {
+	fmt.Println("TestResolveAddresses()")
+
+	const input = ":0500100048656C6C6FF7\n:020000040800F2\n:0500100048656C6C6FF7\n:00000001FF\n"
+	recs, err := ParseBytes([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ResolveAddresses(recs)
+	if len(got) != 2 || got[0].Address != 0x10 || got[1].Address != 0x08000010 || string(got[1].Data) != "Hello" {
+		fmt.Printf("resolved %v\n", got)
+		t.Fail()
+	}
+
+	var addrs []uint32
+	d := NewDecoder(strings.NewReader(input))
+	for range d.Records() {
+		if addr, isData := d.Address(); isData {
+			addrs = append(addrs, addr)
+		}
+	}
+	if fmt.Sprint(addrs) != "[16 134217744]" {
+		fmt.Println("decoder addresses", addrs)
+		t.Fail()
+	}
+}
+
+func TestDecodeBatch(t *testing.T) {
+	fmt.Println("TestDecodeBatch()")
+
+	lines := []string{":0500100048656C6C6FF7", "", ":020000040800F2", ":00000001FF"}
+	out := make([]HexRec, 3)
+
+	for pass := 0; pass < 2; pass++ {
+		n, err := DecodeBatch(lines, out)
+		if err != nil || n != 3 || string(out[0].Data) != "Hello" ||
+			out[1].RecordType != ExtLinAddr || out[2].RecordType != EndOfFile {
+			fmt.Println(n, err, out)
+			t.Fail()
+		}
+	}
+
+	if n, err := DecodeBatch(lines, out[:2]); n != 2 || err != io.ErrShortBuffer {
+		fmt.Println("expected a short buffer error:", n, err)
+		t.Fail()
+	}
+}
+
+func TestParse(t *testing.T) {
+	fmt.Println("TestParse()")
+
+	fsys := fstest.MapFS{
+		"fw.hex": {Data: []byte(":020000040800F2\r\n\r\n:0500100048656C6C6FF7\r\n:00000001FF")},
+	}
+	f, err := fsys.Open("fw.hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	recs, err := Parse(f)
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+	if len(recs) != 3 || string(recs[1].Data) != "Hello" || recs[2].RecordType != EndOfFile {
+		fmt.Printf("unexpected records: %v\n", recs)
+		t.Fail()
+	}
+
+	if _, err := ParseBytes([]byte(":0500100048656C6C6FF8\n")); err == nil {
+		fmt.Println("bad checksum not detected")
+		t.Fail()
+	}
+}
+
+func TestAddressScale(t *testing.T) {
+	fmt.Println("TestAddressScale()")
+
+	m := image.New()
+	m.Write(0x1FFF0, []byte("word addressed DSP code."))
+	m.Write(0x20010, []byte("!!"))
+
+	var sb strings.Builder
+	x := NewWriter(&sb)
+	x.SetAddressScale(2)
+	if err := x.WriteImage(m); err != nil {
+		t.Fatal(err)
+	}
+	x.Close()
+
+	// Byte address 0x1FFF0 is word address 0xFFF8
+	if !strings.HasPrefix(sb.String(), ":10FFF800") {
+		fmt.Printf("unexpected output:\n%s", sb.String())
+		t.Fail()
+	}
+
+	recs, err := ReadAll(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := image.RequireEqual(NewFileScale(recs, 2).Image(), m); err != nil {
+		fmt.Println(err)
+		t.Fail()
+	}
+
+	x = NewWriter(&sb)
+	x.SetAddressScale(2)
+	if err := x.WriteSegment(image.Segment{Address: 1, Data: []byte{0, 0}}); err == nil {
+		fmt.Println("misaligned segment accepted")
+		t.Fail()
+	}
+}
+
+func TestBankSize(t *testing.T) {
+	fmt.Println("TestBankSize()")
+
+	m := image.New()
+	m.Write(0x1000, []byte("bank zero"))
+	m.Write(0x7FFE, []byte("xyz"))
+
+	var sb strings.Builder
+	x := NewWriter(&sb)
+	x.SetBankSize(0x4000)
+	if err := x.WriteImage(m); err != nil {
+		t.Fatal(err)
+	}
+	x.Close()
+
+	recs, err := ReadAll(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bank 1 holds "xy" at its top, bank 2 "z" at its bottom
+	want := ":0910000062616E6B207A65726F6B\n:020000040001F9\n:023FFE007879D0\n" +
+		":020000040002F8\n:010000007A85\n:00000001FF\n"
+	if sb.String() != want {
+		fmt.Printf("got:\n%swant:\n%s", sb.String(), want)
+		t.Fail()
+	}
+
+	var res AddressResolver
+	res.SetBankSize(0x4000)
+	got := image.New()
+	for _, r := range recs {
+		if a, ok := res.Resolve(r); ok {
+			got.Write(a, r.Data)
+		}
+	}
+	if err := image.RequireEqual(got, m); err != nil {
+		fmt.Println(err)
+		t.Fail()
+	}
+
+	x = NewWriter(&sb)
+	if err := x.SetBankSize(0x10001); err == nil || x.Options().Bank != 0 {
+		fmt.Println("bank size over 64K accepted")
+		t.Fail()
+	}
+	x = NewWriter(&sb, WithBankSize(0x10001))
+	if _, err := x.Write(make([]byte, 16)); err == nil {
+		fmt.Println("bank size over 64K accepted by WithBankSize")
+		t.Fail()
+	}
+}
+
+func TestCloseFlushError(t *testing.T) {
+	fmt.Println("TestCloseFlushError()")
+
+	// 3 bytes are no whole number of 2 byte address units
+	var sb strings.Builder
+	x := NewWriter(&sb, WithAddressScale(2))
+	x.Write([]byte{1, 2, 3})
+	if err := x.Close(); err == nil {
+		fmt.Printf("misaligned data dropped silently:\n%s", sb.String())
+		t.Fail()
+	}
+}
+
+func TestEmitted(t *testing.T) {
+	fmt.Println("TestEmitted()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb)
+	x.Write(make([]byte, 40))
+	if x.Accepted() != 40 || x.Emitted() != 32 || x.Buffered() != 8 {
+		fmt.Println("counts after write:", x.Accepted(), x.Emitted(), x.Buffered())
+		t.Fail()
+	}
+	x.Close()
+	if x.Emitted() != 40 || x.Buffered() != 0 {
+		fmt.Println("counts after close:", x.Accepted(), x.Emitted(), x.Buffered())
+		t.Fail()
+	}
+}
+
+func TestCloseChecks(t *testing.T) {
+	fmt.Println("TestCloseChecks()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb)
+	x.SetCloseChecks(true)
+	if err := x.Close(); !errors.Is(err, ErrNoData) {
+		fmt.Println("empty output:", err)
+		t.Fail()
+	}
+
+	x.Reset(&sb)
+	x.WriteStartLinAddr(0x100)
+	if err := x.Close(); !errors.Is(err, ErrNoData) || !strings.Contains(err.Error(), "start address") {
+		fmt.Println("start address without data:", err)
+		t.Fail()
+	}
+
+	x.Reset(failWriter{})
+	x.Write([]byte{1, 2, 3})
+	if err := x.Close(); err == nil || !strings.Contains(err.Error(), "3 buffered bytes") {
+		fmt.Println("failed flush:", err)
+		t.Fail()
+	}
+
+	sb.Reset()
+	x.Reset(&sb)
+	x.Write([]byte{1})
+	if err := x.Close(); err != nil || !strings.HasSuffix(sb.String(), ":00000001FF\n") {
+		fmt.Println("good output:", err)
+		t.Fail()
+	}
+}
+
+// failWriter fails every write
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestDoubleClose(t *testing.T) {
+	fmt.Println("TestDoubleClose()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb)
+	x.Write([]byte{1})
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Close(); !errors.Is(err, ErrClosed) {
+		fmt.Println("second Close:", err)
+		t.Fail()
+	}
+	if n := strings.Count(sb.String(), ":00000001FF"); n != 1 {
+		fmt.Printf("%d EOF records\n", n)
+		t.Fail()
+	}
+
+	x.Reset(&sb)
+	if err := x.Close(); err != nil {
+		fmt.Println("Close after Reset:", err)
+		t.Fail()
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	fmt.Println("TestWriteAfterClose()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb)
+	x.Close()
+	out := sb.String()
+
+	if _, err := x.Write([]byte{1}); !errors.Is(err, ErrClosed) {
+		fmt.Println("Write:", err)
+		t.Fail()
+	}
+	if err := x.Flush(); !errors.Is(err, ErrClosed) {
+		fmt.Println("Flush:", err)
+		t.Fail()
+	}
+	if err := x.WriteExtLinAddr(1); !errors.Is(err, ErrClosed) {
+		fmt.Println("WriteExtLinAddr:", err)
+		t.Fail()
+	}
+	if sb.String() != out {
+		fmt.Printf("records after EOF:\n%s", sb.String())
+		t.Fail()
+	}
+}
+
+func TestTrailingRecords(t *testing.T) {
+	fmt.Println("TestTrailingRecords()")
+
+	const input = ":0100000001FE\n:00000001FF\n:0100010002FC\n"
+
+	d := NewDecoder(strings.NewReader(input))
+	recs, err := d.DecodeAll()
+	if err != nil || len(recs) != 3 || d.Trailing() != 1 {
+		fmt.Println("lenient:", len(recs), d.Trailing(), err)
+		t.Fail()
+	}
+
+	d.Reset(strings.NewReader(input))
+	d.SetRejectTrailing(true)
+	if _, err := d.DecodeAll(); !errors.Is(err, ErrAfterEnd) || d.Line() != 3 {
+		fmt.Println("strict:", d.Line(), err)
+		t.Fail()
+	}
+}
+
+func TestStrictOrder(t *testing.T) {
+	fmt.Println("TestStrictOrder()")
+
+	for input, want := range map[string]string{
+		":0100000001FE\n:0400000500000100F6\n:00000001FF\n": "",
+		":0100000001FE\n":            "no EOF record at the end of the input",
+		":00000001FF\n:00000001FF\n": "EOF record on line 2 after the EOF record on line 1",
+		":0400000500000100F6\n\n" +
+			":0400000500000100F6\n:00000001FF\n": "second start address record on line 3, the first on line 1",
+	} {
+		d := NewDecoder(strings.NewReader(input))
+		d.SetStrictOrder(true)
+		_, err := d.DecodeAll()
+		if want == "" && err != nil || want != "" && (!errors.Is(err, ErrOrder) || !strings.HasSuffix(err.Error(), want)) {
+			fmt.Printf("%q: %v\n", input, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	fmt.Println("TestStripMetadata()")
+
+	// Segment 0x1FFF puts the record at 0x1FFFE, across a 64K page
+	recs, err := ParseBytes([]byte(":020000021FFFDE\n:04000E00AABBCCDDE0\n:0400000500001000E7\n" +
+		":020000040000FA\n:01002000EEF1\n:00000001FF\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want []*HexRec
+	for _, r := range []*HexRec{NewExtLinAddrRec(1), {Address: 0xFFFE, Data: []byte{0xAA, 0xBB}},
+		NewExtLinAddrRec(2), {Data: []byte{0xCC, 0xDD}}, NewExtLinAddrRec(0),
+		{Address: 0x20, Data: []byte{0xEE}}, NewEOFRec()} {
+		want = append(want, r)
+	}
+	got := StripMetadata(recs)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		fmt.Printf("got  %v\nwant %v\n", got, want)
+		t.Fail()
+	}
+}
+
+func TestEmptyData(t *testing.T) {
+	fmt.Println("TestEmptyData()")
+
+	const input = ":0100000011EE\n:00001000F0\n:00000001FF\n"
+	for p, want := range map[EmptyPolicy]int{EmptyPreserve: 3, EmptyDrop: 2, EmptyReject: -1} {
+		d := NewDecoder(strings.NewReader(input))
+		d.SetEmptyData(p)
+		recs, err := d.DecodeAll()
+		if want < 0 && !errors.Is(err, ErrEmptyData) || want >= 0 && (err != nil || len(recs) != want) {
+			fmt.Println("decode with policy", p, len(recs), err)
+			t.Fail()
+		}
+	}
+
+	for p, want := range map[EmptyPolicy]string{
+		EmptyPreserve: ":020000040001F9\n:00100000F0\n:00000001FF\n",
+		EmptyDrop:     ":00000001FF\n",
+	} {
+		var sb strings.Builder
+		x := NewWriter(&sb)
+		x.SetEmptyData(p)
+		if err := x.WriteSegment(image.Segment{Address: 0x11000}); err != nil {
+			t.Fatal(err)
+		}
+		x.Close()
+		if sb.String() != want {
+			fmt.Printf("write with policy %d: %q\n", p, sb.String())
+			t.Fail()
+		}
+	}
+
+	x := NewWriter(io.Discard)
+	x.SetEmptyData(EmptyReject)
+	if err := x.WriteSegment(image.Segment{Address: 0x11000}); !errors.Is(err, ErrEmptyData) {
+		fmt.Println("empty segment accepted:", err)
+		t.Fail()
+	}
+}
+
+func TestStartCode(t *testing.T) {
+	fmt.Println("TestStartCode()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb)
+	x.SetStartCode('$')
+	x.Write([]byte("Hello"))
+	x.Close()
+	if want := "$0500000048656C6C6F07\n$00000001FF\n"; sb.String() != want {
+		fmt.Printf("got %q, want %q\n", sb.String(), want)
+		t.Fail()
+	}
+
+	d := NewDecoder(strings.NewReader(sb.String()))
+	d.SetStartCode('$')
+	recs, err := d.DecodeAll()
+	if err != nil || len(recs) != 2 || string(recs[0].Data) != "Hello" {
+		fmt.Println(recs, err)
+		t.Fail()
+	}
+	if _, err := NewDecoder(strings.NewReader(sb.String())).DecodeAll(); err == nil {
+		fmt.Println("'$' records accepted by default")
+		t.Fail()
+	}
+
+	o := x.Options()
+	for _, c := range []byte{0, 'A', '7', ' '} {
+		o.StartCode = c
+		if o.Validate() == nil {
+			fmt.Printf("start code %q accepted\n", c)
+			t.Fail()
+		}
+	}
+}
+
+func TestDumper(t *testing.T) {
+	fmt.Println("TestDumper()")
+
+	recs, err := ParseBytes([]byte(":020000040800F2\n:0500100048656C6C6FF7\n:00000001FF\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var all, each strings.Builder
+	Dump(&all, recs)
+	dd := NewDumper(&each)
+	for _, r := range recs {
+		dd.Dump(r)
+	}
+	if each.String() != all.String() {
+		fmt.Printf("incremental dump differs:\n%s", each.String())
+		t.Fail()
+	}
+
+	var paged strings.Builder
+	dd = NewDumper(&paged)
+	dd.SetPage(2)
+	for _, r := range recs {
+		dd.Dump(r)
+	}
+	if n := strings.Count(paged.String(), "Address   Type"); n != 2 || !strings.HasPrefix(paged.String(), "Address") {
+		fmt.Printf("%d headings:\n%s", n, paged.String())
+		t.Fail()
+	}
+}
+
+func TestIntegrity(t *testing.T) {
+	fmt.Println("TestIntegrity()")
+
+	for _, dg := range checksum.Digests {
+		var sb strings.Builder
+		x := NewWriter(&sb)
+		x.SetIntegrity(dg)
+		x.WriteSegment(image.Segment{Address: 0x12340, Data: []byte("integrity trailer ok")})
+		x.WriteStartLinAddr(0x12340)
+		if err := x.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		d := NewDecoder(strings.NewReader(sb.String()))
+		d.SetVerifyIntegrity(true)
+		if _, err := d.DecodeAll(); err != nil {
+			fmt.Println(dg, err)
+			t.Fail()
+		}
+
+		// Swap the two data records, which keeps every line valid
+		lines := strings.Split(sb.String(), "\n")
+		lines[1], lines[2] = lines[2], lines[1]
+		d = NewDecoder(strings.NewReader(strings.Join(lines, "\n")))
+		d.SetVerifyIntegrity(true)
+		if _, err := d.DecodeAll(); !errors.Is(err, ErrIntegrity) {
+			fmt.Println(dg, "reordered records accepted:", err)
+			t.Fail()
+		}
+	}
+
+	d := NewDecoder(strings.NewReader(":0100000011EE\n:00000001FF\n"))
+	d.SetVerifyIntegrity(true)
+	if _, err := d.DecodeAll(); !errors.Is(err, ErrIntegrity) {
+		fmt.Println("missing trailer accepted:", err)
+		t.Fail()
+	}
+}
+
+func TestFixedRecords(t *testing.T) {
+	fmt.Println("TestFixedRecords()")
+
+	m := image.New()
+	m.Write(0xFFFA, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}) // Crosses a page
+	m.Write(0x10013, []byte{10})
+
+	var sb strings.Builder
+	x := NewWriterWidth(&sb, 8)
+	x.SetFixedRecords(0xFF)
+	if err := x.WriteImage(m); err != nil {
+		t.Fatal(err)
+	}
+	x.Write([]byte("Hi"))
+	x.Close()
+
+	recs, err := ReadAll(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data int
+	for _, r := range recs {
+		if r.RecordType == Data {
+			data++
+			if len(r.Data) != 8 || r.Address%8 != 0 {
+				fmt.Printf("record of %d bytes at 0x%04X\n", len(r.Data), r.Address)
+				t.Fail()
+			}
+		}
+	}
+	if data != 4 {
+		fmt.Printf("%d data records:\n%s", data, sb.String())
+		t.Fail()
+	}
+
+	got := NewFile(recs).Image()
+	if b := got.Extract(0xFFF8, 16, 0); !bytes.Equal(b, []byte{0xFF, 0xFF, 1, 2, 3, 4, 5, 6, 7, 8, 9, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}) {
+		fmt.Printf("padded data % X\n", b)
+		t.Fail()
+	}
+
+	x = NewWriterWidth(io.Discard, 24)
+	x.SetFixedRecords(0)
+	if err := x.WriteImage(m); err == nil {
+		fmt.Println("record width not dividing the page accepted")
+		t.Fail()
+	}
+
+	// A padded Flush mid-stream leaves the following data in place
+	sb.Reset()
+	x = NewWriterWidth(&sb, 8)
+	x.SetFixedRecords(0xFF)
+	x.SetCloseChecks(true)
+	x.Write([]byte{1, 2, 3, 4, 5})
+	x.Flush()
+	x.Write([]byte{6, 7, 8, 9, 10})
+	if err := x.Close(); err != nil {
+		fmt.Println("close after padded flush:", err)
+		t.Fail()
+	}
+	if x.Accepted() != 10 || x.Emitted() != 10 {
+		fmt.Printf("accepted %d, emitted %d\n", x.Accepted(), x.Emitted())
+		t.Fail()
+	}
+	recs, err = ReadAll(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 || recs[1].Address != 5 || !bytes.Equal(recs[1].Data, []byte{6, 7, 8, 9, 10, 0xFF, 0xFF, 0xFF}) {
+		fmt.Printf("data after padded flush:\n%s", sb.String())
+		t.Fail()
+	}
+}
+
+func TestWriterAddress32(t *testing.T) {
+	fmt.Println("TestWriterAddress32()")
+
+	data := make([]byte, 0x18)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	var sb strings.Builder
+	x := NewWriter(&sb)
+	x.SetAddress(0x1FFF8)
+	x.Write(data)
+	x.Close()
+
+	recs, err := ReadAll(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []RecTyp
+	for _, r := range recs {
+		types = append(types, r.RecordType)
+	}
+	want := []RecTyp{ExtLinAddr, Data, ExtLinAddr, Data, Data, EndOfFile}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		fmt.Printf("records:\n%s", sb.String())
+		t.Fail()
+	}
+	if got := NewFile(recs).Image().Extract(0x1FFF8, len(data), 0); !bytes.Equal(got, data) {
+		fmt.Printf("data % X\n", got)
+		t.Fail()
+	}
+}
+
+func TestExtract(t *testing.T) {
+	fmt.Println("TestExtract()")
+
+	recs, err := ParseBytes([]byte(":0500100048656C6C6FF7\n:020000040800F2\n:0500100048656C6C6FF7\n:00000001FF\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := Extract(recs, 0x0800000E, 6, 0xFF); string(b) != "\xFF\xFFHell" {
+		fmt.Printf("extracted %q\n", b)
+		t.Fail()
+	}
+	if b := Extract(recs, 0x12, 8, 0); string(b) != "llo\x00\x00\x00\x00\x00" {
+		fmt.Printf("extracted %q\n", b)
+		t.Fail()
+	}
+}
+
+func TestWriteBinary(t *testing.T) {
+	fmt.Println("TestWriteBinary()")
+
+	recs, err := ParseBytes([]byte(":0100000011EE\n:020000040001F9\n:0100000022DD\n:00000001FF\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	base, err := WriteBinary(&buf, recs, 0xFF)
+	if err != nil || base != 0 || buf.Len() != 0x10001 || buf.Bytes()[0] != 0x11 || buf.Bytes()[1] != 0xFF || buf.Bytes()[0x10000] != 0x22 {
+		fmt.Printf("wrote %d bytes at 0x%X, %v\n", buf.Len(), base, err)
+		t.Fail()
+	}
+}
+
+func TestLineEnding(t *testing.T) {
+	fmt.Println("TestLineEnding()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb)
+	x.SetLineEnding(CRLF)
+	x.Write([]byte("CRLF terminated records"))
+	x.Close()
+
+	out := sb.String()
+	if n := strings.Count(out, "\n"); n != 3 || strings.Count(out, "\r\n") != n {
+		fmt.Printf("records not CRLF terminated: %q\n", out)
+		t.Fail()
+	}
+	recs, err := ReadAll(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := NewFile(recs).Image().Extract(0, 23, 0); string(got) != "CRLF terminated records" {
+		fmt.Printf("read back %q\n", got)
+		t.Fail()
+	}
+
+	clone := x.CloneTo(io.Discard)
+	if clone.Options().LineEnding != CRLF {
+		fmt.Println("line ending not cloned")
+		t.Fail()
+	}
+
+	o := x.Options()
+	o.LineEnding = "\n;"
+	if o.Validate() == nil {
+		fmt.Println("printable line ending accepted")
+		t.Fail()
+	}
+}
+
+func TestWriterOptions(t *testing.T) {
+	fmt.Println("TestWriterOptions()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb, WithWidth(4), WithStartAddress(0x1234), WithLineEnding(CRLF))
+	x.Write([]byte("options!"))
+	x.Close()
+
+	recs, err := ReadAll(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []RecTyp
+	for _, r := range recs {
+		types = append(types, r.RecordType)
+	}
+	if !reflect.DeepEqual(types, []RecTyp{Data, Data, StartLinAddr, EndOfFile}) || !strings.HasSuffix(sb.String(), "\r\n") {
+		fmt.Printf("unexpected output:\n%s", sb.String())
+		t.Fail()
+	}
+	if addr, _, ok := NewFile(recs).EntryPoint(); !ok || addr != 0x1234 {
+		fmt.Printf("entry point 0x%X, %v\n", addr, ok)
+		t.Fail()
+	}
+
+	// An explicit start record takes precedence
+	sb.Reset()
+	x = NewWriter(&sb, WithOptions(x.Options()))
+	x.Write([]byte{1})
+	x.WriteStartLinAddr(0x5678)
+	x.Close()
+	if n := strings.Count(sb.String(), ":04000005"); n != 1 {
+		fmt.Printf("%d start records:\n%s", n, sb.String())
+		t.Fail()
+	}
+	if o := x.Options(); o.Width != 4 || o.LineEnding != CRLF || !o.EmitStart {
+		fmt.Printf("options not carried over: %+v\n", o)
+		t.Fail()
+	}
+}
+
+func TestLayout(t *testing.T) {
+	fmt.Println("TestLayout()")
+
+	recs, err := ParseBytes([]byte(":0500100048656C6C6FF7\n:03001200010203E5\n:020000040800F2\n:0500100048656C6C6FF7\n:00000001FF\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []image.Span{
+		{Start: 0x12, End: 0x15, Kind: image.Overlap},
+		{Start: 0x15, End: 0x08000010, Kind: image.Gap},
+	}
+	if got := Layout(recs); !reflect.DeepEqual(got, want) {
+		fmt.Printf("failure: got %v\n", got)
+		t.Fail()
+	}
+}
+
+func TestValidate(t *testing.T) {
+	fmt.Println("TestValidate()")
+
+	text := ":0500100048656C6C6FF7\n:0500100048656C6C6FF8\n\ngarbage\r\n:00000001FF\n"
+	recs, errs, err := Validate(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 || len(errs) != 2 ||
+		errs[0].Line != 2 || errs[0].Offset != 22 || errs[0].RawRecord != ":0500100048656C6C6FF8" ||
+		errs[1].Line != 4 || errs[1].Offset != 45 || errs[1].RawRecord != "garbage" {
+		fmt.Printf("failure: %d records, errors %+v\n", len(recs), errs)
+		t.Fail()
+	}
+
+	d := NewDecoder(strings.NewReader(":0500100048656C6C6FF7\n"))
+	d.SetStrictOrder(true)
+	_, errs, err = d.Validate()
+	if err != nil || len(errs) != 1 || errs[0].Line != 2 || errs[0].Offset != 22 || !errors.Is(errs[0], ErrOrder) {
+		fmt.Printf("failure: errors %+v, %v\n", errs, err)
+		t.Fail()
+	}
+}
+
+func TestLenient(t *testing.T) {
+	fmt.Println("TestLenient()")
+
+	text := "# built by hand\n  :0500100048656c6c6ff7 \t\n   \n; no EOF record\n"
+
+	if _, err := NewDecoder(strings.NewReader(text)).DecodeAll(); err == nil {
+		fmt.Println("failure: deviations accepted by default")
+		t.Fail()
+	}
+
+	d := NewDecoder(strings.NewReader(text))
+	d.SetLenient(AllowWhitespace | AllowComments)
+	d.SetStrictOrder(true)
+	if _, err := d.DecodeAll(); !errors.Is(err, ErrOrder) {
+		fmt.Printf("failure: missing EOF record gives %v\n", err)
+		t.Fail()
+	}
+
+	d = NewDecoder(strings.NewReader(text))
+	d.SetLenient(Lenient)
+	d.SetStrictOrder(true)
+	recs, err := d.DecodeAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || string(recs[0].Data) != "Hello" {
+		fmt.Printf("failure: records %v\n", recs)
+		t.Fail()
+	}
+
+	// The package level read functions take the leniency, and the lines
+	// skipped are logged
+	var log bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&log, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if recs, err := ReadAll(strings.NewReader(text), WithLeniency(Lenient), WithDecoderLogger(l)); err != nil || len(recs) != 1 {
+		fmt.Printf("failure: ReadAll gives %v, %v\n", recs, err)
+		t.Fail()
+	}
+	for _, want := range []string{"skipped comment line\" line=1", "skipped white space line\" line=3", "skipped comment line\" line=4"} {
+		if !strings.Contains(log.String(), want) {
+			fmt.Printf("failure: no %s in log:\n%s", want, log.String())
+			t.Fail()
+		}
+	}
+}
+
+func TestWriterWidthRange(t *testing.T) {
+	fmt.Println("TestWriterWidthRange()")
+
+	for _, x := range []*Writer{
+		NewWriter(io.Discard, WithWidth(300)),
+		NewWriter(io.Discard, WithWidth(0)),
+		NewWriterWidth(io.Discard, 256),
+	} {
+		if _, err := x.Write(make([]byte, 16)); err == nil {
+			fmt.Println("Write accepted width", x.Options().Width)
+			t.Fail()
+		}
+		if err := x.Close(); err == nil {
+			fmt.Println("Close accepted width", x.Options().Width)
+			t.Fail()
+		}
+	}
+
+	m := image.New()
+	m.Write(0, make([]byte, 300))
+	if err := m.Encode(io.Discard, image.IntelHex, image.WithWidth(300)); err == nil {
+		fmt.Println("encoded with width 300")
+		t.Fail()
+	}
+}
+
+// failOn fails the writes holding a given string
+type failOn string
+
+func (s failOn) Write(p []byte) (int, error) {
+	if strings.Contains(string(p), string(s)) {
+		return 0, errors.New("disk full")
+	}
+	return len(p), nil
+}
+
+func TestCloseStartError(t *testing.T) {
+	fmt.Println("TestCloseStartError()")
+
+	for _, check := range []bool{false, true} {
+		x := NewWriter(failOn(":04000005"), WithStartAddress(0x100))
+		x.SetCloseChecks(check)
+		x.Write([]byte{1, 2, 3})
+		if err := x.Close(); err == nil || !strings.Contains(err.Error(), "start record") {
+			fmt.Printf("checks %v: Close returned %v\n", check, err)
+			t.Fail()
+		}
+	}
+
+	x := NewWriter(io.Discard, WithStartAddress(0x100), WithCloseChecks())
+	if err := x.Close(); !errors.Is(err, ErrNoData) || !strings.Contains(err.Error(), "start address") {
+		fmt.Println("start address without data:", err)
+		t.Fail()
+	}
+}
+
+func TestSetPageOffset(t *testing.T) {
+	fmt.Println("TestSetPageOffset()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb)
+	x.WriteExtLinAddr(1)
+	if err := x.SetPageOffset(0x10); err != nil { // Within the page just opened
+		t.Fatal(err)
+	}
+	x.Write([]byte{1, 2, 3, 4})
+	x.Flush()
+	x.SetAddress(0x20010)
+	x.Write([]byte{5})
+	x.Flush()
+	x.SetAddress(0x10) // Absolute, whatever page is open
+	x.Write([]byte{6})
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(sb.String(), ":02000004"); n != 3 {
+		fmt.Printf("%d ELA records:\n%s", n, sb.String())
+		t.Fail()
+	}
+	recs, err := ReadAll(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []AbsRec{{0x10010, []byte{1, 2, 3, 4}}, {0x20010, []byte{5}}, {0x10, []byte{6}}}
+	if got := ResolveAddresses(recs); !reflect.DeepEqual(got, want) {
+		fmt.Printf("got %v\n", got)
+		t.Fail()
+	}
+
+	x = NewWriter(io.Discard)
+	x.SetBankSize(0x8000)
+	if err := x.SetPageOffset(0x8000); err == nil {
+		fmt.Println("offset beyond the bank accepted")
+		t.Fail()
+	}
+}
+
+func TestLogger(t *testing.T) {
+	fmt.Println("TestLogger()")
+
+	var a, b bytes.Buffer
+	debug := &slog.HandlerOptions{Level: slog.LevelDebug}
+
+	// Each decoder logs to its own sink
+	in := ":00000001FF\n:0100000041BE\n"
+	if _, err := ReadAll(strings.NewReader(in), WithDecoderLogger(slog.New(slog.NewTextHandler(&a, debug)))); err != nil {
+		t.Fatal(err)
+	}
+	NewDecoder(strings.NewReader(in), WithDecoderLogger(slog.New(slog.NewTextHandler(&b, debug)))).DecodeAll()
+	for _, log := range []string{a.String(), b.String()} {
+		if !strings.Contains(log, "record after the EOF record") || !strings.Contains(log, "line=2") {
+			fmt.Printf("decoder log:\n%s", log)
+			t.Fail()
+		}
+	}
+
+	// The writer logs the ELA records it emits by itself
+	a.Reset()
+	x := NewWriter(io.Discard, WithLogger(slog.New(slog.NewTextHandler(&a, debug))))
+	x.SetAddress(0x1FFFF)
+	x.Write([]byte{1, 2})
+	x.Close()
+	if n := strings.Count(a.String(), "emitted ELA record"); n != 2 {
+		fmt.Printf("writer log:\n%s", a.String())
+		t.Fail()
+	}
+}
+
+func TestFileFields(t *testing.T) {
+	fmt.Println("TestFileFields()")
+
+	recs, err := ParseBytes([]byte(`:020000040800F2
+:0400100001020304E2
+:040000031234005063
+:0400000508000100EE
+:020014000506DF
+:00000001FF
+`))
+	if err != nil {
+		fmt.Println(err)
+		t.FailNow()
+	}
+	odd := &HexRec{RecordType: ExtLinAddr, Data: []byte{1}} // Malformed, so not applied
+	recs = append(recs[:5:5], odd, recs[5])
+
+	f := NewFile(recs)
+	want := []image.Segment{{Address: 0x08000010, Data: []byte{1, 2, 3, 4, 5, 6}}}
+	if !reflect.DeepEqual(f.Data, want) {
+		fmt.Printf("data %v, want %v\n", f.Data, want)
+		t.Fail()
+	}
+	// The last start record wins; the earlier one is kept in Other
+	if f.Start == nil || *f.Start != (Entry{Address: 0x08000100, Kind: StartLinear}) {
+		fmt.Printf("start %+v\n", f.Start)
+		t.Fail()
+	}
+	if len(f.Other) != 2 || f.Other[0] != recs[2] || f.Other[1] != odd {
+		fmt.Printf("other %v\n", f.Other)
+		t.Fail()
+	}
+	n := 0
+	for range f.Records() {
+		n++
+	}
+	if n != len(recs) {
+		fmt.Printf("%d records iterated, want %d\n", n, len(recs))
+		t.Fail()
+	}
+}
+
+func TestEntryPoint(t *testing.T) {
+	fmt.Println("TestEntryPoint()")
+
+	for _, c := range []struct {
+		in   string
+		addr uint32
+		kind StartKind
+		ok   bool
+	}{
+		{":040000031234005063\n:00000001FF\n", 0x12340050, StartSegment, true},
+		{":0400000508000100EE\n:00000001FF\n", 0x08000100, StartLinear, true},
+		{":0400100001020304E2\n:00000001FF\n", 0, StartNone, false},
+	} {
+		recs, err := ParseBytes([]byte(c.in))
+		if err != nil {
+			fmt.Println(err)
+			t.FailNow()
+		}
+		if addr, kind, ok := NewFile(recs).EntryPoint(); addr != c.addr || kind != c.kind || ok != c.ok {
+			fmt.Printf("%q: 0x%X %v %v, want 0x%X %v %v\n", c.in, addr, kind, ok, c.addr, c.kind, c.ok)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package ihex
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// NewDataRec creates a Data record holding data at addr.  A record
+// carries at most 255 data bytes.
+func NewDataRec(addr uint16, data []byte) (*HexRec, error) {
+	r := &HexRec{Address: addr, RecordType: Data, Data: data}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewEOFRec creates an End Of File record
+func NewEOFRec() *HexRec {
+	return &HexRec{RecordType: EndOfFile}
+}
+
+// NewExtSegAddrRec creates an Extended Segment Address record; data
+// addresses that follow are offset by seg << 4
+func NewExtSegAddrRec(seg uint16) *HexRec {
+	return &HexRec{RecordType: ExtSegAddr, Data: binary.BigEndian.AppendUint16(nil, seg)}
+}
+
+// NewStartSegAddrRec creates a Start Segment Address record for the
+// 80x86 CS:IP register pair
+func NewStartSegAddrRec(cs, ip uint16) *HexRec {
+	b := binary.BigEndian.AppendUint16(nil, cs)
+	return &HexRec{RecordType: StartSegAddr, Data: binary.BigEndian.AppendUint16(b, ip)}
+}
+
+// NewExtLinAddrRec creates an Extended Linear Address record; upper
+// becomes the upper 16 bits of the data addresses that follow
+func NewExtLinAddrRec(upper uint16) *HexRec {
+	return &HexRec{RecordType: ExtLinAddr, Data: binary.BigEndian.AppendUint16(nil, upper)}
+}
+
+// NewStartLinAddrRec creates a Start Linear Address record holding the
+// 32-bit entry point eip
+func NewStartLinAddrRec(eip uint32) *HexRec {
+	return &HexRec{RecordType: StartLinAddr, Data: binary.BigEndian.AppendUint32(nil, eip)}
+}
+
+// Validate checks that the record could appear in a conforming file:
+// the record type is known, the data length suits the type and, apart
+// from Data records, the address field is zero.  Integrity trailers
+// count as conforming.
+func (r *HexRec) Validate() error {
+	var want int // required data length; -1 for Data records
+
+	switch r.RecordType {
+	case Data, Integrity:
+		want = -1
+	case EndOfFile:
+		want = 0
+	case ExtSegAddr, ExtLinAddr:
+		want = 2
+	case StartSegAddr, StartLinAddr:
+		want = 4
+	default:
+		return fmt.Errorf("unknown record type %v", r.RecordType)
+	}
+
+	if want < 0 {
+		if len(r.Data) > 255 {
+			return fmt.Errorf("%d data bytes exceed the 255 byte record limit", len(r.Data))
+		}
+		return nil
+	}
+
+	if len(r.Data) != want {
+		return fmt.Errorf("%v record needs %d data bytes, has %d", r.RecordType, want, len(r.Data))
+	}
+	if r.Address != 0 {
+		return errors.New("address field of a non-data record must be zero")
+	}
+	return nil
+}
+
+// StripMetadata reduces the records of a file to its data, for loaders
+// that accept nothing else and for scrubbing build metadata.  Start
+// address records and records of unknown type are dropped, and the
+// extended address records are replaced by the fewest Extended Linear
+// Address records placing the data at its original absolute addresses;
+// a data record crossing a 64K page is split.  A single EOF record ends
+// the result.  The data of recs is shared, not copied.
+func StripMetadata(recs []*HexRec) []*HexRec {
+	var (
+		out []*HexRec
+		res AddressResolver
+		ela uint16 // Upper address bits in effect in the result
+	)
+	for _, r := range recs {
+		addr, isData := res.Resolve(r)
+		if !isData {
+			continue
+		}
+		for data := r.Data; len(data) > 0; {
+			n := min(len(data), int(0x10000-addr&0xFFFF))
+			if hi := uint16(addr >> 16); hi != ela {
+				out = append(out, NewExtLinAddrRec(hi))
+				ela = hi
+			}
+			out = append(out, &HexRec{Address: uint16(addr), RecordType: Data, Data: data[:n]})
+			addr += uint32(n)
+			data = data[n:]
+		}
+	}
+	return append(out, NewEOFRec())
+}
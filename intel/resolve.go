@@ -0,0 +1,76 @@
+package ihex
+
+// AddressResolver turns the 16-bit addresses of Data records into
+// absolute addresses by tracking the Extended Segment and Extended
+// Linear Address records that precede them.  It lets streaming consumers
+// work with absolute addresses without buffering the whole file.  The
+// zero value is ready to use.
+type AddressResolver struct {
+	base  uint32 // Upper address bits currently in effect
+	scale uint32 // Bytes per address unit, 0 taken as 1
+	bank  uint32 // Bank size, 0 for none
+}
+
+// SetScale makes the resolver take file addresses as counting units of
+// n bytes, the inverse of Writer.SetAddressScale, so Resolve returns
+// byte addresses for files written for word-addressed targets
+func (a *AddressResolver) SetScale(n uint32) {
+	a.scale = n
+}
+
+// SetBankSize makes the resolver take Extended Linear Address records as
+// bank numbers for banks of n address units, the inverse of
+// Writer.SetBankSize
+func (a *AddressResolver) SetBankSize(n uint32) {
+	a.bank = n
+}
+
+// Resolve feeds the next record of the stream to the resolver.  For a
+// Data record it returns the absolute address of its first byte and
+// isData true; for all other records it returns 0 and false.
+func (a *AddressResolver) Resolve(r *HexRec) (absAddr uint32, isData bool) {
+	a.base = nextBase(a.base, r)
+	if r.RecordType != Data {
+		return 0, false
+	}
+	base := a.base
+	if a.bank > 0 {
+		base = (base >> 16) * a.bank
+	}
+	return (base + uint32(r.Address)) * max(a.scale, 1), true
+}
+
+// Base returns the upper address bits currently in effect
+func (a *AddressResolver) Base() uint32 {
+	return a.base
+}
+
+// Reset returns the resolver to its initial state, for reuse on a new
+// stream
+func (a *AddressResolver) Reset() {
+	a.base = 0
+}
+
+// AbsRec is the data of a Data record at its absolute address
+type AbsRec struct {
+	Address uint32 // Absolute address of the first byte
+	Data    []byte
+}
+
+// ResolveAddresses returns the Data records of recs in file order, each
+// at the absolute address resolved against the Extended Segment and
+// Extended Linear Address records preceding it, so data above 64K can be
+// used without tracking those records.  Other records are left out.  The
+// data of recs is shared, not copied.
+func ResolveAddresses(recs []*HexRec) []AbsRec {
+	var (
+		out []AbsRec
+		res AddressResolver
+	)
+	for _, r := range recs {
+		if addr, isData := res.Resolve(r); isData {
+			out = append(out, AbsRec{Address: addr, Data: r.Data})
+		}
+	}
+	return out
+}
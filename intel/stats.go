@@ -0,0 +1,36 @@
+package ihex
+
+import (
+	"github.com/peteArnt/GoHexIO/internal/stats"
+)
+
+// Stats summarizes the content of a hex file
+type Stats struct {
+	Records    int            // Total number of records
+	ByType     map[RecTyp]int // Record counts keyed by record type
+	DataBytes  int            // Total number of data bytes in Data records
+	MinAddress uint32         // Lowest absolute data address
+	MaxAddress uint32         // Highest absolute data address (inclusive)
+	LargestGap uint32         // Largest run of unused addresses between data
+}
+
+// Stats gathers record counts, address bounds and gap information in a
+// single pass over the file.  Data addresses are resolved against any
+// Extended Segment or Extended Linear Address records that precede them.
+func (f *File) Stats() Stats {
+	var (
+		t   = stats.Tally[RecTyp]{ByType: make(map[RecTyp]int)}
+		res AddressResolver
+	)
+
+	for _, r := range f.recs {
+		t.Record(r.RecordType)
+		if addr, isData := res.Resolve(r); isData {
+			t.Data(addr, len(r.Data))
+		}
+	}
+
+	st := Stats{Records: t.Records, ByType: t.ByType, DataBytes: t.DataBytes}
+	st.MinAddress, st.MaxAddress, st.LargestGap = t.Bounds()
+	return st
+}
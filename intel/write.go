@@ -2,63 +2,310 @@ package ihex
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"strings"
+	"log/slog"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+	"github.com/peteArnt/GoHexIO/image"
+	"github.com/peteArnt/GoHexIO/internal/atomicfile"
+	"github.com/peteArnt/GoHexIO/internal/hexenc"
+	"github.com/peteArnt/GoHexIO/internal/integrity"
+)
+
+// Errors reported by a Writer
+var (
+	ErrNoData = errors.New("no data written")       // Closed without having written any data
+	ErrClosed = errors.New("Writer already closed") // Used after Close
+)
+
+// Record terminators for SetLineEnding
+const (
+	LF   = "\n"   // Unix, the default
+	CRLF = "\r\n" // DOS, expected by many device programmers and PROM burners
 )
 
 // Writer implements an Intel Hex file writer
 type Writer struct {
-	w     io.Writer    // Underlying writer object
-	width int          // Standard length for data records
-	addr  uint16       // Address counter for data records
-	fifo  bytes.Buffer // FIFO for writes
+	w     io.Writer          // Underlying writer object
+	width int                // Standard length for data records
+	addr  uint32             // File address counter for data records
+	ela   uint16             // Upper address bits from the last ELA record
+	fifo  bytes.Buffer       // FIFO for writes
+	sum   checksum.Algorithm // Record checksum algorithm
+	code  byte               // Start code opening each record
+	log   *slog.Logger       // Optional diagnostics sink
+	trace func(HexRec)       // Optional per-record callback
+	scale int                // Bytes per address unit, 0 taken as 1
+	bank  uint32             // Bank size addresses wrap at, 0 for 64K pages
+	empty EmptyPolicy        // Handling of empty segments
+	fixed bool               // Pad every data record to width
+	fill  byte               // Value of the padding bytes
+	eol   string             // Record terminator, "" for "\n"
+
+	start     uint32 // Start linear address written at Close
+	emitStart bool   // Write it, unless a start record has been written
+
+	integrity checksum.Digest // Digest of the integrity trailer, "" for none
+	digest    *integrity.Sum  // Digest of the records written so far
+
+	err      error // Configuration error, reported by every record written
+	started  bool  // A start address record has been written
+	fin      bool  // Close() has been called
+	check    bool  // Close checks the output for sanity
+	accepted int64 // Data bytes taken by Write
+	emitted  int64 // Data bytes serialized into data records
+
+	bin  bytes.Buffer // Scratch space for the binary record image
+	line []byte       // Scratch space for the ASCII record
 }
 
-// NewWriterWidth creates a new Intel Hex writer with a specific data record length
+// NewWriterWidth creates a new Intel Hex writer with a specific data
+// record length.  A width outside 1..255 makes every write fail.
 func NewWriterWidth(w io.Writer, width int) *Writer {
-	return &Writer{w: w, width: width}
+	x := &Writer{w: w, width: width, sum: checksum.TwosComplement, code: ':'}
+	x.err = x.Options().Validate()
+	return x
+}
+
+// NewWriter Creates a new Intel Hex writer with a default length,
+// configured by opts.  If the resulting configuration fails
+// Options.Validate, every write fails with that error.
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	x := NewWriterWidth(w, 16)
+	for _, o := range opts {
+		o(x)
+	}
+	x.err = x.Options().Validate()
+	return x
 }
 
-// NewWriter Creates a new Intel Hex writer with a default length
-func NewWriter(w io.Writer) *Writer {
-	return NewWriterWidth(w, 16)
+// Reset discards any buffered data and makes the writer start over on w
+// at address 0, keeping its configuration and scratch buffers
+func (x *Writer) Reset(w io.Writer) {
+	x.w = w
+	x.addr = 0
+	x.ela = 0
+	x.fifo.Reset()
+	x.started = false
+	x.fin = false
+	x.accepted, x.emitted = 0, 0
+	x.digest = integrity.NewSum(x.integrity)
 }
 
-// SetAddress sets the data record base address within the writer
-func (x *Writer) SetAddress(a uint16) {
+// Accepted returns the number of data bytes taken by Write since the
+// writer was created or Reset, whether or not they are in records yet
+func (x *Writer) Accepted() int64 {
+	return x.accepted
+}
+
+// Emitted returns the number of data bytes serialized into data records
+// since the writer was created or Reset.  Accepted less Emitted is the
+// data still buffered, waiting for a full record or a Flush.
+func (x *Writer) Emitted() int64 {
+	return x.emitted
+}
+
+// Buffered returns the number of data bytes held back waiting for a full
+// record or a Flush
+func (x *Writer) Buffered() int {
+	return x.fifo.Len()
+}
+
+// SetAddress sets the full 32-bit file address of the next data record.
+// Extended Linear Address records are emitted as needed when data is
+// written there and as it crosses into further 64K pages, or banks with
+// SetBankSize.
+func (x *Writer) SetAddress(a uint32) {
 	x.addr = a
 }
 
-// Emit generic data record
-func (x *Writer) emitDataRecord(p []byte) error {
-	// collect all the stuff that goes into this type of record
-	var data = []interface{}{
-		byte(len(p)), // byte count
-		x.addr,       // standard 16-bit base address
-		byte(Data),   // record type
-		p,            // slice of data
+// SetPageOffset sets the address counter to offset off within the page,
+// or bank, of the last Extended Linear Address record, as SetAddress did
+// when the address counter had 16 bits.  It is for callers that write
+// their own records with WriteExtLinAddr.
+func (x *Writer) SetPageOffset(off uint16) error {
+	page := x.page()
+	if uint32(off) >= page {
+		return fmt.Errorf("page offset 0x%X lies outside the page of 0x%X", off, page)
 	}
+	x.addr = uint32(x.ela)*page + uint32(off)
+	return nil
+}
 
-	err := x.emitRecord(data)
-	if err != nil {
-		return fmt.Errorf("emitDataRecord: %v", err)
+// page returns the number of address units an Extended Linear Address
+// record covers: a bank, or 64K
+func (x *Writer) page() uint32 {
+	if x.bank > 0 {
+		return x.bank
 	}
+	return 0x10000
+}
 
-	x.addr += uint16(len(p))
+// SetAddressScale makes addresses in the output count units of n bytes,
+// e.g. 2 for word-addressed DSPs and PICs whose programmers expect word
+// addresses.  Addresses passed to SetAddress and WriteExtLinAddr are
+// file addresses already; WriteSegment and WriteImage take byte
+// addresses and divide them by n.  Data records, and hence the record
+// width, must then hold a multiple of n bytes.
+func (x *Writer) SetAddressScale(n int) {
+	x.scale = n
+}
 
+// SetBankSize makes WriteSegment and WriteImage wrap addresses modulo a
+// bank of n address units, as banked EPROM programmers expect: data
+// records carry the address within the bank, and each bank is opened by
+// an Extended Linear Address record holding its number.  n above
+// 0x10000 is rejected, leaving the bank size unchanged; 0 restores the
+// standard 64K pages.
+func (x *Writer) SetBankSize(n uint32) error {
+	if n > 0x10000 {
+		return fmt.Errorf("bank size 0x%X exceeds 64K", n)
+	}
+	x.bank = n
 	return nil
 }
 
-// Write if the idiomatic Go Write() method.  The size of p can be
-// be up to 64K.
-func (x *Writer) Write(p []byte) (int, error) {
+// unit returns the number of bytes per address unit
+func (x *Writer) unit() int {
+	return max(x.scale, 1)
+}
+
+// SetCloseChecks makes Close fail with a descriptive error when the
+// output is suspect: no data was written, wrapping ErrNoData, a start
+// address was given without any data, or buffered data could not be
+// flushed.  Such output is then left without its terminating records.
+func (x *Writer) SetCloseChecks(on bool) {
+	x.check = on
+}
+
+// SetEmptyData selects what WriteSegment makes of a segment without data:
+// a zero-length data record at its address, the default, nothing, or an
+// error wrapping ErrEmptyData
+func (x *Writer) SetEmptyData(p EmptyPolicy) {
+	x.empty = p
+}
+
+// SetIntegrity makes Close write an integrity trailer before the EOF
+// record: a record of the vendor type Integrity whose content, such as
+// "GoHexIO CRC32:1a2b3c4d", carries digest d of every record before it.
+// Decoders verify it under SetVerifyIntegrity; tools sticking to the
+// standard record types reject such files.  "" writes none.
+func (x *Writer) SetIntegrity(d checksum.Digest) {
+	x.integrity = d
+	x.digest = integrity.NewSum(d)
+}
+
+// SetStartCode sets the character opening each record, for the Intel Hex
+// variants of legacy toolchains using e.g. '$' instead of the standard
+// ':'
+func (x *Writer) SetStartCode(c byte) {
+	x.code = c
+}
+
+// SetLineEnding sets the terminator written after each record, e.g.
+// CRLF for device programmers that insist on DOS line endings.  The
+// readers of this package split records at line feeds, accepting LF and
+// CRLF alike, so a bare "\r" is for other tools only.  "" restores the
+// default LF.
+func (x *Writer) SetLineEnding(eol string) {
+	x.eol = eol
+}
+
+// SetFixedRecords makes every data record carry exactly the record
+// width, for ROM emulators that choke on runt records: the data a Flush
+// leaves short of a full record is padded with fill, though later data
+// still follows on from the end of the data, and WriteImage and
+// WriteSegment pad the data to whole records aligned to multiples of the
+// width, which must then divide the 64K page or bank.  Segments without
+// data write nothing unless SetEmptyData rejects them.
+func (x *Writer) SetFixedRecords(fill byte) {
+	x.fixed = true
+	x.fill = fill
+}
+
+// SetChecksum selects the record checksum algorithm, for target tools
+// that deviate from the Intel standard
+func (x *Writer) SetChecksum(a checksum.Algorithm) {
+	x.sum = a
+}
+
+// Emit generic data record at the address counter, preceded by an
+// Extended Linear Address record if it lies in another page than the
+// last one; data crossing into the next page is split there
+func (x *Writer) emitDataRecord(p []byte) error {
+	u := x.unit()
+	if len(p)%u != 0 {
+		return fmt.Errorf("emitDataRecord: %d bytes are not a multiple of the address scale %d",
+			len(p), u)
+	}
+
+	page := x.page()
+	for {
+		if x.addr/page > 0xFFFF {
+			return fmt.Errorf("emitDataRecord: bank number 0x%X exceeds 16 bits", x.addr/page)
+		}
+		if hi := uint16(x.addr / page); hi != x.ela {
+			if err := x.WriteExtLinAddr(hi); err != nil {
+				return err
+			}
+			x.logger().Debug("ihex: emitted ELA record", "page", hi, "address", x.addr)
+		}
+		n := min(len(p), int(page-x.addr%page)*u)
+
+		// collect all the stuff that goes into this type of record
+		var data = []interface{}{
+			byte(n),               // byte count
+			uint16(x.addr % page), // standard 16-bit base address
+			byte(Data),            // record type
+			p[:n],                 // slice of data
+		}
+
+		err := x.emitRecord(data)
+		if err != nil {
+			return fmt.Errorf("emitDataRecord: %v", err)
+		}
+
+		x.addr += uint32(n / u)
+		x.emitted += int64(n)
+		if p = p[n:]; len(p) == 0 {
+			return nil
+		}
+	}
+}
+
+// Write if the idiomatic Go Write() method.  Data is written from the
+// address counter on, emitting Extended Linear Address records as it
+// crosses 64K pages.  Data short of a full record is buffered, yet
+// counted as written; Emitted tells how much has gone into records.
+func (x *Writer) Write(p []byte) (n int, err error) {
 	var (
 		originalXferLen = len(p)
 		xferLen         int
 	)
+	if x.fin {
+		return 0, ErrClosed
+	}
+	if x.err != nil {
+		return 0, x.err
+	}
+	defer func() { x.accepted += int64(n) }()
+
+	// Fast path: with nothing buffered, encode full width records
+	// straight from the caller's slice and only buffer the tail
+	if x.fifo.Len() == 0 {
+		for len(p) >= x.width {
+			err := x.emitDataRecord(p[:x.width])
+			if err != nil {
+				return xferLen, err
+			}
+			xferLen += x.width
+			p = p[x.width:]
+		}
+	}
 
 	// Write caller's data to our internal FIFO
 	x.fifo.Write(p)
@@ -81,20 +328,63 @@ func (x *Writer) Write(p []byte) (int, error) {
 // output stream; the effect is a runt hex record written to the
 // output stream.
 func (x *Writer) Flush() error {
-	if x.fifo.Len() > 0 {
-		err := x.emitDataRecord(x.fifo.Next(x.fifo.Len()))
+	if x.fin {
+		return ErrClosed
+	}
+	if n := x.fifo.Len(); n > 0 {
+		p := x.fifo.Next(n)
+		pad := 0
+		if x.fixed && n < x.width {
+			// Pad a copy, not the FIFO's buffer
+			pad = x.width - n
+			p = append(p[:n:n], bytes.Repeat([]byte{x.fill}, pad)...)
+		}
+		err := x.emitDataRecord(p)
 		if err != nil {
 			return err
 		}
+		// The padding is not data: leave the address counter at the
+		// end of the data and keep it out of the emitted count
+		x.addr -= uint32(pad / x.unit())
+		x.emitted -= int64(pad)
 	}
 	return nil
 }
 
-// Close the output Stream.
+// Close the output Stream.  Closing a second time fails with ErrClosed
+// and writes nothing, so there is never more than one EOF record.
 // Note: the underlying io.Writer is NOT closed
 func (x *Writer) Close() error {
+	if x.fin {
+		return ErrClosed
+	}
+	defer func() { x.fin = true }()
+
 	// Flush any residual data
-	x.Flush()
+	err := x.Flush()
+	if x.check {
+		if err := x.checkClose(err); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Write the start address given by WithStartAddress unless a start
+	// record has been written already
+	if x.emitStart && !x.started {
+		if err := x.WriteStartLinAddr(x.start); err != nil {
+			return fmt.Errorf("Close: start record: %w", err)
+		}
+		x.logger().Debug("ihex: emitted start record", "address", x.start)
+	}
+
+	if x.integrity != "" {
+		if err := x.emitTrailer(); err != nil {
+			return err
+		}
+	}
 
 	// Build up an EOF record
 	var data = []interface{}{
@@ -105,12 +395,58 @@ func (x *Writer) Close() error {
 
 	// Write the EOF record; this will be the last
 	// entity written to the stream.
-	return x.emitRecord(data)
+	err = x.emitRecord(data)
+	if err != nil {
+		return err
+	}
+
+	x.logger().Debug("ihex: emitted EOF record")
+	return nil
+}
+
+// emitTrailer writes the integrity trailer, which is not itself digested
+func (x *Writer) emitTrailer() error {
+	if x.digest == nil {
+		return fmt.Errorf("unknown integrity digest %q", x.integrity)
+	}
+	b := x.digest.Trailer()
+	x.digest = nil
+
+	err := x.emitRecord([]interface{}{byte(len(b)), uint16(0), byte(Integrity), b})
+	if err != nil {
+		return err
+	}
+
+	x.logger().Debug("ihex: emitted integrity trailer", "digest", x.integrity)
+	return nil
 }
 
-// Generic emit-record
+// checkClose returns the error Close reports under SetCloseChecks, given
+// the result of the final flush
+func (x *Writer) checkClose(flushErr error) error {
+	switch {
+	case flushErr != nil:
+		return fmt.Errorf("Close: %d buffered bytes could not be flushed: %w",
+			x.accepted-x.emitted, flushErr)
+	case x.emitted == 0 && (x.started || x.emitStart):
+		return fmt.Errorf("Close: start address set, but %w", ErrNoData)
+	case x.emitted == 0:
+		return fmt.Errorf("Close: %w", ErrNoData)
+	}
+	return nil
+}
+
+// Generic emit-record; nothing may follow the EOF record
 func (x *Writer) emitRecord(data []interface{}) error {
-	buf := new(bytes.Buffer)
+	if x.fin {
+		return ErrClosed
+	}
+	if x.err != nil {
+		return x.err
+	}
+
+	buf := &x.bin
+	buf.Reset()
 
 	// construct segment address record image
 	for _, v := range data {
@@ -121,17 +457,37 @@ func (x *Writer) emitRecord(data []interface{}) error {
 	}
 
 	// append checksum
-	err := buf.WriteByte(calcChecksum(buf.Bytes()))
+	err := buf.WriteByte(x.sum.Sum(buf.Bytes()))
 	if err != nil {
 		return fmt.Errorf("internal inconsistency writing to bytes.Buffer: %v", err)
 	}
 
-	s := strings.ToUpper(hex.EncodeToString(buf.Bytes()))
-	_, err = fmt.Fprintf(x.w, ":%s\n", s)
+	// Render the record straight into the reused line buffer
+	x.line = append(x.line[:0], x.code)
+	x.line = hexenc.AppendUpper(x.line, buf.Bytes())
+	if x.eol == "" {
+		x.line = append(x.line, '\n')
+	} else {
+		x.line = append(x.line, x.eol...)
+	}
+
+	_, err = x.w.Write(x.line)
 	if err != nil {
 		return fmt.Errorf("emitRecord: Failure formatting Intel Hex record: %v", err)
 	}
 
+	b := buf.Bytes()
+	if x.digest != nil {
+		x.digest.Add(b[3], uint32(binary.BigEndian.Uint16(b[1:3])), b[4:len(b)-1])
+	}
+	if x.trace != nil {
+		x.trace(HexRec{
+			Address:    binary.BigEndian.Uint16(b[1:3]),
+			RecordType: RecTyp(b[3]),
+			Data:       b[4 : len(b)-1],
+		})
+	}
+
 	return nil
 }
 
@@ -159,10 +515,17 @@ func (x *Writer) WriteStartSegAddr(cs, ip uint16) error {
 		ip,                 // 80x86 processor IP register value
 	}
 
-	return x.emitRecord(data)
+	if err := x.emitRecord(data); err != nil {
+		return err
+	}
+	x.started = true
+	return nil
 }
 
-// WriteExtLinAddr writes an Extended Linear Address record
+// WriteExtLinAddr writes an Extended Linear Address record and moves
+// the address counter to the same offset within the page it opens.
+// Data records emit the records they need by themselves; see SetAddress
+// and SetPageOffset.
 func (x *Writer) WriteExtLinAddr(ela uint16) error {
 	// collect all the stuff that goes into this type of record
 	var data = []interface{}{
@@ -172,7 +535,14 @@ func (x *Writer) WriteExtLinAddr(ela uint16) error {
 		ela,              // upper 16-bits for all 00 type records
 	}
 
-	return x.emitRecord(data)
+	err := x.emitRecord(data)
+	if err != nil {
+		return err
+	}
+
+	x.ela = ela
+	x.addr = uint32(ela)*x.page() + x.addr%x.page()
+	return nil
 }
 
 // WriteStartLinAddr writes a Start Extended Linear Address record
@@ -185,14 +555,203 @@ func (x *Writer) WriteStartLinAddr(eip uint32) error {
 		eip,                // 32-bit value loaded into the EIP register
 	}
 
-	return x.emitRecord(data)
+	if err := x.emitRecord(data); err != nil {
+		return err
+	}
+	x.started = true
+	return nil
 }
 
-// Calculate checksum value based on Intel Hex Spec
-func calcChecksum(buf []byte) byte {
-	var cs int
-	for _, b := range buf {
-		cs += int(b)
+// CopyContext copies binary data from src into the Intel Hex writer dst
+// until EOF is reached on src, returning the number of bytes copied.
+// The copy is abandoned with ctx.Err() if ctx is cancelled or its
+// deadline expires.  Residual data is left buffered in dst; the caller
+// is still responsible for calling Flush() or Close().
+func CopyContext(ctx context.Context, dst *Writer, src io.Reader) (int64, error) {
+	var (
+		buf     = make([]byte, 32*1024)
+		written int64
+	)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		n, err := src.Read(buf)
+		if n > 0 {
+			nw, werr := dst.Write(buf[:n])
+			written += int64(nw)
+			if werr != nil {
+				return written, werr
+			}
+		}
+
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// WriteImage writes the data of memory image m through the writer.
+// Extended Linear Address records are emitted whenever data crosses into
+// a different 64K page.  Any data already buffered is flushed first.
+func (x *Writer) WriteImage(m *image.Image) error {
+	if x.fixed {
+		m = m.Pad(x.width, x.fill)
+	}
+	for _, s := range m.Segments() {
+		if err := x.writeSegment(s); err != nil {
+			return err
+		}
+	}
+
+	return x.Flush()
+}
+
+// WriteSegment writes the data of s through the writer starting at its
+// absolute address, emitting Extended Linear Address records as needed,
+// one per 64K page or per bank if a bank size is set.
+// Any data already buffered is flushed first, and s is flushed in turn,
+// so segments may be written in any order.  A segment without data is
+// handled as set by SetEmptyData.
+func (x *Writer) WriteSegment(s image.Segment) error {
+	if !x.fixed || len(s.Data) == 0 {
+		return x.writeSegment(s)
+	}
+	m := image.New()
+	m.Write(s.Address, s.Data)
+	for _, s := range m.Pad(x.width, x.fill).Segments() {
+		if err := x.writeSegment(s); err != nil {
+			return err
+		}
 	}
-	return byte(-cs)
+	return nil
+}
+
+// writeSegment is WriteSegment without the padding of SetFixedRecords
+func (x *Writer) writeSegment(s image.Segment) error {
+	u := x.unit()
+	if s.Address%uint32(u) != 0 {
+		return fmt.Errorf("segment at 0x%X is not aligned to the address scale %d", s.Address, u)
+	}
+
+	page := x.page()
+	if x.fixed && int(page)*u%x.width != 0 {
+		return fmt.Errorf("record width %d does not divide the page of 0x%X bytes", x.width, int(page)*u)
+	}
+
+	// addr is a file address from here on
+	addr, data := s.Address/uint32(u), s.Data
+	if len(data) == 0 {
+		if ok, err := x.emptySegment(s.Address); !ok {
+			return err
+		}
+		if err := x.seek(addr, page, s.Address); err != nil {
+			return err
+		}
+		return x.emitDataRecord(nil)
+	}
+	for len(data) > 0 {
+		// Never let a record straddle a page or bank boundary
+		n := min(len(data), int(page-addr%page)*u)
+
+		if err := x.seek(addr, page, s.Address); err != nil {
+			return err
+		}
+		if _, err := x.Write(data[:n]); err != nil {
+			return err
+		}
+
+		addr += uint32(n / u)
+		data = data[n:]
+	}
+
+	return x.Flush()
+}
+
+// seek flushes any buffered data and moves the address counter to file
+// address addr in pages of the given size.  at is the byte address
+// reported in errors.
+func (x *Writer) seek(addr, page, at uint32) error {
+	if err := x.Flush(); err != nil {
+		return err
+	}
+	if addr/page > 0xFFFF {
+		return fmt.Errorf("bank number 0x%X at 0x%X exceeds 16 bits", addr/page, at)
+	}
+	x.SetAddress(addr)
+	return nil
+}
+
+// emptySegment applies the empty data policy to a segment at addr without data;
+// it reports whether a marker record is to be written
+func (x *Writer) emptySegment(addr uint32) (bool, error) {
+	switch x.empty {
+	case EmptyDrop:
+		return false, nil
+	case EmptyReject:
+		return false, fmt.Errorf("%w at 0x%X", ErrEmptyData, addr)
+	}
+	return !x.fixed, nil
+}
+
+func init() {
+	image.RegisterEncoder(image.IntelHex, encode)
+}
+
+// Encode writes the memory image m to w as Intel Hex, followed by an EOF
+// record.
+func Encode(w io.Writer, m *image.Image) error {
+	return encode(w, m, image.EncodeOptions{})
+}
+
+// encode is Encode configured by o: o.Width bytes per data record, 0 for
+// the default, and a Start Linear Address record for o.Start if
+// o.HasStart is set and o.DataOnly is not
+func encode(w io.Writer, m *image.Image, o image.EncodeOptions) error {
+	var opts []Option
+	if o.Width > 0 {
+		opts = append(opts, WithWidth(o.Width))
+	}
+	if o.HasStart && !o.DataOnly {
+		opts = append(opts, WithStartAddress(o.Start))
+	}
+	x := NewWriter(w, opts...)
+	if err := x.WriteImage(m); err != nil {
+		return err
+	}
+	return x.Close()
+}
+
+// WriteFile writes the memory image m to the file named fn as Intel Hex
+// configured by opts, where a zero Width or nil Checksum selects the
+// default.  The file is replaced atomically: the records go to a
+// temporary file renamed over fn once complete, so an interrupted
+// conversion never leaves a truncated file for a programmer to pick up.
+func WriteFile(fn string, m *image.Image, opts Options) error {
+	if opts.Width == 0 {
+		opts.Width = 16
+	}
+	if opts.Checksum == nil {
+		opts.Checksum = checksum.TwosComplement
+	}
+	if opts.StartCode == 0 {
+		opts.StartCode = ':'
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(fn, 0644, func(w io.Writer) error {
+		x := &Writer{w: w}
+		x.apply(opts)
+		if err := x.WriteImage(m); err != nil {
+			return err
+		}
+		return x.Close()
+	})
 }
@@ -0,0 +1,45 @@
+// Package arena hands out records and byte slices carved from large
+// shared backing arrays, trading per-record allocations for a few big
+// ones when decoding millions of records.
+package arena
+
+// Arena allocates values of type T and byte slices.  Slices are capped
+// at their length, so appending to one never clobbers its neighbours.
+// Anything allocated keeps its whole backing array alive.
+type Arena[T any] struct {
+	items []T    // Unused part of the current item slab
+	data  []byte // Unused part of the current byte slab
+	chunk int    // Size of byte slabs
+}
+
+// itemsPerSlab is the number of values allocated at once
+const itemsPerSlab = 1024
+
+// New creates an arena allocating bytes chunk at a time
+func New[T any](chunk int) *Arena[T] {
+	return &Arena[T]{chunk: chunk}
+}
+
+// Item returns a pointer to a new zero value of type T
+func (a *Arena[T]) Item() *T {
+	if len(a.items) == 0 {
+		a.items = make([]T, itemsPerSlab)
+	}
+	p := &a.items[0]
+	a.items = a.items[1:]
+	return p
+}
+
+// Bytes returns a new slice of n bytes.  Requests larger than the chunk
+// size get a backing array of their own.
+func (a *Arena[T]) Bytes(n int) []byte {
+	if n > len(a.data) {
+		if n > a.chunk {
+			return make([]byte, n)
+		}
+		a.data = make([]byte, a.chunk)
+	}
+	b := a.data[:n:n]
+	a.data = a.data[n:]
+	return b
+}
@@ -0,0 +1,46 @@
+// Package atomicfile writes files so that readers only ever see the old
+// content or the complete new content, never a truncated file left
+// behind by an interrupted write.
+package atomicfile
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile creates or replaces the file name with the output of write.
+// The output goes to a temporary file in the same directory, which is
+// synced and renamed over name only if write succeeds; on failure it is
+// removed and name is left untouched.  perm applies to the new file.
+func WriteFile(name string, perm os.FileMode, write func(io.Writer) error) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	if err = write(w); err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -0,0 +1,44 @@
+package atomicfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	fmt.Println("TestWriteFile()")
+
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "fw.hex")
+
+	if err := WriteFile(fn, 0644, func(w io.Writer) error {
+		_, err := io.WriteString(w, "good")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed write must leave the previous content and no temporary
+	// file behind
+	err := WriteFile(fn, 0644, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errors.New("interrupted")
+	})
+	if err == nil {
+		fmt.Println("error not reported")
+		t.Fail()
+	}
+
+	if b, _ := os.ReadFile(fn); string(b) != "good" {
+		fmt.Printf("content %q, want %q\n", b, "good")
+		t.Fail()
+	}
+	if ents, _ := os.ReadDir(dir); len(ents) != 1 {
+		fmt.Printf("%d directory entries, want 1\n", len(ents))
+		t.Fail()
+	}
+}
@@ -0,0 +1,100 @@
+// Package coalesce is the format independent engine behind the
+// CoalesceDataRecs functions of the individual hex record packages.  A
+// package describes its record type through an Adapter; the engine
+// merges contiguous runs of data records into so-called "jumbo" records
+// and passes all other records through unchanged.
+package coalesce
+
+import "bytes"
+
+// Options tune how data records are merged.  The zero value merges
+// strictly contiguous records without any size limit.
+type Options struct {
+	// MaxSize splits jumbo records so none carries more than MaxSize
+	// data bytes, which also bounds the memory held while merging a
+	// long run; zero means no limit.
+	MaxSize int
+
+	// GapFill merges runs separated by a gap of at most GapFill bytes,
+	// filling the gap with Fill; zero merges contiguous runs only.
+	GapFill int
+	Fill    byte
+}
+
+// Address is the set of record address types the engine handles
+type Address interface {
+	~uint16 | ~uint32
+}
+
+// Adapter describes a record type R with addresses of type A
+type Adapter[R any, A Address] struct {
+	IsData  func(r R) bool           // Reports whether r is a data record
+	Address func(r R) A              // Address of a data record
+	Data    func(r R) []byte         // Payload of a data record
+	Make    func(addr A, b []byte) R // Builds a jumbo data record
+}
+
+// Records merges the runs of data records in list according to opts.
+// Non-data records terminate the run in progress and are passed through
+// in place.  The data of emitted jumbo records is never shared with the
+// input records.  With MaxSize set, no more than MaxSize bytes are ever
+// accumulated: a jumbo record is split off as soon as it is full.
+func Records[R any, A Address](list []R, ad Adapter[R, A], opts Options) []R {
+	var (
+		out     []R
+		buf     []byte
+		base    A
+		counter A
+		inRun   bool
+	)
+
+	emit := func() {
+		if len(buf) > 0 {
+			out = append(out, ad.Make(base, buf))
+		}
+		buf, inRun = nil, false
+	}
+
+	add := func(b []byte) {
+		for len(b) > 0 {
+			n := len(b)
+			if opts.MaxSize > 0 {
+				if len(buf) == opts.MaxSize {
+					out = append(out, ad.Make(base, buf))
+					base += A(len(buf))
+					buf = nil
+				}
+				if buf == nil {
+					buf = make([]byte, 0, opts.MaxSize)
+				}
+				n = min(n, opts.MaxSize-len(buf))
+			}
+			buf = append(buf, b[:n]...)
+			b = b[n:]
+		}
+	}
+
+	for _, r := range list {
+		if !ad.IsData(r) {
+			emit()
+			out = append(out, r)
+			continue
+		}
+
+		addr, data := ad.Address(r), ad.Data(r)
+		if inRun && addr >= counter && int(addr-counter) <= opts.GapFill {
+			if gap := int(addr - counter); gap > 0 {
+				add(bytes.Repeat([]byte{opts.Fill}, gap))
+			}
+		} else {
+			emit()
+			inRun, base = true, addr
+		}
+
+		add(data)
+		counter = addr + A(len(data))
+	}
+	emit()
+
+	return out
+}
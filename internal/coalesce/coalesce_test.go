@@ -0,0 +1,73 @@
+package coalesce
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type rec struct {
+	addr uint16
+	data []byte // nil marks a non-data record
+}
+
+var adapter = Adapter[rec, uint16]{
+	IsData:  func(r rec) bool { return r.data != nil },
+	Address: func(r rec) uint16 { return r.addr },
+	Data:    func(r rec) []byte { return r.data },
+	Make:    func(addr uint16, b []byte) rec { return rec{addr, b} },
+}
+
+func TestRecords(t *testing.T) {
+	fmt.Println("TestRecords()")
+
+	in := []rec{
+		{0x00, []byte{1, 2}},
+		{0x02, []byte{3}},
+		{0x05, []byte{4}},
+		{0x10, nil},
+		{0x20, []byte{5, 6, 7}},
+	}
+
+	cases := []struct {
+		opts Options
+		want []rec
+	}{
+		{Options{}, []rec{
+			{0x00, []byte{1, 2, 3}}, {0x05, []byte{4}}, {0x10, nil}, {0x20, []byte{5, 6, 7}},
+		}},
+		{Options{GapFill: 2, Fill: 0xFF}, []rec{
+			{0x00, []byte{1, 2, 3, 0xFF, 0xFF, 4}}, {0x10, nil}, {0x20, []byte{5, 6, 7}},
+		}},
+		{Options{MaxSize: 2}, []rec{
+			{0x00, []byte{1, 2}}, {0x02, []byte{3}}, {0x05, []byte{4}}, {0x10, nil},
+			{0x20, []byte{5, 6}}, {0x22, []byte{7}},
+		}},
+	}
+
+	for _, c := range cases {
+		if got := Records(in, adapter, c.opts); !reflect.DeepEqual(got, c.want) {
+			fmt.Printf("%+v: got %v, want %v\n", c.opts, got, c.want)
+			t.Fail()
+		}
+	}
+}
+
+func TestRecordsMaxSize(t *testing.T) {
+	fmt.Println("TestRecordsMaxSize()")
+
+	in := []rec{{0x40, []byte{1, 2, 3, 4, 5}}, {0x45, []byte{6}}}
+	want := []rec{{0x40, []byte{1, 2}}, {0x42, []byte{3, 4}}, {0x44, []byte{5, 6}}}
+
+	got := Records(in, adapter, Options{MaxSize: 2})
+	if !reflect.DeepEqual(got, want) {
+		fmt.Printf("got %v, want %v\n", got, want)
+		t.Fail()
+	}
+	for _, r := range got {
+		if cap(r.data) > 2 {
+			fmt.Printf("record at 0x%X holds %d bytes of buffer\n", r.addr, cap(r.data))
+			t.Fail()
+		}
+	}
+}
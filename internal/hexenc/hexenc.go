@@ -0,0 +1,62 @@
+// Package hexenc converts between binary data and the ASCII hex digits
+// of the record formats without the intermediate allocations of
+// encoding/hex when working on strings.
+package hexenc
+
+import (
+	"encoding/hex"
+)
+
+// fromHex maps an ASCII character to its value, or 0xFF if it is not a
+// hex digit
+var fromHex = func() (t [256]byte) {
+	for i := range t {
+		t[i] = 0xFF
+	}
+	for i, c := range "0123456789abcdef" {
+		t[c] = byte(i)
+	}
+	for i, c := range "ABCDEF" {
+		t[c] = byte(10 + i)
+	}
+	return t
+}()
+
+// DecodeString decodes the hex digits of s into dst, which must hold at
+// least len(s)/2 bytes, and returns the number of bytes written.  Errors
+// are those of encoding/hex.
+func DecodeString(dst []byte, s string) (int, error) {
+	return Decode(dst, s)
+}
+
+// Decode is DecodeString for digits held in either a string or a byte
+// slice, sparing callers holding bytes the conversion
+func Decode[S ~string | ~[]byte](dst []byte, s S) (int, error) {
+	if len(s)%2 != 0 {
+		return 0, hex.ErrLength
+	}
+
+	for i := 0; i < len(s); i += 2 {
+		hi, lo := fromHex[s[i]], fromHex[s[i+1]]
+		if hi == 0xFF {
+			return i / 2, hex.InvalidByteError(s[i])
+		}
+		if lo == 0xFF {
+			return i / 2, hex.InvalidByteError(s[i+1])
+		}
+		dst[i/2] = hi<<4 | lo
+	}
+
+	return len(s) / 2, nil
+}
+
+const upperDigits = "0123456789ABCDEF"
+
+// AppendUpper appends the uppercase hex digits of src to dst and returns
+// the extended buffer, in a single pass without temporary strings
+func AppendUpper(dst, src []byte) []byte {
+	for _, b := range src {
+		dst = append(dst, upperDigits[b>>4], upperDigits[b&0x0F])
+	}
+	return dst
+}
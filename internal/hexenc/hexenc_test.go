@@ -0,0 +1,34 @@
+package hexenc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func TestDecodeString(t *testing.T) {
+	fmt.Println("TestDecodeString()")
+
+	for _, s := range []string{"", "00", "0123456789abcdefABCDEF", "0", "0G", "G0", "zz"} {
+		want, werr := hex.DecodeString(s)
+
+		got := make([]byte, len(s)/2)
+		n, err := DecodeString(got, s)
+
+		if (err == nil) != (werr == nil) || (err == nil && !bytes.Equal(got[:n], want)) {
+			fmt.Printf("%q: got % X %v, want % X %v\n", s, got[:n], err, want, werr)
+			t.Fail()
+		}
+	}
+}
+
+func TestAppendUpper(t *testing.T) {
+	fmt.Println("TestAppendUpper()")
+
+	src := []byte{0x00, 0x1F, 0xA5, 0xFF}
+	if got := string(AppendUpper([]byte(":"), src)); got != ":001FA5FF" {
+		fmt.Printf("got %q\n", got)
+		t.Fail()
+	}
+}
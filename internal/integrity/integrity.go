@@ -0,0 +1,110 @@
+// Package integrity implements the integrity trailer records shared by
+// the Intel Hex and S-Record readers and writers: a record following the
+// data whose content carries a digest of every record preceding it.  The
+// content reads e.g. "GoHexIO CRC32:1a2b3c4d".
+package integrity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+)
+
+// prefix opens the content of every trailer, telling trailers apart from
+// other records of the same type
+const prefix = "GoHexIO "
+
+// ErrIntegrity reports a missing, malformed or mismatching trailer
+var ErrIntegrity = errors.New("integrity check failed")
+
+// Sum digests the records of a file as they are written
+type Sum struct {
+	d checksum.Digest
+	h hash.Hash
+}
+
+// NewSum returns a Sum computing d, or nil if d is unknown
+func NewSum(d checksum.Digest) *Sum {
+	if h := d.New(); h != nil {
+		return &Sum{d: d, h: h}
+	}
+	return nil
+}
+
+// Add feeds one record, given by its type, address field and data, to
+// the digest
+func (s *Sum) Add(typ byte, addr uint32, data []byte) {
+	add(s.h, typ, addr, data)
+}
+
+// Trailer returns the content of the trailer record for the records
+// added so far
+func (s *Sum) Trailer() []byte {
+	return fmt.Appendf(nil, "%s%s:%x", prefix, s.d, s.h.Sum(nil))
+}
+
+func add(h hash.Hash, typ byte, addr uint32, data []byte) {
+	var b [5]byte
+	b[0] = typ
+	binary.BigEndian.PutUint32(b[1:], addr)
+	h.Write(b[:])
+	h.Write(data)
+}
+
+// IsTrailer reports whether b is the content of a trailer record
+func IsTrailer(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(prefix))
+}
+
+// Verifier digests the records of a file as they are read, with every
+// provided digest since the one the trailer uses is not known up front
+type Verifier struct {
+	hashes map[checksum.Digest]hash.Hash
+}
+
+// Add feeds one record preceding the trailer to the verifier
+func (v *Verifier) Add(typ byte, addr uint32, data []byte) {
+	for _, h := range v.sums() {
+		add(h, typ, addr, data)
+	}
+}
+
+func (v *Verifier) sums() map[checksum.Digest]hash.Hash {
+	if v.hashes == nil {
+		v.hashes = map[checksum.Digest]hash.Hash{}
+		for _, d := range checksum.Digests {
+			v.hashes[d] = d.New()
+		}
+	}
+	return v.hashes
+}
+
+// Check verifies the trailer content b against the records added,
+// failing with ErrIntegrity if they disagree
+func (v *Verifier) Check(b []byte) error {
+	rest, _ := bytes.CutPrefix(b, []byte(prefix))
+	name, value, ok := bytes.Cut(rest, []byte(":"))
+	sum, err := hex.DecodeString(string(value))
+	if !ok || err != nil {
+		return fmt.Errorf("%w: malformed trailer %q", ErrIntegrity, b)
+	}
+
+	h, ok := v.sums()[checksum.Digest(name)]
+	if !ok {
+		return fmt.Errorf("%w: unknown digest %q", ErrIntegrity, name)
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, sum) {
+		return fmt.Errorf("%w: %s %x, the records digest to %x", ErrIntegrity, name, sum, got)
+	}
+	return nil
+}
+
+// Reset clears the records added
+func (v *Verifier) Reset() {
+	v.hashes = nil
+}
@@ -0,0 +1,57 @@
+// Package stats is the format independent part of the Stats methods of
+// the individual hex record packages: it counts records by type and
+// derives the address bounds and largest gap of their data.
+package stats
+
+import "sort"
+
+// Tally accumulates the statistics of a record list with record types
+// of type T
+type Tally[T comparable] struct {
+	Records   int       // Total number of records
+	ByType    map[T]int // Record counts keyed by record type
+	DataBytes int       // Total number of data bytes
+
+	spans []span
+}
+
+// span is a run of data addresses; end is exclusive
+type span struct{ start, end uint64 }
+
+// Record counts a record of type t
+func (s *Tally[T]) Record(t T) {
+	if s.ByType == nil {
+		s.ByType = make(map[T]int)
+	}
+	s.Records++
+	s.ByType[t]++
+}
+
+// Data counts n data bytes at absolute address addr; n 0 is ignored
+func (s *Tally[T]) Data(addr uint32, n int) {
+	if n == 0 {
+		return
+	}
+	s.spans = append(s.spans, span{uint64(addr), uint64(addr) + uint64(n)})
+	s.DataBytes += n
+}
+
+// Bounds returns the lowest and highest, inclusive, data address and the
+// largest run of unused addresses between data, all 0 without data
+func (s *Tally[T]) Bounds() (lo, hi, gap uint32) {
+	if len(s.spans) == 0 {
+		return 0, 0, 0
+	}
+	sort.Slice(s.spans, func(i, j int) bool { return s.spans[i].start < s.spans[j].start })
+
+	end := s.spans[0].end
+	for _, sp := range s.spans[1:] {
+		if sp.start > end && sp.start-end > uint64(gap) {
+			gap = uint32(sp.start - end)
+		}
+		if sp.end > end {
+			end = sp.end
+		}
+	}
+	return uint32(s.spans[0].start), uint32(end - 1), gap
+}
@@ -0,0 +1,32 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTally(t *testing.T) {
+	fmt.Println("TestTally()")
+
+	var s Tally[string]
+	if lo, hi, gap := s.Bounds(); lo != 0 || hi != 0 || gap != 0 {
+		t.Errorf("empty bounds %X %X %X", lo, hi, gap)
+	}
+
+	s.Record("data")
+	s.Data(0x100, 0x10)
+	s.Record("ela")
+	s.Record("data")
+	s.Data(0x20, 0x10)
+	s.Record("data")
+	s.Data(0x108, 0x10) // Overlaps the first
+	s.Record("data")
+	s.Data(0x200, 0)
+
+	if s.Records != 5 || s.ByType["data"] != 4 || s.ByType["ela"] != 1 || s.DataBytes != 0x30 {
+		t.Errorf("counts %+v", s)
+	}
+	if lo, hi, gap := s.Bounds(); lo != 0x20 || hi != 0x117 || gap != 0xD0 {
+		t.Errorf("bounds %X %X %X, want 20 117 D0", lo, hi, gap)
+	}
+}
@@ -0,0 +1,52 @@
+package plainhex
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestLoopback(t *testing.T) {
+	fmt.Println("TestLoopback()")
+
+	m := image.New()
+	m.Write(0x10, []byte("plain hex text format, more than one line"))
+	m.Write(0x1000, []byte{0xDE, 0xAD})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m); err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	m2, err := Decode(&buf)
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	if !reflect.DeepEqual(m.Segments(), m2.Segments()) {
+		fmt.Println("failure: images differ")
+		t.Fail()
+	}
+}
+
+func TestDecodeTokens(t *testing.T) {
+	fmt.Println("TestDecodeTokens()")
+
+	m, err := Decode(strings.NewReader("# comment\n01 02\nDEADBEEF @20 ff\n"))
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	if !bytes.Equal(m.Extract(0, 6, 0), []byte{1, 2, 0xDE, 0xAD, 0xBE, 0xEF}) ||
+		!bytes.Equal(m.Extract(0x20, 1, 0), []byte{0xFF}) {
+		fmt.Printf("failure: bad decode %v\n", m.Segments())
+		t.Fail()
+	}
+}
@@ -0,0 +1,63 @@
+// Package plainhex reads and writes the trivial "plain hex" text format
+// consumed by many ROM tools and simulators: whitespace separated hex
+// bytes, with optional @address markers setting the load address of the
+// bytes that follow.
+//
+//	@00000100
+//	48 65 6C 6C 6F 2C 20 77 6F 72 6C 64
+//
+// Lines starting with '#' or "//" are treated as comments.
+package plainhex
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Decode reads plain hex text from r into a memory image.  Tokens of
+// more than two digits are taken as a run of bytes, most significant
+// first.  Data starts at address 0 unless an @address marker says
+// otherwise.
+func Decode(r io.Reader) (*image.Image, error) {
+	var (
+		m    = image.New()
+		addr uint32
+	)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		for _, tok := range strings.Fields(line) {
+			if strings.HasPrefix(tok, "@") {
+				a, err := strconv.ParseUint(tok[1:], 16, 32)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: Address marker error: %s", n, err)
+				}
+				addr = uint32(a)
+				continue
+			}
+
+			b, err := hex.DecodeString(tok)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: Data chars bad: %s", n, err)
+			}
+			m.Write(addr, b)
+			addr += uint32(len(b))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
@@ -0,0 +1,125 @@
+package plainhex
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Writer implements a plain hex text writer
+type Writer struct {
+	w       *bufio.Writer // Buffered text output
+	width   int           // Bytes per line
+	col     int           // Bytes written to the current line
+	addr    uint32        // Address of the next byte
+	newAddr bool          // An @address marker is due before the next byte
+	fin     bool          // Close() has been called
+}
+
+// NewWriterWidth creates a new plain hex writer with a specific number of
+// bytes per line
+func NewWriterWidth(w io.Writer, width int) *Writer {
+	return &Writer{w: bufio.NewWriter(w), width: width}
+}
+
+// NewWriter creates a new plain hex writer with 16 bytes per line
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterWidth(w, 16)
+}
+
+// SetAddress causes an @address marker to precede the next byte written
+func (x *Writer) SetAddress(a uint32) {
+	x.addr = a
+	x.newAddr = true
+}
+
+// Write is the idiomatic Go Write() method
+func (x *Writer) Write(p []byte) (int, error) {
+	if x.fin {
+		return 0, errors.New("Writer closed")
+	}
+
+	for i, b := range p {
+		if x.newAddr {
+			if err := x.endLine(); err != nil {
+				return i, err
+			}
+			if _, err := fmt.Fprintf(x.w, "@%08X\n", x.addr); err != nil {
+				return i, err
+			}
+			x.newAddr = false
+		}
+
+		sep := " "
+		if x.col == 0 {
+			sep = ""
+		}
+		if _, err := fmt.Fprintf(x.w, "%s%02X", sep, b); err != nil {
+			return i, err
+		}
+
+		x.addr++
+		if x.col++; x.col == x.width {
+			if err := x.endLine(); err != nil {
+				return i + 1, err
+			}
+		}
+	}
+
+	return len(p), nil
+}
+
+// Terminate a partially filled line
+func (x *Writer) endLine() error {
+	if x.col == 0 {
+		return nil
+	}
+	x.col = 0
+	return x.w.WriteByte('\n')
+}
+
+// Close terminates the last line and flushes buffered output.
+// Note: the underlying io.Writer is NOT closed
+func (x *Writer) Close() error {
+	if x.fin {
+		return errors.New("Writer already closed")
+	}
+	x.fin = true
+
+	if err := x.endLine(); err != nil {
+		return err
+	}
+	return x.w.Flush()
+}
+
+// Encode writes the memory image m to w as plain hex text, each segment
+// preceded by an @address marker
+func Encode(w io.Writer, m *image.Image) error {
+	return encode(w, m, 0)
+}
+
+func init() {
+	image.RegisterEncoder(image.PlainHex, func(w io.Writer, m *image.Image, o image.EncodeOptions) error {
+		return encode(w, m, o.Width)
+	})
+}
+
+// encode is Encode with width bytes per data record, 0 for the default
+func encode(w io.Writer, m *image.Image, width int) error {
+	x := NewWriter(w)
+	if width > 0 {
+		x = NewWriterWidth(w, width)
+	}
+
+	for _, s := range m.Segments() {
+		x.SetAddress(s.Address)
+		if _, err := x.Write(s.Data); err != nil {
+			return err
+		}
+	}
+
+	return x.Close()
+}
@@ -0,0 +1,12 @@
+package signetics
+
+// Signetics checksum algorithm: exclusive-or each byte into the running
+// checksum, then rotate the checksum left by one bit.
+func calcChecksum(b []byte) byte {
+	var cs byte
+	for _, v := range b {
+		cs ^= v
+		cs = cs<<1 | cs>>7
+	}
+	return cs
+}
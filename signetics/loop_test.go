@@ -0,0 +1,51 @@
+package signetics
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestLoopback(t *testing.T) {
+	fmt.Println("TestLoopback()")
+
+	m := image.New()
+	m.Write(0x0100, []byte("Signetics absolute object format"))
+	m.Write(0x8000, []byte{0xDE, 0xAD, 0xBE, 0xEF})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m); err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	m2, err := Decode(&buf)
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	if !reflect.DeepEqual(m.Segments(), m2.Segments()) {
+		fmt.Println("failure: images differ")
+		t.Fail()
+	}
+}
+
+func TestBadChecksum(t *testing.T) {
+	fmt.Println("TestBadChecksum()")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write([]byte{1, 2, 3})
+	w.Close()
+
+	rec := []byte(buf.String())
+	rec[9] ^= 1 // first data digit
+	if _, err := ReadAll(bytes.NewReader(rec)); err == nil {
+		fmt.Println("corrupt record accepted")
+		t.Fail()
+	}
+}
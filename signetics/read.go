@@ -0,0 +1,132 @@
+// Package signetics reads and writes the Signetics absolute object
+// format.  Each record is laid out as
+//
+//	:AAAACCSSDD...DDSS
+//
+// where AAAA is the 16-bit load address, CC the data byte count, the
+// first SS a checksum over the address and count bytes, DD the data and
+// the final SS a checksum over the data.  A record with a zero byte count
+// (and hence no data checksum) terminates the file.
+package signetics
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// HexRec is a decoded Signetics record.  A record without data is the
+// end of file record.
+type HexRec struct {
+	Address uint16
+	Data    []byte
+}
+
+// String is the idiomatic Go string-ize method
+func (r HexRec) String() string {
+	return fmt.Sprintf("Address: 0x%04X, Length: %d, content: %v",
+		r.Address, len(r.Data), r.Data)
+}
+
+func decodeRecord(s string) (*HexRec, error) {
+	if len(s) < 9 || s[0] != ':' {
+		return nil, errors.New("Malformed Signetics record")
+	}
+
+	// Convert the Hex-ASCII representation to binary
+	b, err := hex.DecodeString(s[1:])
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decode hex record: %s", err)
+	}
+
+	// Address, count and address checksum
+	hdr, b := b[:3], b[3:]
+	if calcChecksum(hdr) != b[0] {
+		return nil, errors.New("Bad address checksum detected")
+	}
+	b = b[1:]
+
+	hr := &HexRec{Address: uint16(hdr[0])<<8 | uint16(hdr[1])}
+	count := int(hdr[2])
+
+	// The end of file record carries no data and no data checksum
+	if count == 0 {
+		if len(b) != 0 {
+			return nil, errors.New("Unexpected data in end of file record")
+		}
+		return hr, nil
+	}
+
+	if len(b) != count+1 {
+		return nil, errors.New("byte-count error")
+	}
+
+	// Pop the data checksum byte off the end
+	checksum, data := b[count], b[:count]
+	if checksum != calcChecksum(data) {
+		return nil, errors.New("Bad data checksum detected")
+	}
+
+	hr.Data = data
+	return hr, nil
+}
+
+// ReadAll reads Signetics records from r up to and including the end of
+// file record and returns them in file order.
+func ReadAll(r io.Reader) ([]*HexRec, error) {
+	var hrecs []*HexRec
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		rec := scanner.Text()
+		if len(rec) == 0 {
+			continue
+		}
+
+		hr, err := decodeRecord(rec)
+		if err != nil {
+			return nil, err
+		}
+		hrecs = append(hrecs, hr)
+
+		if len(hr.Data) == 0 { // end of file record
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return hrecs, nil
+}
+
+// ReadFile reads the Signetics file named fn
+func ReadFile(fn string) ([]*HexRec, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadAll(f)
+}
+
+// Decode reads Signetics records from r into a memory image
+func Decode(r io.Reader) (*image.Image, error) {
+	recs, err := ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := image.New()
+	for _, rec := range recs {
+		m.Write(uint32(rec.Address), rec.Data)
+	}
+
+	return m, nil
+}
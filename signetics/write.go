@@ -0,0 +1,145 @@
+package signetics
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Writer implements a Signetics absolute object format writer
+type Writer struct {
+	w     io.Writer    // Underlying writer object
+	width int          // Standard length for data records
+	addr  uint16       // Address counter for data records
+	start uint16       // Address placed in the end of file record
+	fin   bool         // Close() has been called
+	fifo  bytes.Buffer // FIFO for writes
+}
+
+// NewWriterWidth creates a new Signetics writer with a specific data record length
+func NewWriterWidth(w io.Writer, width int) *Writer {
+	return &Writer{w: w, width: width}
+}
+
+// NewWriter creates a new Signetics writer with a default length
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterWidth(w, 16)
+}
+
+// SetAddress flushes any buffered data and sets the address of the next
+// data record
+func (x *Writer) SetAddress(a uint16) error {
+	if err := x.Flush(); err != nil {
+		return err
+	}
+	x.addr = a
+	return nil
+}
+
+// SetStartAddress sets the address carried by the end of file record
+func (x *Writer) SetStartAddress(a uint16) {
+	x.start = a
+}
+
+// Generic emit-record
+func (x *Writer) emitRecord(addr uint16, p []byte) error {
+	var buf bytes.Buffer
+
+	hdr := []byte{byte(addr >> 8), byte(addr), byte(len(p))}
+	buf.Write(hdr)
+	buf.WriteByte(calcChecksum(hdr))
+	if len(p) > 0 {
+		buf.Write(p)
+		buf.WriteByte(calcChecksum(p))
+	}
+
+	s := strings.ToUpper(hex.EncodeToString(buf.Bytes()))
+	_, err := fmt.Fprintf(x.w, ":%s\n", s)
+	return err
+}
+
+// Write is the idiomatic Go Write() method.  Residual data shorter than
+// the record width is held until a follow-up Write(), Flush() or Close().
+func (x *Writer) Write(p []byte) (int, error) {
+	if x.fin {
+		return 0, errors.New("Writer closed")
+	}
+
+	x.fifo.Write(p)
+
+	for x.fifo.Len() >= x.width {
+		chunk := x.fifo.Next(x.width)
+		if err := x.emitRecord(x.addr, chunk); err != nil {
+			return 0, err
+		}
+		x.addr += uint16(len(chunk))
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any data remaining in the FIFO as a runt record
+func (x *Writer) Flush() error {
+	if x.fifo.Len() > 0 {
+		chunk := x.fifo.Next(x.fifo.Len())
+		if err := x.emitRecord(x.addr, chunk); err != nil {
+			return err
+		}
+		x.addr += uint16(len(chunk))
+	}
+	return nil
+}
+
+// Close flushes buffered data and writes the end of file record.
+// Note: the underlying io.Writer is NOT closed
+func (x *Writer) Close() error {
+	if x.fin {
+		return errors.New("Writer already closed")
+	}
+	x.fin = true
+
+	if err := x.Flush(); err != nil {
+		return err
+	}
+
+	return x.emitRecord(x.start, nil)
+}
+
+// Encode writes the memory image m to w in Signetics format.  The format
+// only has 16-bit addresses, so every segment must lie below 64K.
+func Encode(w io.Writer, m *image.Image) error {
+	return encode(w, m, 0)
+}
+
+func init() {
+	image.RegisterEncoder(image.Signetics, func(w io.Writer, m *image.Image, o image.EncodeOptions) error {
+		return encode(w, m, o.Width)
+	})
+}
+
+// encode is Encode with width bytes per data record, 0 for the default
+func encode(w io.Writer, m *image.Image, width int) error {
+	x := NewWriter(w)
+	if width > 0 {
+		x = NewWriterWidth(w, width)
+	}
+
+	for _, s := range m.Segments() {
+		if s.End() > 0x10000 {
+			return fmt.Errorf("segment at 0x%X exceeds the 16-bit address space", s.Address)
+		}
+		if err := x.SetAddress(uint16(s.Address)); err != nil {
+			return err
+		}
+		if _, err := x.Write(s.Data); err != nil {
+			return err
+		}
+	}
+
+	return x.Close()
+}
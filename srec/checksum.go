@@ -2,24 +2,22 @@ package srec
 
 import (
 	"encoding/hex"
+
+	"github.com/peteArnt/GoHexIO/checksum"
 )
 
 // Awkward name, but essentially we're taking an ASCII Hexadecimal string
 // (two chars per byte) and calculating the checksum of the binary
 // equivelant.
 func calcChecksumHexASCII(s string) (byte, error) {
+	return sumHexASCII(s, checksum.OnesComplement)
+}
+
+// As above, using an arbitrary checksum algorithm
+func sumHexASCII(s string, sum checksum.Algorithm) (byte, error) {
 	b, err := hex.DecodeString(s)
 	if err != nil {
 		return 0, err
 	}
-	return calcChecksum(b), nil
-}
-
-// S-Record checksum algorithm
-func calcChecksum(b []byte) byte {
-	var cs byte
-	for _, v := range b {
-		cs += v
-	}
-	return ^cs
+	return sum.Sum(b), nil
 }
@@ -0,0 +1,333 @@
+package srec
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+	"github.com/peteArnt/GoHexIO/internal/arena"
+	"github.com/peteArnt/GoHexIO/internal/integrity"
+)
+
+// Errors reported by a Decoder
+var (
+	ErrAfterEnd = errors.New("record after the start record") // See SetRejectTrailing
+	ErrOrder    = errors.New("records out of order")          // See SetStrictOrder
+
+	ErrIntegrity = integrity.ErrIntegrity // See SetVerifyIntegrity
+)
+
+// Decoder reads and decodes S-Records from an input stream
+type Decoder struct {
+	s    *bufio.Scanner     // Line splitter over the input
+	line int                // Line number of the most recent record
+	off  int64              // Input offset of that line
+	next int64              // Input offset of the line after it
+	eof  bool               // The input is exhausted
+	sum  checksum.Algorithm // Record checksum algorithm
+	buf  []byte             // Initial line buffer, kept across Reset
+	log  *slog.Logger       // Diagnostics sink, nil for none
+
+	ended    bool // The terminating record has been decoded
+	trailing int  // Records decoded after it
+	strict   bool // Records after it are an error
+
+	order   bool           // Enforce the canonical record order
+	records int            // Records decoded
+	lines   map[string]int // Line of the first record of each kind checked
+
+	empty EmptyPolicy // Handling of zero-length data records
+
+	verify  bool               // Verify the integrity trailer
+	digests integrity.Verifier // Digests of the records before it
+	sealed  int                // Line of the integrity trailer, 0 if none yet
+
+	arena *arena.Arena[HexRec] // Shared record storage, nil for none
+}
+
+// DecoderOption configures a Decoder created by NewDecoder, or the one
+// used by the package level read functions
+type DecoderOption func(*Decoder)
+
+// WithDecoderLogger routes the decoder's diagnostics to l; see
+// Decoder.SetLogger
+func WithDecoderLogger(l *slog.Logger) DecoderOption {
+	return func(d *Decoder) { d.SetLogger(l) }
+}
+
+// NewDecoder creates a new Decoder reading from r, configured by opts
+func NewDecoder(r io.Reader, opts ...DecoderOption) *Decoder {
+	d := &Decoder{buf: make([]byte, 4096), sum: checksum.OnesComplement}
+	for _, o := range opts {
+		o(d)
+	}
+	d.Reset(r)
+	return d
+}
+
+// Reset makes the decoder read from r, starting over at line 1 while
+// keeping its configuration and its line buffer and arena, so services
+// converting many files need not reallocate them for each one
+func (d *Decoder) Reset(r io.Reader) {
+	d.s = bufio.NewScanner(r)
+	d.s.Buffer(d.buf, bufio.MaxScanTokenSize)
+	d.s.Split(d.scanLines)
+	d.line = 0
+	d.off, d.next = 0, 0
+	d.eof = false
+	d.ended = false
+	d.trailing = 0
+	d.records = 0
+	d.lines = nil
+	d.digests.Reset()
+	d.sealed = 0
+}
+
+// SetRejectTrailing makes Decode fail with ErrAfterEnd on records
+// following the terminating start record.  By default they are decoded
+// like any other, but counted by Trailing and logged as a warning, since
+// such content usually means a corrupted or wrongly concatenated file.
+func (d *Decoder) SetRejectTrailing(on bool) {
+	d.strict = on
+}
+
+// SetStrictOrder makes Decode enforce the canonical record order: at
+// most one header record, which comes first, then the data records, at
+// most one count record and finally a single start record.  The first
+// violation fails with ErrOrder, as does input ending without a start
+// record.
+func (d *Decoder) SetStrictOrder(on bool) {
+	d.order = on
+}
+
+// SetEmptyData selects how zero-length data records are handled:
+// passed through, the default, skipped like blank lines or rejected with
+// ErrEmptyData
+func (d *Decoder) SetEmptyData(p EmptyPolicy) {
+	d.empty = p
+}
+
+// SetVerifyIntegrity makes Decode verify the integrity trailer written
+// by Writer.SetIntegrity against the records before it.  Input without
+// a trailer, a mismatching trailer and records other than count and
+// start records after it fail with ErrIntegrity.
+func (d *Decoder) SetVerifyIntegrity(on bool) {
+	d.verify = on
+}
+
+// Trailing returns the number of records decoded after the terminating
+// start record
+func (d *Decoder) Trailing() int {
+	return d.trailing
+}
+
+// SetChecksum selects the checksum algorithm records are verified
+// against, for files from tools that deviate from the Motorola standard
+func (d *Decoder) SetChecksum(a checksum.Algorithm) {
+	d.sum = a
+}
+
+// SetArena switches the decoder to arena storage: records and their data
+// are carved from shared backing arrays of chunk bytes instead of being
+// allocated one by one, which cuts allocations and GC work dramatically
+// when reading millions of records.  The price is that a single record
+// kept around keeps its whole backing array alive.  chunk 0 restores
+// per-record allocation.
+func (d *Decoder) SetArena(chunk int) {
+	d.arena = nil
+	if chunk > 0 {
+		d.arena = arena.New[HexRec](chunk)
+	}
+}
+
+// Line returns the line number of the record most recently decoded
+func (d *Decoder) Line() int {
+	return d.line
+}
+
+// Offset returns the byte offset within the input of the line of the
+// record most recently decoded
+func (d *Decoder) Offset() int64 {
+	return d.off
+}
+
+// scanLines is bufio.ScanLines tracking the input offset of each line
+func (d *Decoder) scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	adv, tok, err := bufio.ScanLines(data, atEOF)
+	if tok != nil {
+		d.off = d.next
+	}
+	d.next += int64(adv)
+	return adv, tok, err
+}
+
+// Decode returns the next record from the input stream, skipping blank
+// lines.  At the end of the input it returns io.EOF.
+func (d *Decoder) Decode() (*HexRec, error) {
+	for d.s.Scan() {
+		d.line++
+		if rec := d.s.Bytes(); len(rec) > 0 {
+			hr, err := decodeRecordIn(rec, d.sum, d.arena)
+			if err != nil && d.s.Err() != nil {
+				// The record was cut short by a read error; report
+				// that instead
+				return nil, d.s.Err()
+			}
+			if err != nil {
+				return nil, err
+			}
+			if hr.RecordType.IsData() && len(hr.Data) == 0 && d.empty != EmptyPreserve {
+				if d.empty == EmptyReject {
+					return nil, ErrEmptyData
+				}
+				continue
+			}
+			d.records++
+			if d.order {
+				if err := d.checkOrder(hr); err != nil {
+					return nil, err
+				}
+			}
+			if d.verify {
+				if err := d.checkIntegrity(hr); err != nil {
+					return nil, err
+				}
+			}
+			return hr, d.checkEnd(hr)
+		}
+	}
+	d.eof = true
+	if err := d.s.Err(); err != nil {
+		return nil, err
+	}
+	if d.order && !d.ended {
+		return nil, fmt.Errorf("%w: no start record at the end of the input", ErrOrder)
+	}
+	if d.verify && d.sealed == 0 {
+		return nil, fmt.Errorf("%w: no integrity trailer", ErrIntegrity)
+	}
+	return nil, io.EOF
+}
+
+// checkIntegrity digests hr, or verifies it if it is the integrity
+// trailer
+func (d *Decoder) checkIntegrity(hr *HexRec) error {
+	t := hr.RecordType
+	switch {
+	case d.sealed > 0 && (t == S5Count || t == S6Count || t == S7Start || t == S8Start || t == S9Start):
+		return nil
+	case d.sealed > 0:
+		return fmt.Errorf("%w: %s record after the trailer on line %d", ErrIntegrity, t, d.sealed)
+	case t == S0Header && integrity.IsTrailer(hr.Data):
+		d.sealed = d.line
+		return d.digests.Check(hr.Data)
+	}
+	d.digests.Add(byte(t), hr.Address, hr.Data)
+	return nil
+}
+
+// checkOrder enforces the canonical record order on hr
+func (d *Decoder) checkOrder(hr *HexRec) error {
+	var kind string
+	switch t := hr.RecordType; {
+	case t == S0Header && integrity.IsTrailer(hr.Data):
+		kind = "integrity trailer"
+	case t == S0Header:
+		kind = "header"
+	case t.IsData():
+		kind = "data"
+	case t == S5Count || t == S6Count:
+		kind = "count"
+	case t == S7Start || t == S8Start || t == S9Start:
+		kind = "start"
+	default:
+		return nil
+	}
+
+	switch {
+	case d.lines["start"] > 0:
+		return d.orderError("%s record on line %d after the start record on line %d", kind, d.line, d.lines["start"])
+	case kind == "data" && d.lines["count"] > 0:
+		return d.orderError("data record on line %d after the count record on line %d", d.line, d.lines["count"])
+	case kind != "data" && d.lines[kind] > 0:
+		return d.orderError("second %s record on line %d, the first on line %d", kind, d.line, d.lines[kind])
+	case kind == "header" && d.records > 1:
+		return d.orderError("header record on line %d not first", d.line)
+	}
+	if d.lines[kind] == 0 {
+		if d.lines == nil {
+			d.lines = map[string]int{}
+		}
+		d.lines[kind] = d.line
+	}
+	return nil
+}
+
+func (d *Decoder) orderError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrOrder, fmt.Sprintf(format, args...))
+}
+
+// checkEnd tracks the terminating record, reporting hr if it follows
+func (d *Decoder) checkEnd(hr *HexRec) error {
+	if d.ended {
+		d.trailing++
+		if d.strict {
+			return fmt.Errorf("%w: %s record", ErrAfterEnd, hr.RecordType)
+		}
+		d.logger().Warn("srec: record after the start record", "line", d.line, "type", hr.RecordType)
+	}
+	if hr.RecordType == S7Start || hr.RecordType == S8Start || hr.RecordType == S9Start {
+		d.ended = true
+	}
+	return nil
+}
+
+// Records returns an iterator over the records remaining in the input
+// stream.  Iteration stops after the first error, which is yielded with
+// a nil record; reaching the end of the input is not an error.
+func (d *Decoder) Records() iter.Seq2[*HexRec, error] {
+	return func(yield func(*HexRec, error) bool) {
+		for {
+			hr, err := d.Decode()
+			if err == io.EOF {
+				return
+			}
+			if !yield(hr, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// DecodeAll decodes records until the end of the input stream
+func (d *Decoder) DecodeAll() ([]*HexRec, error) {
+	return d.decodeAll(context.Background())
+}
+
+func (d *Decoder) decodeAll(ctx context.Context) ([]*HexRec, error) {
+	var hrecs []*HexRec
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		hr, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			d.logger().Debug("srec: bad record", "line", d.line, "err", err)
+			return nil, err
+		}
+		hrecs = append(hrecs, hr)
+	}
+
+	d.logger().Debug("srec: records decoded", "count", len(hrecs))
+	return hrecs, nil
+}
@@ -0,0 +1,27 @@
+package srec
+
+import "bytes"
+
+// Tally accumulates the records and bytes a dry-run writer would have
+// produced.
+type Tally struct {
+	Records int   // Records emitted, terminating records included
+	Bytes   int64 // Bytes of S-Record text, line endings included
+}
+
+// Write counts the records in p and discards it.  It never fails.
+func (t *Tally) Write(p []byte) (int, error) {
+	t.Records += bytes.Count(p, []byte{'\n'})
+	t.Bytes += int64(len(p))
+	return len(p), nil
+}
+
+// NewDryRunWriter returns a writer that performs all the validation,
+// address arithmetic and record formatting of a regular Writer but
+// writes nothing, tallying the would-be output in the returned Tally
+// instead.  It is useful for pre-validating a generation run and for
+// sizing progress bars.
+func NewDryRunWriter(aMode AddrMode) (*Writer, *Tally) {
+	t := new(Tally)
+	return NewWriter(t, aMode), t
+}
@@ -0,0 +1,78 @@
+package srec
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Pretty renders the record for human consumption: the address, record
+// type and length, then the data 16 bytes per line in hex with an ASCII
+// gutter.
+func (r HexRec) Pretty() string {
+	var sb strings.Builder
+
+	header := srecStrMap[r.RecordType]
+	if header == "" {
+		header = "S?"
+	}
+
+	fmt.Fprintf(&sb, "%08X  %-2s %3d", r.Address, header, len(r.Data))
+	for off := 0; off < len(r.Data); off += 16 {
+		if off > 0 {
+			fmt.Fprintf(&sb, "\n%08X  %-2s %3s", r.Address+uint32(off), "", "")
+		}
+		fmt.Fprintf(&sb, "  %s", image.HexASCII(r.Data[off:min(off+16, len(r.Data))]))
+	}
+
+	return sb.String()
+}
+
+// Dump writes the Pretty rendering of each of recs to w
+func Dump(w io.Writer, recs []*HexRec) error {
+	dd := NewDumper(w)
+	for _, r := range recs {
+		if err := dd.Dump(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dumper renders records one at a time as Dump does, so a dump can be
+// written as the records arrive, e.g. from a file still being written
+type Dumper struct {
+	w     io.Writer
+	page  int // Output lines between column headings, 0 for none
+	lines int // Output lines since the last headings
+}
+
+// NewDumper creates a Dumper writing to w
+func NewDumper(w io.Writer) *Dumper {
+	return &Dumper{w: w}
+}
+
+// SetPage makes the dumper write column headings before the first
+// record and again whenever n more lines have been written, so long
+// dumps remain readable in a terminal.  0, the default, writes none.
+func (d *Dumper) SetPage(n int) {
+	d.page = n
+	d.lines = n
+}
+
+// Dump writes the Pretty rendering of r
+func (d *Dumper) Dump(r *HexRec) error {
+	if d.page > 0 && d.lines >= d.page {
+		if _, err := fmt.Fprintf(d.w, "%-8s  %-2s %3s  %s\n", "Address", "Ty", "Len", "Data"); err != nil {
+			return err
+		}
+		d.lines = 0
+	}
+
+	s := r.Pretty()
+	d.lines += strings.Count(s, "\n") + 1
+	_, err := fmt.Fprintln(d.w, s)
+	return err
+}
@@ -0,0 +1,123 @@
+package srec
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+)
+
+// Explain breaks a single S-Record into labeled fields: start code, record
+// type, byte count, address, data, checksum and the checksum computed
+// from the other fields.  Malformed fields are flagged rather than
+// rejected, making it a teaching and debugging aid for investigating bad
+// records.
+func Explain(line string) string {
+	var sb strings.Builder
+	line = strings.TrimSpace(line)
+
+	field := func(label, value, note string) {
+		if note != "" {
+			value += " (" + note + ")"
+		}
+		fmt.Fprintf(&sb, "%-18s %s\n", label+":", value)
+	}
+
+	if line == "" {
+		field("Start code", "missing", "empty record")
+		return sb.String()
+	}
+
+	if line[0] != 'S' {
+		field("Start code", fmt.Sprintf("%q", line[0]), "expected 'S'")
+	} else {
+		field("Start code", "'S'", "")
+	}
+
+	if len(line) < 2 {
+		field("Record type", "missing", "")
+		return sb.String()
+	}
+
+	recTyp, ok := srecTypeMap["S"+line[1:2]]
+	if !ok {
+		field("Record type", line[1:2], "unknown record type")
+		return sb.String()
+	}
+	addrLen := addrSize(recTyp)
+	field("Record type", fmt.Sprintf("S%s (%s)", line[1:2], recTypeName[recTyp]), "")
+
+	body := line[2:]
+	if len(body) < 2+2*addrLen+2 {
+		field("Record", body, fmt.Sprintf("too short; at least %d hex digits required", 2+2*addrLen+2))
+		return sb.String()
+	}
+
+	var (
+		countStr = body[0:2]
+		addrStr  = body[2 : 2+2*addrLen]
+		dataStr  = body[2+2*addrLen : len(body)-2]
+		csStr    = body[len(body)-2:]
+	)
+
+	count, err := hex.DecodeString(countStr)
+	if err != nil {
+		field("Byte count", countStr, "invalid hex")
+	} else if int(count[0]) != addrLen+len(dataStr)/2+1 {
+		field("Byte count", fmt.Sprintf("%s (%d)", countStr, count[0]),
+			fmt.Sprintf("record holds %d bytes after the count", addrLen+len(dataStr)/2+1))
+	} else {
+		field("Byte count", fmt.Sprintf("%s (%d)", countStr, count[0]), "")
+	}
+
+	if _, err := hex.DecodeString(addrStr); err != nil {
+		field("Address", addrStr, "invalid hex")
+	} else {
+		field("Address", addrStr, fmt.Sprintf("%d-bit", 8*addrLen))
+	}
+
+	data, err := hex.DecodeString(dataStr)
+	switch {
+	case err != nil:
+		field("Data", dataStr, "invalid hex")
+	case len(data) == 0:
+		field("Data", "none", "")
+	default:
+		field("Data", strings.ToUpper(hex.EncodeToString(data)), "")
+	}
+
+	cs, err := hex.DecodeString(csStr)
+	if err != nil {
+		field("Checksum", csStr, "invalid hex")
+	} else {
+		field("Checksum", strings.ToUpper(csStr), "")
+	}
+
+	bin, err := hex.DecodeString(body[:len(body)-2])
+	if err != nil {
+		field("Computed checksum", "n/a", "record contains invalid hex")
+	} else {
+		calc := checksum.OnesComplement.Sum(bin)
+		note := "ok"
+		if len(cs) == 1 && cs[0] != calc {
+			note = "MISMATCH"
+		}
+		field("Computed checksum", fmt.Sprintf("%02X", calc), note)
+	}
+
+	return sb.String()
+}
+
+// Descriptive names of the record types
+var recTypeName = map[SrecType]string{
+	S0Header: "Header",
+	S1Data:   "Data, 16-bit address",
+	S2Data:   "Data, 24-bit address",
+	S3Data:   "Data, 32-bit address",
+	S5Count:  "Count, 16-bit",
+	S6Count:  "Count, 24-bit",
+	S7Start:  "Start address, 32-bit",
+	S8Start:  "Start address, 24-bit",
+	S9Start:  "Start address, 16-bit",
+}
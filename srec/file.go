@@ -0,0 +1,181 @@
+package srec
+
+import (
+	"io"
+	"iter"
+
+	"github.com/peteArnt/GoHexIO/image"
+	"github.com/peteArnt/GoHexIO/internal/atomicfile"
+	"github.com/peteArnt/GoHexIO/internal/integrity"
+)
+
+// File is an in-memory representation of a complete S-Record file.  Its
+// fields sort the records by purpose, so consumers need not scan them
+// for what they need; Records still yields them in file order.
+type File struct {
+	Data   []image.Segment // Data records merged into contiguous segments
+	Header []byte          // Content of the S0 header record, nil if none
+	Count  *uint32         // Value of the S5/S6 count record, nil if none
+	Start  *uint32         // Address of the S7/S8/S9 start record, nil if none
+	Other  []*HexRec       // Records not reflected above, e.g. repeated headers
+
+	recs []*HexRec // Records in file order
+}
+
+// NewFile wraps a slice of already decoded hex records in a File.  Should
+// the file hold more than one header record the first one is used; of
+// several count or start records the last one wins.  The records passed
+// over, and integrity trailers, end up in Other.
+func NewFile(recs []*HexRec) *File {
+	return NewFileScale(recs, 1)
+}
+
+// NewFileScale is NewFile for files whose addresses count units of scale
+// bytes, as written by a Writer with SetAddressScale.  The addresses of
+// Data are byte addresses; the start address is left as is.
+func NewFileScale(recs []*HexRec, scale uint32) *File {
+	var (
+		f                 = &File{recs: recs}
+		m                 = image.New()
+		hdr, count, start = -1, -1, -1
+	)
+
+	for i, r := range recs {
+		switch {
+		case r.RecordType.IsData():
+			m.Write(r.Address*max(scale, 1), r.Data)
+		case r.RecordType == S0Header && hdr < 0 && !integrity.IsTrailer(r.Data):
+			hdr = i
+		case r.RecordType == S5Count || r.RecordType == S6Count:
+			count = i
+		case r.RecordType == S7Start || r.RecordType == S8Start || r.RecordType == S9Start:
+			start = i
+		}
+	}
+
+	for i, r := range recs {
+		switch {
+		case r.RecordType.IsData():
+		case i == hdr:
+			f.Header = r.Data
+		case i == count:
+			f.Count = &r.Address
+		case i == start:
+			f.Start = &r.Address
+		default:
+			f.Other = append(f.Other, r)
+		}
+	}
+
+	f.Data = m.Segments()
+	return f
+}
+
+// Records returns an iterator over the records of the file in file
+// order.  The error is always nil; the signature matches
+// Decoder.Records so in-memory and streamed records can be consumed
+// alike.
+func (f *File) Records() iter.Seq2[*HexRec, error] {
+	return func(yield func(*HexRec, error) bool) {
+		for _, r := range f.recs {
+			if !yield(r, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Open reads and decodes the S-Record file named fn
+func Open(fn string, opts ...DecoderOption) (*File, error) {
+	recs, err := ReadFile(fn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewFile(recs), nil
+}
+
+// StartAddress returns the execution start address carried by the
+// S7/S8/S9 termination record.  ok is false if the file has none.
+func (f *File) StartAddress() (addr uint32, ok bool) {
+	if f.Start == nil {
+		return 0, false
+	}
+	return *f.Start, true
+}
+
+// RecordCount returns the data record count carried by the S5/S6 count
+// record.  ok is false if the file has none.
+func (f *File) RecordCount() (count uint32, ok bool) {
+	if f.Count == nil {
+		return 0, false
+	}
+	return *f.Count, true
+}
+
+// Image assembles the data of the file into a memory image
+func (f *File) Image() *image.Image {
+	m := image.New()
+	for _, s := range f.Data {
+		m.Write(s.Address, s.Data)
+	}
+	return m
+}
+
+// Segments returns the data payload of the file as contiguous runs of
+// bytes in ascending address order.
+func (f *File) Segments() []image.Segment {
+	return append([]image.Segment(nil), f.Data...)
+}
+
+// Extract renders the data records of recs into a flat buffer of length
+// bytes holding the data from address start on, as a programmer takes
+// it; addresses without data are set to fill, such as 0xFF for flash.
+// Where records overlap the later one wins.
+func Extract(recs []*HexRec, start uint32, length int, fill byte) []byte {
+	return NewFile(recs).Image().Extract(start, length, fill)
+}
+
+// Layout returns the gaps between the data records of recs and the
+// ranges more than one of them provides, as image.Layout does, for
+// checking that linker output covers the expected flash sectors without
+// collisions
+func Layout(recs []*HexRec) []image.Span {
+	var segs []image.Segment
+	for _, r := range recs {
+		if r.RecordType.IsData() {
+			segs = append(segs, image.Segment{Address: r.Address, Data: r.Data})
+		}
+	}
+	return image.Layout(segs)
+}
+
+// WriteBinary writes the data of recs to w as raw binary, as a flash
+// programmer takes it: from the lowest to the highest address with data,
+// gaps set to fill.  The address of the first byte written is returned.
+func WriteBinary(w io.Writer, recs []*HexRec, fill byte) (base uint32, err error) {
+	return NewFile(recs).Image().WriteBinary(w, fill)
+}
+
+// WriteSparseBinary is WriteBinary leaving the gaps as holes, as
+// image.Image.WriteSparseBinary does
+func WriteSparseBinary(w io.WriteSeeker, recs []*HexRec) (base uint32, err error) {
+	return NewFile(recs).Image().WriteSparseBinary(w)
+}
+
+// WriteBinaryFile is WriteBinary to the file named fn, which is
+// replaced atomically
+func WriteBinaryFile(fn string, recs []*HexRec, fill byte) (base uint32, err error) {
+	m := NewFile(recs).Image()
+	err = atomicfile.WriteFile(fn, 0644, func(w io.Writer) error {
+		base, err = m.WriteBinary(w, fill)
+		return err
+	})
+	return base, err
+}
+
+// DataBytes returns the data payload of the file as a single buffer
+// starting at base.  Gaps between data records are filled with 0xFF,
+// the erased state of most flash parts.
+func (f *File) DataBytes() (base uint32, data []byte) {
+	return f.Image().Flatten(0xFF)
+}
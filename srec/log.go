@@ -0,0 +1,42 @@
+package srec
+
+import "log/slog"
+
+// discard is handed out whenever no logger has been configured
+var discard = slog.New(slog.DiscardHandler)
+
+// SetLogger directs the decoder's diagnostics, such as decode failures
+// with their line numbers, record counts and records after the
+// terminating record, to l.  Passing nil, the default, disables logging.
+func (d *Decoder) SetLogger(l *slog.Logger) {
+	d.log = l
+}
+
+func (d *Decoder) logger() *slog.Logger {
+	if d.log != nil {
+		return d.log
+	}
+	return discard
+}
+
+// SetLogger directs writer diagnostics, such as automatically emitted
+// header, count and start records, to l.  Passing nil disables logging.
+func (x *Writer) SetLogger(l *slog.Logger) {
+	x.log = l
+}
+
+func (x *Writer) logger() *slog.Logger {
+	if x.log != nil {
+		return x.log
+	}
+	return discard
+}
+
+// SetTrace registers fn to be called with every record the writer emits,
+// header, data, count and start records alike, after it has been written
+// to the output stream.  This lets generation pipelines audit record
+// type, address and length without parsing their own output.  The
+// record's Data must not be retained by fn.  Passing nil disables tracing.
+func (x *Writer) SetTrace(fn func(r HexRec)) {
+	x.trace = fn
+}
@@ -1,12 +1,18 @@
 package srec
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math/rand"
 	"os"
-	//	"strings"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/peteArnt/GoHexIO/checksum"
 )
 
 var binData []byte
@@ -21,7 +27,7 @@ func init() {
 func TestLoopback(t *testing.T) {
 	fmt.Fprintln(os.Stdout, "Loopback test...")
 
-	f, err := os.OpenFile("temp.srec", os.O_WRONLY, 0755)
+	f, err := os.OpenFile("temp.srec", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failure creating temp file: %s\n", err)
 		t.Fail()
@@ -62,3 +68,263 @@ func TestLoopback(t *testing.T) {
 	fmt.Printf("%d records\n", len(recs))
 
 }
+
+func TestWriterLogger(t *testing.T) {
+	fmt.Println("TestWriterLogger()")
+
+	var out, logBuf bytes.Buffer
+	w := NewWriter(&out, Addr16)
+	w.SetLogger(slog.New(slog.NewTextHandler(&logBuf,
+		&slog.HandlerOptions{Level: slog.LevelDebug})))
+	w.SetHeader([]byte("hdr"))
+	w.SetCountEmit()
+	w.SetStartAddress(0)
+	w.Write([]byte{1, 2, 3})
+	w.Close()
+
+	for _, s := range []string{"header record", "count record", "start record"} {
+		if !bytes.Contains(logBuf.Bytes(), []byte(s)) {
+			fmt.Printf("log output missing %q\n", s)
+			t.Fail()
+		}
+	}
+}
+
+func TestCustomChecksum(t *testing.T) {
+	fmt.Println("TestCustomChecksum()")
+
+	var out bytes.Buffer
+	w := NewWriter(&out, Addr24)
+	w.SetChecksum(checksum.XOR)
+	w.Write(binData[:100])
+	w.Close()
+
+	if _, err := ReadAll(bytes.NewReader(out.Bytes())); err == nil {
+		fmt.Println("failure: XOR checksums accepted by default decoder")
+		t.Fail()
+	}
+
+	d := NewDecoder(bytes.NewReader(out.Bytes()))
+	d.SetChecksum(checksum.XOR)
+	recs, err := d.DecodeAll()
+	if err != nil || len(recs) != 10 {
+		fmt.Printf("failure: %d records, err=%v\n", len(recs), err)
+		t.Fail()
+	}
+}
+
+func TestWriterTrace(t *testing.T) {
+	fmt.Println("TestWriterTrace()")
+
+	var (
+		out   bytes.Buffer
+		trace []HexRec
+	)
+	w := NewWriter(&out, Addr24)
+	w.SetTrace(func(r HexRec) {
+		r.Data = append([]byte(nil), r.Data...)
+		trace = append(trace, r)
+	})
+	w.SetWidth(4)
+	w.SetAddress(0x12340)
+	w.SetStartAddress(0x12340)
+	w.Write([]byte{1, 2, 3, 4, 5, 6})
+	w.Close()
+
+	want := []HexRec{
+		{Address: 0x12340, RecordType: S2Data, Data: []byte{1, 2, 3, 4}},
+		{Address: 0x12344, RecordType: S2Data, Data: []byte{5, 6}},
+		{Address: 0x12340, RecordType: S8Start, Data: nil},
+	}
+	if !reflect.DeepEqual(trace, want) {
+		fmt.Printf("trace %v, want %v\n", trace, want)
+		t.Fail()
+	}
+}
+
+func TestDryRunWriter(t *testing.T) {
+	fmt.Println("TestDryRunWriter()")
+
+	var out bytes.Buffer
+	real := NewWriter(&out, Addr32)
+	dry, tally := NewDryRunWriter(Addr32)
+	for _, w := range []*Writer{real, dry} {
+		w.SetHeader([]byte("dry"))
+		w.SetCountEmit()
+		w.SetStartAddress(0x8000)
+		w.SetAddress(0x8000)
+		w.Write(binData[:1000])
+		w.Close()
+	}
+
+	if tally.Bytes != int64(out.Len()) || tally.Records != bytes.Count(out.Bytes(), []byte{'\n'}) {
+		fmt.Printf("tally %+v, output %d bytes\n", *tally, out.Len())
+		t.Fail()
+	}
+}
+
+func TestCloneTo(t *testing.T) {
+	fmt.Println("TestCloneTo()")
+
+	var a, b bytes.Buffer
+	w := NewWriter(&a, Addr24)
+	w.SetHeader([]byte("clone"))
+	w.SetCountEmit()
+	w.SetStartAddress(0x100)
+	if err := w.Options().Validate(); err != nil {
+		fmt.Println(err)
+		t.Fail()
+	}
+
+	c := w.CloneTo(&b)
+	for _, x := range []*Writer{w, c} {
+		x.SetAddress(0x100)
+		x.Write(binData[:100])
+		x.Close()
+	}
+	if a.String() != b.String() {
+		fmt.Println("clone produced different output")
+		t.Fail()
+	}
+
+	o := w.Options()
+	o.StartAddress = 0x1000000
+	if o.Validate() == nil {
+		fmt.Println("out of range start address accepted")
+		t.Fail()
+	}
+}
+
+func TestWriteChunking(t *testing.T) {
+	fmt.Println("TestWriteChunking()")
+
+	var whole, pieces bytes.Buffer
+
+	w := NewWriter(&whole, Addr32)
+	w.Write(binData)
+	w.Close()
+
+	w = NewWriter(&pieces, Addr32)
+	for p := binData; len(p) > 0; p = p[min(7, len(p)):] {
+		w.Write(p[:min(7, len(p))])
+	}
+	w.Close()
+
+	if whole.String() != pieces.String() {
+		fmt.Println("output depends on write sizes")
+		t.Fail()
+	}
+}
+
+func TestReset(t *testing.T) {
+	fmt.Println("TestReset()")
+
+	var a, b bytes.Buffer
+	w := NewWriter(&a, Addr16)
+	w.SetHeader([]byte("reset"))
+	w.SetCountEmit()
+	w.Write(binData[:50])
+	w.Close()
+
+	w.Reset(&b)
+	w.Write(binData[:50])
+	w.Close()
+
+	if a.String() != b.String() {
+		fmt.Println("output after Reset differs")
+		t.Fail()
+	}
+
+	d := NewDecoder(&a)
+	first, err := d.DecodeAll()
+	if err != nil {
+		fmt.Println(err)
+		t.FailNow()
+	}
+	d.Reset(&b)
+	second, err := d.DecodeAll()
+	if err != nil || !reflect.DeepEqual(first, second) || d.Line() != len(second) {
+		fmt.Println("decoding after Reset differs:", err)
+		t.Fail()
+	}
+}
+
+func TestEmitted(t *testing.T) {
+	fmt.Println("TestEmitted()")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Addr32)
+	w.Write(binData[:25])
+	if w.Accepted() != 25 || w.Emitted() != 20 || w.Buffered() != 5 {
+		fmt.Println("counts after write:", w.Accepted(), w.Emitted(), w.Buffered())
+		t.Fail()
+	}
+	w.Flush()
+	if w.Emitted() != 25 || w.Buffered() != 0 {
+		fmt.Println("counts after flush:", w.Accepted(), w.Emitted(), w.Buffered())
+		t.Fail()
+	}
+	w.Reset(&buf)
+	if w.Accepted() != 0 || w.Emitted() != 0 {
+		fmt.Println("counts survive Reset")
+		t.Fail()
+	}
+}
+
+func TestCloseChecks(t *testing.T) {
+	fmt.Println("TestCloseChecks()")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Addr32)
+	w.SetCloseChecks(true)
+	w.SetStartAddress(0x100)
+	if err := w.Close(); !errors.Is(err, ErrNoData) {
+		fmt.Println("start address without data:", err)
+		t.Fail()
+	}
+
+	w.Reset(&buf)
+	w.Write(binData[:3])
+	if err := w.Close(); err != nil {
+		fmt.Println("good output:", err)
+		t.Fail()
+	}
+}
+
+func TestDoubleClose(t *testing.T) {
+	fmt.Println("TestDoubleClose()")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Addr32)
+	w.Close()
+	if err := w.Close(); !errors.Is(err, ErrClosed) {
+		fmt.Println("second Close:", err)
+		t.Fail()
+	}
+}
+
+func TestAutoHeader(t *testing.T) {
+	fmt.Println("TestAutoHeader()")
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Addr16)
+	w.SetAutoHeader("gohexio", "v1.2")
+	w.Write([]byte{1})
+	w.Close()
+
+	recs, err := ReadAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := string(recs[0].Data); recs[0].RecordType != S0Header || h != "gohexio v1.2 2023-11-14T22:13:20Z" {
+		fmt.Printf("header %q\n", h)
+		t.Fail()
+	}
+
+	if h := AutoHeader(strings.Repeat("x", 300), "", time.Unix(0, 0)); len(h) != 252 {
+		fmt.Printf("header of %d bytes\n", len(h))
+		t.Fail()
+	}
+}
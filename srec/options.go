@@ -0,0 +1,226 @@
+package srec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+)
+
+// Options is a snapshot of the configuration of a Writer
+type Options struct {
+	AddrMode     AddrMode           // Address mode: 16, 24, or 32 bit addressing
+	Width        int                // Bytes per data record
+	Header       []byte             // S0 header content, nil for none
+	StartAddress uint32             // Address carried by the start record
+	EmitStart    bool               // Emit a start record at Close()
+	EmitCount    bool               // Emit a count record at Close()
+	Checksum     checksum.Algorithm // Record checksum algorithm
+	Logger       *slog.Logger       // Diagnostics sink, nil for none
+	Trace        func(HexRec)       // Per-record callback, nil for none
+	Scale        int                // Bytes per address unit, 0 or 1 for byte addressing
+	CheckClose   bool               // Close fails on suspect output; see SetCloseChecks
+	EmptyData    EmptyPolicy        // Handling of empty segments; see SetEmptyData
+	FixedRecords bool               // Pad every data record to Width; see SetFixedRecords
+	Fill         byte               // Value of the padding bytes under FixedRecords
+	Integrity    checksum.Digest    // Digest of the integrity trailer, "" for none; see SetIntegrity
+	LineEnding   string             // Record terminator, "" for LF; see SetLineEnding
+	Uppercase    bool               // Upper case hex digits; see WithUppercase
+}
+
+// Validate reports whether the options describe a usable writer
+func (o Options) Validate() error {
+	var top uint64
+	switch o.AddrMode {
+	case Addr16, Addr24, Addr32:
+		top = 1 << o.AddrMode
+	default:
+		return fmt.Errorf("invalid address mode %d", o.AddrMode)
+	}
+
+	// The byte count field covers address, data and checksum
+	if limit := 255 - int(o.AddrMode)/8 - 1; o.Width < 1 || o.Width > limit {
+		return fmt.Errorf("record width %d out of range 1..%d", o.Width, limit)
+	}
+	if len(o.Header) > 252 {
+		return fmt.Errorf("header of %d bytes exceeds 252", len(o.Header))
+	}
+	if o.EmitStart && uint64(o.StartAddress) >= top {
+		return fmt.Errorf("start address 0x%X exceeds %d-bit address mode",
+			o.StartAddress, o.AddrMode)
+	}
+	if o.Checksum == nil {
+		return errors.New("no checksum algorithm")
+	}
+	if o.Scale < 0 || o.Width%max(o.Scale, 1) != 0 {
+		return fmt.Errorf("record width %d is not a multiple of the address scale %d", o.Width, o.Scale)
+	}
+	if err := validateEOL(o.LineEnding); err != nil {
+		return err
+	}
+	if o.Integrity != "" && o.Integrity.New() == nil {
+		return fmt.Errorf("unknown integrity digest %q", o.Integrity)
+	}
+	return o.EmptyData.validate()
+}
+
+// Options returns a snapshot of the writer's configuration
+func (x *Writer) Options() Options {
+	return Options{
+		AddrMode:     x.addrMode,
+		Width:        x.width,
+		Header:       x.header,
+		StartAddress: x.startAddr,
+		EmitStart:    x.emitStartRec,
+		EmitCount:    x.emitCountRec,
+		Checksum:     x.sum,
+		Logger:       x.log,
+		Trace:        x.trace,
+		Scale:        x.scale,
+		CheckClose:   x.check,
+		EmptyData:    x.empty,
+		FixedRecords: x.fixed,
+		Fill:         x.fill,
+		Integrity:    x.integrity,
+		LineEnding:   x.eol,
+		Uppercase:    x.upper,
+	}
+}
+
+// CloneTo creates a new writer for w configured identically to x.  None
+// of x's state, such as its address counter, record count or buffered
+// data, is copied.
+func (x *Writer) CloneTo(w io.Writer) *Writer {
+	c := &Writer{w: w}
+	c.apply(x.Options())
+	return c
+}
+
+// apply replaces the configuration of x by o
+func (x *Writer) apply(o Options) {
+	x.addrMode = o.AddrMode
+	x.width = o.Width
+	x.header = o.Header
+	x.startAddr = o.StartAddress
+	x.emitStartRec = o.EmitStart
+	x.emitCountRec = o.EmitCount
+	x.sum = o.Checksum
+	x.log = o.Logger
+	x.trace = o.Trace
+	x.scale = o.Scale
+	x.check = o.CheckClose
+	x.empty = o.EmptyData
+	x.fixed = o.FixedRecords
+	x.fill = o.Fill
+	x.eol = o.LineEnding
+	x.upper = o.Uppercase
+	x.SetIntegrity(o.Integrity)
+}
+
+// Option configures a Writer created by NewWriter, so its whole
+// configuration is given at once:
+//
+//	x := srec.NewWriter(w, srec.Addr32, srec.WithHeader(h), srec.WithCountRecord())
+type Option func(*Writer)
+
+// WithOptions configures the writer as o describes, as taken from
+// Writer.Options, address mode included; options following it adjust
+// the configuration further
+func WithOptions(o Options) Option {
+	return func(x *Writer) { x.apply(o) }
+}
+
+// WithWidth sets the number of data bytes per record
+func WithWidth(n int) Option {
+	return func(x *Writer) { x.SetWidth(n) }
+}
+
+// WithHeader sets the content of the S0 header record
+func WithHeader(h []byte) Option {
+	return func(x *Writer) { x.SetHeader(h) }
+}
+
+// WithAutoHeader sets a header identifying the generating tool; see
+// SetAutoHeader
+func WithAutoHeader(tool, version string) Option {
+	return func(x *Writer) { x.SetAutoHeader(tool, version) }
+}
+
+// WithStartAddress makes Close write a start record for address a
+func WithStartAddress(a uint32) Option {
+	return func(x *Writer) { x.SetStartAddress(a) }
+}
+
+// WithCountRecord makes Close write a count record
+func WithCountRecord() Option {
+	return func(x *Writer) { x.SetCountEmit() }
+}
+
+// WithUppercase writes the hex digits of records in upper case, as many
+// tools do, rather than in lower case
+func WithUppercase() Option {
+	return func(x *Writer) { x.upper = true }
+}
+
+// WithChecksum selects the record checksum algorithm; see SetChecksum
+func WithChecksum(a checksum.Algorithm) Option {
+	return func(x *Writer) { x.SetChecksum(a) }
+}
+
+// WithLineEnding sets the record terminator; see SetLineEnding
+func WithLineEnding(eol string) Option {
+	return func(x *Writer) { x.SetLineEnding(eol) }
+}
+
+// WithFixedRecords pads every data record to the record width; see
+// SetFixedRecords
+func WithFixedRecords(fill byte) Option {
+	return func(x *Writer) { x.SetFixedRecords(fill) }
+}
+
+// WithAddressScale makes addresses count units of n bytes; see
+// SetAddressScale
+func WithAddressScale(n int) Option {
+	return func(x *Writer) { x.SetAddressScale(n) }
+}
+
+// WithCloseChecks makes Close fail on suspect output; see SetCloseChecks
+func WithCloseChecks() Option {
+	return func(x *Writer) { x.SetCloseChecks(true) }
+}
+
+// WithEmptyData selects the handling of empty segments; see SetEmptyData
+func WithEmptyData(p EmptyPolicy) Option {
+	return func(x *Writer) { x.SetEmptyData(p) }
+}
+
+// WithIntegrity appends an integrity trailer; see SetIntegrity
+func WithIntegrity(d checksum.Digest) Option {
+	return func(x *Writer) { x.SetIntegrity(d) }
+}
+
+// WithLogger routes the writer's diagnostics to l; see SetLogger
+func WithLogger(l *slog.Logger) Option {
+	return func(x *Writer) { x.SetLogger(l) }
+}
+
+// WithTrace calls fn with every record emitted; see SetTrace
+func WithTrace(fn func(r HexRec)) Option {
+	return func(x *Writer) { x.SetTrace(fn) }
+}
+
+// validateEOL reports whether eol is a usable record terminator, made of
+// control characters alone
+func validateEOL(eol string) error {
+	if eol == "" {
+		return nil
+	}
+	for i := 0; i < len(eol); i++ {
+		if eol[i] >= ' ' {
+			return fmt.Errorf("line ending %q holds other than control characters", eol)
+		}
+	}
+	return nil
+}
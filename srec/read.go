@@ -2,26 +2,34 @@ package srec
 
 import (
 	"bytes"
-	"encoding/hex"
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"strconv"
+	"io"
+	"io/fs"
+	"os"
 	"strings"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+	"github.com/peteArnt/GoHexIO/image"
+	"github.com/peteArnt/GoHexIO/internal/arena"
+	"github.com/peteArnt/GoHexIO/internal/coalesce"
+	"github.com/peteArnt/GoHexIO/internal/hexenc"
 )
 
-type srecType int
+// SrecType identifies the type of an S-Record, S0 through S9
+type SrecType int
 
 // HexRec is the generalized form of a hex record
 type HexRec struct {
 	Address    uint32
-	RecordType srecType
+	RecordType SrecType
 	Data       []byte
 }
 
 // Enumerated S-Record types
 const (
-	S0Header srecType = iota // 0
+	S0Header SrecType = iota // 0
 	S1Data                   // 1...
 	S2Data                   //
 	S3Data                   //
@@ -33,14 +41,14 @@ const (
 	S9Start
 )
 
-var srecTypeMap map[string]srecType
-var srecStrMap map[srecType]string
+var srecTypeMap map[string]SrecType
+var srecStrMap map[SrecType]string
 
 func init() {
-	srecTypeMap = make(map[string]srecType)
-	srecStrMap = make(map[srecType]string)
+	srecTypeMap = make(map[string]SrecType)
+	srecStrMap = make(map[SrecType]string)
 
-	var srecEnums = []srecType{
+	var srecEnums = []SrecType{
 		S0Header, S1Data, S2Data,
 		S3Data, S5Count, S6Count,
 		S7Start, S8Start, S9Start}
@@ -52,6 +60,56 @@ func init() {
 	}
 }
 
+// Go identifiers of the record types, also accepted by ParseSrecType
+var srecIdentMap = map[SrecType]string{
+	S0Header: "S0Header",
+	S1Data:   "S1Data",
+	S2Data:   "S2Data",
+	S3Data:   "S3Data",
+	S5Count:  "S5Count",
+	S6Count:  "S6Count",
+	S7Start:  "S7Start",
+	S8Start:  "S8Start",
+	S9Start:  "S9Start",
+}
+
+// String returns the record type as it appears in a file, such as "S1"
+func (t SrecType) String() string {
+	if s, ok := srecStrMap[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("SrecType(%d)", int(t))
+}
+
+// AddrMode returns the width of the address field of records of type t
+func (t SrecType) AddrMode() AddrMode {
+	return AddrMode(8 * addrSize(t))
+}
+
+// IsData reports whether t is one of the data record types S1, S2 or S3
+func (t SrecType) IsData() bool {
+	return t == S1Data || t == S2Data || t == S3Data
+}
+
+// ParseSrecType returns the record type named s: either its form in a
+// file ("S1"), its bare number ("1") or its Go constant name ("S1Data"),
+// regardless of case.
+func ParseSrecType(s string) (SrecType, error) {
+	key := strings.ToUpper(strings.TrimSpace(s))
+	if len(key) == 1 {
+		key = "S" + key
+	}
+	if t, ok := srecTypeMap[key]; ok {
+		return t, nil
+	}
+	for t, ident := range srecIdentMap {
+		if strings.EqualFold(s, ident) {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown S-Record type %q", s)
+}
+
 // String is the idiomatic Go string-ize method
 func (r HexRec) String() string {
 	var s string
@@ -78,85 +136,142 @@ func (r HexRec) String() string {
 	return s
 }
 
+// addrSize returns the size in bytes of the address field of records of
+// type t
+func addrSize(t SrecType) int {
+	switch t {
+	case S2Data, S6Count, S8Start: // 24-bit address cases
+		return 3
+
+	case S3Data, S7Start: // 32-bit address cases
+		return 4
+	}
+	return 2 // 16-bit address cases
+}
+
 // Break the ASCII-Hex record up into fields; translate
 // and validate all fields according to record type.
-func decodeRecord(r string) (rec *HexRec, err error) {
-	defer func() {
-		if x := recover(); x != nil {
-			err = fmt.Errorf("run time panic: %v", x)
-		}
-	}()
+func decodeRecord(r string, sum checksum.Algorithm) (*HexRec, error) {
+	return decodeRecordIn(r, sum, nil)
+}
 
+// decodeRecordIn is decodeRecord taking the record and its data from a,
+// or from the heap if a is nil.  r may be a string or, sparing the
+// conversion, a byte slice.
+func decodeRecordIn[S ~string | ~[]byte](r S, sum checksum.Algorithm, a *arena.Arena[HexRec]) (*HexRec, error) {
 	var (
-		address   string
-		data      string
-		checksum  string
-		header    = r[:2]
-		byteCount = r[2:4]
-		recTyp    = srecTypeMap[header]
-		ovhd      int
-		csData    = r[2 : len(r)-2] // this is what will be checksum'd
+		rec *HexRec
+		buf []byte
 	)
+	if a != nil {
+		rec, buf = a.Item(), a.Bytes(len(r)/2)
+	} else {
+		rec, buf = new(HexRec), make([]byte, len(r)/2)
+	}
 
-	switch recTyp {
-	case S0Header, S1Data, S5Count, S9Start: // 16-bit address cases
-		address = r[4:8]
-		data = r[8:]
-		ovhd = 2 + 1
-
-	case S2Data, S6Count, S8Start: // 24-bit address cases
-		address = r[4:10]
-		data = r[10:]
-		ovhd = 3 + 1
+	if err := decodeRecordInto(rec, r, sum, buf); err != nil {
+		return nil, err
+	}
+	rec.Data = rec.Data[:len(rec.Data):len(rec.Data)]
+	return rec, nil
+}
 
-	case S3Data, S7Start: // 32-bit address cases
-		address = r[4:12]
-		data = r[12:]
-		ovhd = 4 + 1
+// decodeRecordInto decodes r into rec, storing the data at the start of
+// buf, which must hold at least len(r)/2 bytes.  All fields are decoded
+// in one pass into buf and picked apart by index, so no intermediate
+// strings are created.
+func decodeRecordInto[S ~string | ~[]byte](rec *HexRec, r S, sum checksum.Algorithm, buf []byte) (err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("run time panic: %v", x)
+		}
+	}()
 
-	default:
-		return nil, errors.New("Unknown SREC type")
+	if len(r) < 4 {
+		return errors.New("Record too short")
 	}
 
-	checksum, data = data[len(data)-2:], data[:len(data)-2]
-	cs, err := strconv.ParseUint(checksum, 16, 8)
-	if err != nil {
-		return nil, err
+	if r[0] != 'S' || r[1] < '0' || r[1] > '9' {
+		return errors.New("Unknown SREC type")
 	}
-
-	csCalc, err := calcChecksumHexASCII(csData)
-	if err != nil {
-		return nil, err
+	recTyp := SrecType(r[1] - '0')
+	if _, ok := srecStrMap[recTyp]; !ok {
+		return errors.New("Unknown SREC type")
 	}
-	if byte(cs) != csCalc {
-		return nil, errors.New("Checksum error")
+
+	addrLen := addrSize(recTyp)
+
+	// Byte count, address and checksum digits must at least be present
+	if len(r) < 4+2*addrLen+2 {
+		return errors.New("Record too short")
 	}
 
-	binData, err := hex.DecodeString(data)
-	if err != nil {
-		return nil, fmt.Errorf("Data chars bad: %s", err)
+	// Everything after the type is hex: byte count, address, data and
+	// checksum
+	b := buf[:(len(r)-2)/2]
+	if _, err := hexenc.Decode(b, r[2:]); err != nil {
+		return fmt.Errorf("Data chars bad: %s", err)
 	}
 
-	addrBin, err := strconv.ParseUint(address, 16, 32)
-	if err != nil {
-		return nil, fmt.Errorf("Address field error: %s", err)
+	cs, b := b[len(b)-1], b[:len(b)-1]
+	if cs != sum.Sum(b) {
+		return errors.New("Checksum error")
 	}
 
-	bc, err := strconv.ParseUint(byteCount, 16, 8)
-	if err != nil {
-		return nil, fmt.Errorf("Byte-count field error: %s", err)
+	// The byte count covers address, data and checksum
+	if int(b[0]) != len(b) {
+		return errors.New("byte-count error")
 	}
 
-	if int(bc) != (len(binData) + ovhd) {
-		return nil, errors.New("byte-count error")
+	var addr uint32
+	for _, v := range b[1 : 1+addrLen] {
+		addr = addr<<8 | uint32(v)
 	}
 
-	rec = new(HexRec)
-	rec.Address = uint32(addrBin)
+	rec.Address = addr
 	rec.RecordType = recTyp
-	rec.Data = binData
 
-	return rec, nil
+	// Move the data to the front of buf, so a recycled buffer keeps its
+	// full capacity
+	rec.Data = buf[:copy(buf, b[1+addrLen:])]
+
+	return nil
+}
+
+// DecodeBatch decodes S-Records from lines into the caller's slice out,
+// verifying them against the standard checksum, and returns the number of
+// records stored.  Blank lines are skipped.  The Data slices already held
+// by out are reused when large enough, so services recycling out between
+// files avoid per-record allocations.  Decoding stops at the first bad
+// record, and with io.ErrShortBuffer if out fills up before lines run
+// out.
+func DecodeBatch(lines []string, out []HexRec) (n int, err error) {
+	for i, s := range lines {
+		if s == "" {
+			continue
+		}
+		if n == len(out) {
+			return n, io.ErrShortBuffer
+		}
+
+		buf := out[n].Data
+		if cap(buf) < len(s)/2 {
+			buf = make([]byte, len(s)/2)
+		}
+		if err := decodeRecordInto(&out[n], s, checksum.OnesComplement, buf[:cap(buf)]); err != nil {
+			return n, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// DecodeRecordString decodes a single S-Record, verifying it against the
+// standard checksum.  It performs no I/O and returns an error, never
+// panics, on malformed input, making it suitable as a fuzzing entry point
+// and for handling untrusted data.
+func DecodeRecordString(s string) (*HexRec, error) {
+	return decodeRecord(s, checksum.OnesComplement)
 }
 
 // Process all hex records
@@ -165,7 +280,7 @@ func processRecords(records []string) ([]*HexRec, error) {
 
 	for _, rec := range records {
 		if len(rec) > 0 {
-			hr, err := decodeRecord(rec)
+			hr, err := decodeRecord(rec, checksum.OnesComplement)
 			if err != nil {
 				return nil, err
 			}
@@ -176,74 +291,99 @@ func processRecords(records []string) ([]*HexRec, error) {
 	return hrecs, nil
 }
 
-// Load contents of hex file into memory; break up into
-// a slice of strings.
-func loadFile(fn string) ([]string, error) {
-	content, err := ioutil.ReadFile(fn)
-	if err != nil {
-		return nil, err
+// ReadAll reads S-Records from r until EOF and converts them into
+// a slice of hex records, decoded by a Decoder configured by opts.
+func ReadAll(r io.Reader, opts ...DecoderOption) ([]*HexRec, error) {
+	return ReadAllContext(context.Background(), r, opts...)
+}
+
+// ReadAllContext is like ReadAll but stops and returns ctx.Err() if
+// ctx is cancelled or its deadline expires while reading.
+func ReadAllContext(ctx context.Context, r io.Reader, opts ...DecoderOption) ([]*HexRec, error) {
+	return NewDecoder(r, opts...).decodeAll(ctx)
+}
+
+// ParseBytes decodes the S-Records held in b.  Like DecodeRecordString it
+// performs no file I/O and never panics.  The records are decoded
+// straight out of b, which suits images embedded with go:embed, and
+// their data shares a single allocation; b itself is not retained.
+func ParseBytes(b []byte) ([]*HexRec, error) {
+	var (
+		hrecs []*HexRec
+		a     = arena.New[HexRec](len(b) / 2)
+	)
+
+	for line := range bytes.Lines(b) {
+		line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+		if len(line) == 0 {
+			continue
+		}
+		hr, err := decodeRecordIn(line, checksum.OnesComplement, a)
+		if err != nil {
+			return nil, err
+		}
+		hrecs = append(hrecs, hr)
 	}
 
-	records := strings.Split(string(content), "\n")
+	return hrecs, nil
+}
 
-	return records, nil
+// Parse decodes the S-Records of f, such as a file opened from an
+// embed.FS.  The content is read in one go into a buffer sized from
+// f.Stat, then decoded by ParseBytes.
+func Parse(f fs.File) ([]*HexRec, error) {
+	var buf bytes.Buffer
+	if fi, err := f.Stat(); err == nil && fi.Size() > 0 {
+		buf.Grow(int(fi.Size()) + bytes.MinRead)
+	}
+	if _, err := buf.ReadFrom(f); err != nil {
+		return nil, err
+	}
+	return ParseBytes(buf.Bytes())
 }
 
 // ReadFile loads the contents of a hex file into memory and
-// converts the contents into a slice of hex records.
-func ReadFile(fn string) ([]*HexRec, error) {
-	records, err := loadFile(fn)
+// converts the contents into a slice of hex records, decoded as
+// configured by opts.
+func ReadFile(fn string, opts ...DecoderOption) ([]*HexRec, error) {
+	return ReadFileContext(context.Background(), fn, opts...)
+}
+
+// ReadFileContext is like ReadFile but honors cancellation and
+// deadlines of ctx.
+func ReadFileContext(ctx context.Context, fn string, opts ...DecoderOption) ([]*HexRec, error) {
+	f, err := os.Open(fn)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
+
+	return ReadAllContext(ctx, f, opts...)
+}
 
-	return processRecords(records)
+// Decode reads S-Records from r into a memory image
+func Decode(r io.Reader) (*image.Image, error) {
+	recs, err := ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewFile(recs).Image(), nil
 }
 
+// CoalesceOptions tune how CoalesceDataRecsOptions merges data records
+type CoalesceOptions = coalesce.Options
+
 // CoalesceDataRecs merges a contiguous runs of data records. All other
 // record types are unaffected.  Contiguous data records are coalesced into
 // a so-called "jumbo" data record.  A jumbo record is really a hex record
 // that represents a large run of contiguous bytes
 func CoalesceDataRecs(list []*HexRec) []*HexRec {
-	type handler func(*HexRec, srecType)
-	var (
-		dataRecGroup   bool
-		addressCounter uint32
-		outList        []*HexRec
-		data           bytes.Buffer
-		dataBaseAddr   uint32
-		processDataRec handler
-	)
-
-	emitJumboDataRec := func(pt srecType) {
-		var r HexRec
-		r.Address = dataBaseAddr // Set Base Address
-		r.RecordType = pt
-		r.Data = data.Bytes()         // Set Data slice within record
-		data.Reset()                  // Clear accumulation buffer
-		outList = append(outList, &r) // Append record to output slice
-	}
-
-	processDataRec = func(r *HexRec, pt srecType) {
-		if !dataRecGroup { // have we hit a new run of data recs?
-			dataRecGroup = true
-			dataBaseAddr = r.Address
-			addressCounter = r.Address + uint32(len(r.Data))
-			data.Reset()
-			data.Write(r.Data)
-		} else {
-			if r.Address == addressCounter { // Contiguous with previous?
-				data.Write(r.Data)
-				addressCounter += uint32(len(r.Data))
-			} else { // else, data records are not contiguous
-				// Emit a Jumbo Data Record; reset temp buffer
-				emitJumboDataRec(pt)
-				dataRecGroup = false
-				processDataRec(r, pt)
-			}
-		}
-	}
+	return CoalesceDataRecsOptions(list, CoalesceOptions{})
+}
 
+// CoalesceDataRecsOptions is like CoalesceDataRecs but can additionally
+// bridge small gaps between runs and cap the size of the merged records.
+func CoalesceDataRecsOptions(list []*HexRec, opts CoalesceOptions) []*HexRec {
 	// Survey data record types
 	var s1Count, s2Count, s3Count int
 	for _, r := range list {
@@ -259,7 +399,7 @@ func CoalesceDataRecs(list []*HexRec) []*HexRec {
 
 	// In case mixed data records were used in the original file,
 	// determine which should be used for emitted jumbo records
-	var preferredDataRecType srecType
+	var preferredDataRecType SrecType
 	if s3Count > 0 {
 		preferredDataRecType = S3Data
 	} else if s2Count > 0 {
@@ -268,26 +408,12 @@ func CoalesceDataRecs(list []*HexRec) []*HexRec {
 		preferredDataRecType = S1Data
 	}
 
-	// Cycle through all records looking specifically for data
-	// records.
-	for _, r := range list {
-		switch r.RecordType {
-		case S1Data, S2Data, S3Data:
-			processDataRec(r, preferredDataRecType)
-
-		default: // Other type found; terminate preceeding data group
-			if data.Len() > 0 {
-				emitJumboDataRec(preferredDataRecType)
-			}
-			dataRecGroup = false
-			outList = append(outList, r)
-		}
-	}
-
-	// Do we need to emit one last jumbo data record?
-	if data.Len() > 0 {
-		emitJumboDataRec(preferredDataRecType)
-	}
-
-	return outList
+	return coalesce.Records(list, coalesce.Adapter[*HexRec, uint32]{
+		IsData:  func(r *HexRec) bool { return r.RecordType.IsData() },
+		Address: func(r *HexRec) uint32 { return r.Address },
+		Data:    func(r *HexRec) []byte { return r.Data },
+		Make: func(addr uint32, b []byte) *HexRec {
+			return &HexRec{Address: addr, RecordType: preferredDataRecType, Data: b}
+		},
+	}, opts)
 }
@@ -1,9 +1,19 @@
 package srec
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+	"github.com/peteArnt/GoHexIO/image"
 )
 
 func TestChecksumCalc(t *testing.T) {
@@ -77,3 +87,742 @@ S111003848656C6C6F20776F726C642E0A0042
 		t.Fail()
 	}
 }
+
+func TestAddr32Length(t *testing.T) {
+	fmt.Println("TestAddr32Length()")
+
+	var out strings.Builder
+	w := NewWriter(&out, Addr32)
+	w.SetAddress(0x01020304)
+	w.Write([]byte{0xAA, 0xBB})
+	w.Close()
+
+	// Four address bytes, two data bytes and the checksum
+	line, _, _ := strings.Cut(out.String(), "\n")
+	if !strings.HasPrefix(line, "S307") {
+		fmt.Printf("S3 record %q, want byte count 07\n", line)
+		t.Fail()
+	}
+	recs, err := ReadAll(strings.NewReader(out.String()))
+	if err != nil || len(recs) == 0 || recs[0].Address != 0x01020304 || len(recs[0].Data) != 2 {
+		fmt.Printf("failure: %v %v\n", recs, err)
+		t.Fail()
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	fmt.Println("TestReadAll()")
+
+	bulkSrec := `S00F000068656C6C6F202020202000003C
+S11F00007C0802A6900100049421FFF07C6C1B787C8C23783C6000003863000026
+S5030001FB
+S9030000FC
+`
+	hrecs, err := ReadAll(strings.NewReader(bulkSrec))
+	if err != nil {
+		fmt.Println("\t", err)
+		t.Fail()
+	}
+
+	if len(hrecs) != 4 {
+		fmt.Println("bad record count")
+		t.Fail()
+	}
+}
+
+func TestReadAllContextCancelled(t *testing.T) {
+	fmt.Println("TestReadAllContextCancelled()")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ReadAllContext(ctx, strings.NewReader("S9030000FC\n"))
+	if err != context.Canceled {
+		fmt.Println("expected context.Canceled, got", err)
+		t.Fail()
+	}
+}
+
+func TestStats(t *testing.T) {
+	fmt.Println("TestStats()")
+
+	bulkSrec := `S00F000068656C6C6F202020202000003C
+S11F00007C0802A6900100049421FFF07C6C1B787C8C23783C6000003863000026
+S111003848656C6C6F20776F726C642E0A0042
+S5030002FA
+S9030000FC
+`
+	hrecs, err := ReadAll(strings.NewReader(bulkSrec))
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	st := NewFile(hrecs).Stats()
+	if st.Records != 5 || st.ByType[S1Data] != 2 || st.DataBytes != 28+14 {
+		fmt.Printf("bad counts: %+v\n", st)
+		t.Fail()
+	}
+	if st.MinAddress != 0 || st.MaxAddress != 0x45 || st.LargestGap != 0x38-28 {
+		fmt.Printf("bad address summary: %+v\n", st)
+		t.Fail()
+	}
+
+	f := NewFile(hrecs)
+	if a, ok := f.StartAddress(); !ok || a != 0 {
+		fmt.Println("bad start address")
+		t.Fail()
+	}
+	if c, ok := f.RecordCount(); !ok || c != 2 {
+		fmt.Println("bad record count")
+		t.Fail()
+	}
+
+	base, data := f.DataBytes()
+	if base != 0 || len(data) != 0x46 || data[28] != 0xFF || data[0x38] != 'H' {
+		fmt.Println("bad data bytes")
+		t.Fail()
+	}
+	if len(f.Segments()) != 2 {
+		fmt.Println("expected 2 data segments")
+		t.Fail()
+	}
+}
+
+func TestDecodeRecordString(t *testing.T) {
+	fmt.Println("TestDecodeRecordString()")
+
+	if _, err := DecodeRecordString("S9030000FC"); err != nil {
+		fmt.Println("\t", err)
+		t.Fail()
+	}
+
+	for _, s := range []string{"", "S", "S9", "S903", "S4030000FC", "S30500"} {
+		if _, err := DecodeRecordString(s); err == nil {
+			fmt.Printf("failure: %q accepted\n", s)
+			t.Fail()
+		}
+	}
+}
+
+func FuzzDecodeRecordString(f *testing.F) {
+	f.Add("S00F000068656C6C6F202020202000003C")
+	f.Add("S111003848656C6C6F20776F726C642E0A0042")
+	f.Add("S9030000FC")
+	f.Fuzz(func(t *testing.T, s string) {
+		DecodeRecordString(s)
+	})
+}
+
+func FuzzParseBytes(f *testing.F) {
+	f.Add([]byte("S111003848656C6C6F20776F726C642E0A0042\nS9030000FC\n"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		ParseBytes(b)
+	})
+}
+
+func TestDecoderRecords(t *testing.T) {
+	fmt.Println("TestDecoderRecords()")
+
+	d := NewDecoder(strings.NewReader("S5030001FB\nS9030000FC\nS9030000FF\nS5030001FB\n"))
+
+	var (
+		n    int
+		last error
+	)
+	for _, err := range d.Records() {
+		if err != nil {
+			last = err
+			continue
+		}
+		n++
+	}
+
+	if n != 2 || last == nil || d.Line() != 3 {
+		fmt.Printf("%d records, error %v at line %d\n", n, last, d.Line())
+		t.Fail()
+	}
+}
+
+func TestParseSrecType(t *testing.T) {
+	fmt.Println("TestParseSrecType()")
+
+	for _, s := range []string{"S8", "s8", "8", "S8Start", "s8start"} {
+		if typ, err := ParseSrecType(s); err != nil || typ != S8Start {
+			fmt.Printf("%q: %v, %v\n", s, typ, err)
+			t.Fail()
+		}
+	}
+	if _, err := ParseSrecType("S4"); err == nil {
+		fmt.Println("S4 accepted")
+		t.Fail()
+	}
+	if s := fmt.Sprint(S3Data); s != "S3" {
+		fmt.Printf("S3Data prints as %q\n", s)
+		t.Fail()
+	}
+}
+
+func TestSrecTypeHelpers(t *testing.T) {
+	fmt.Println("TestSrecTypeHelpers()")
+
+	for _, m := range []AddrMode{Addr16, Addr24, Addr32} {
+		if d, s := m.DataType(), m.StartType(); !d.IsData() || s.IsData() ||
+			d.AddrMode() != m || s.AddrMode() != m {
+			fmt.Printf("mode %d: data %v, start %v\n", m, d, s)
+			t.Fail()
+		}
+	}
+}
+
+func TestNewRecs(t *testing.T) {
+	fmt.Println("TestNewRecs()")
+
+	if _, err := NewDataRec(Addr16, 0x10000, []byte{1}); err == nil {
+		fmt.Println("17-bit address accepted in an S1 record")
+		t.Fail()
+	}
+	if _, err := NewDataRec(Addr32, 0, make([]byte, 251)); err == nil {
+		fmt.Println("oversized S3 record accepted")
+		t.Fail()
+	}
+	if r, err := NewCountRec(70000); err != nil || r.RecordType != S6Count {
+		fmt.Println("count record:", r, err)
+		t.Fail()
+	}
+	if r, err := NewStartRec(Addr24, 0x123456); err != nil || r.RecordType != S8Start {
+		fmt.Println("start record:", r, err)
+		t.Fail()
+	}
+}
+
+func TestFileFields(t *testing.T) {
+	fmt.Println("TestFileFields()")
+
+	recs, err := ParseBytes([]byte(`S00F000068656C6C6F202020202000003C
+S11F00007C0802A6900100049421FFF07C6C1B787C8C23783C6000003863000026
+S5030001FB
+S9030000FC
+`))
+	if err != nil {
+		fmt.Println(err)
+		t.FailNow()
+	}
+
+	f := NewFile(recs)
+	if len(f.Data) != 1 || len(f.Data[0].Data) != 28 || len(f.Header) != 12 ||
+		f.Count == nil || *f.Count != 1 || f.Start == nil || *f.Start != 0 || len(f.Other) != 0 {
+		fmt.Printf("%+v\n", f)
+		t.Fail()
+	}
+}
+
+func TestDecoderArena(t *testing.T) {
+	fmt.Println("TestDecoderArena()")
+
+	var out strings.Builder
+	w := NewWriter(&out, Addr32)
+	w.SetCountEmit()
+	for i := 0; i < 5000; i++ {
+		w.Write([]byte{byte(i), byte(i >> 8), 0xA5})
+	}
+	w.Close()
+
+	want, err := ReadAll(strings.NewReader(out.String()))
+	if err != nil {
+		fmt.Println(err)
+		t.FailNow()
+	}
+
+	d := NewDecoder(strings.NewReader(out.String()))
+	d.SetArena(4096)
+	got, err := d.DecodeAll()
+	if err != nil || !reflect.DeepEqual(got, want) {
+		fmt.Println("arena decode differs:", err)
+		t.Fail()
+	}
+}
+
+func TestDecodeBatchAllocs(t *testing.T) {
+	fmt.Println("TestDecodeBatchAllocs()")
+
+	lines := []string{"S111003848656C6C6F20776F726C642E0A0042", "S9030000FC"}
+	out := make([]HexRec, len(lines))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := DecodeBatch(lines, out); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		fmt.Printf("%v allocations per batch\n", allocs)
+		t.Fail()
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	fmt.Println("TestWriteFile()")
+
+	m := image.New()
+	m.Write(0x12000, []byte("firmware"))
+	fn := filepath.Join(t.TempDir(), "fw.srec")
+
+	if err := WriteFile(fn, m, Options{Header: []byte("fw"), EmitStart: true}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(f.Header) != "fw" || f.Start == nil || image.RequireEqual(f.Image(), m) != nil {
+		fmt.Printf("unexpected file: %+v\n", f)
+		t.Fail()
+	}
+
+	// Invalid options must leave the existing file alone
+	if err := WriteFile(fn, m, Options{Width: 1000}); err == nil {
+		fmt.Println("bad width not detected")
+		t.Fail()
+	}
+	if _, err := Open(fn); err != nil {
+		fmt.Println("file clobbered:", err)
+		t.Fail()
+	}
+}
+
+func TestTrailingRecords(t *testing.T) {
+	fmt.Println("TestTrailingRecords()")
+
+	const input = "S1040000AA51\nS9030000FC\nS1040001BB3F\n"
+
+	d := NewDecoder(strings.NewReader(input))
+	recs, err := d.DecodeAll()
+	if err != nil || len(recs) != 3 || d.Trailing() != 1 {
+		fmt.Println("lenient:", len(recs), d.Trailing(), err)
+		t.Fail()
+	}
+
+	d.Reset(strings.NewReader(input))
+	d.SetRejectTrailing(true)
+	if _, err := d.DecodeAll(); !errors.Is(err, ErrAfterEnd) || d.Line() != 3 {
+		fmt.Println("strict:", d.Line(), err)
+		t.Fail()
+	}
+}
+
+func TestStrictOrder(t *testing.T) {
+	fmt.Println("TestStrictOrder()")
+
+	const (
+		hdr   = "S0030000FC\n"
+		data  = "S1040000AA51\n"
+		count = "S5030001FB\n"
+		start = "S9030000FC\n"
+	)
+	for input, want := range map[string]string{
+		hdr + data + count + start: "",
+		data + start:               "",
+		data + hdr + start:         "header record on line 2 not first",
+		hdr + hdr + start:          "second header record on line 2, the first on line 1",
+		data + count + data:        "data record on line 3 after the count record on line 2",
+		data + start + count:       "count record on line 3 after the start record on line 2",
+		hdr + data:                 "no start record at the end of the input",
+	} {
+		d := NewDecoder(strings.NewReader(input))
+		d.SetStrictOrder(true)
+		_, err := d.DecodeAll()
+		if want == "" && err != nil || want != "" && (!errors.Is(err, ErrOrder) || !strings.HasSuffix(err.Error(), want)) {
+			fmt.Printf("%q: %v\n", input, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	fmt.Println("TestStripMetadata()")
+
+	recs, err := ParseBytes([]byte("S00600004844521B\nS30900001000AABBCCDDD8\nS5030001FB\nS70500000000FA\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := StripMetadata(recs)
+	if len(got) != 1 || got[0].RecordType != S1Data || got[0].Address != 0x1000 || len(got[0].Data) != 4 {
+		fmt.Println("stripped:", got)
+		t.Fail()
+	}
+
+	m := image.New()
+	m.Write(0x123456, []byte{1, 2})
+	var sb strings.Builder
+	if err := m.Encode(&sb, image.SRecord, image.WithDataOnly()); err != nil {
+		t.Fatal(err)
+	}
+	if want := "S20612345601025a\n"; sb.String() != want {
+		fmt.Printf("data only encoding %q, want %q\n", sb.String(), want)
+		t.Fail()
+	}
+}
+
+func TestEmptyData(t *testing.T) {
+	fmt.Println("TestEmptyData()")
+
+	const input = "S1040000AA51\nS1031000EC\nS9030000FC\n"
+	for p, want := range map[EmptyPolicy]int{EmptyPreserve: 3, EmptyDrop: 2, EmptyReject: -1} {
+		d := NewDecoder(strings.NewReader(input))
+		d.SetEmptyData(p)
+		recs, err := d.DecodeAll()
+		if want < 0 && !errors.Is(err, ErrEmptyData) || want >= 0 && (err != nil || len(recs) != want) {
+			fmt.Println("decode with policy", p, len(recs), err)
+			t.Fail()
+		}
+	}
+
+	for p, want := range map[EmptyPolicy]string{
+		EmptyPreserve: "S1031000ec\n",
+		EmptyDrop:     "",
+	} {
+		var sb strings.Builder
+		x := NewWriter(&sb, Addr16)
+		x.SetEmptyData(p)
+		if err := x.WriteSegment(image.Segment{Address: 0x1000}); err != nil {
+			t.Fatal(err)
+		}
+		x.Close()
+		if sb.String() != want {
+			fmt.Printf("write with policy %d: %q\n", p, sb.String())
+			t.Fail()
+		}
+	}
+
+	x := NewWriter(io.Discard, Addr16)
+	x.SetEmptyData(EmptyReject)
+	if err := x.WriteSegment(image.Segment{Address: 0x1000}); !errors.Is(err, ErrEmptyData) {
+		fmt.Println("empty segment accepted:", err)
+		t.Fail()
+	}
+}
+
+func TestIntegrity(t *testing.T) {
+	fmt.Println("TestIntegrity()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb, Addr16)
+	x.SetHeader([]byte("hdr"))
+	x.SetIntegrity(checksum.SHA256)
+	x.SetCountEmit()
+	x.SetStartAddress(0)
+	x.Write([]byte("integrity trailer"))
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := ReadAll(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := NewFile(recs); string(f.Header) != "hdr" || len(f.Other) != 1 {
+		fmt.Printf("header %q, other %v\n", f.Header, f.Other)
+		t.Fail()
+	}
+
+	d := NewDecoder(strings.NewReader(sb.String()))
+	d.SetVerifyIntegrity(true)
+	d.SetStrictOrder(true)
+	if _, err := d.DecodeAll(); err != nil {
+		fmt.Println(err)
+		t.Fail()
+	}
+
+	// Drop the second data record
+	lines := strings.Split(sb.String(), "\n")
+	lines = append(lines[:2], lines[3:]...)
+	d = NewDecoder(strings.NewReader(strings.Join(lines, "\n")))
+	d.SetVerifyIntegrity(true)
+	if _, err := d.DecodeAll(); !errors.Is(err, ErrIntegrity) {
+		fmt.Println("dropped record accepted:", err)
+		t.Fail()
+	}
+}
+
+func TestFixedRecords(t *testing.T) {
+	fmt.Println("TestFixedRecords()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb, Addr16)
+	x.SetWidth(4)
+	x.SetFixedRecords(0)
+	x.SetEmptyData(EmptyPreserve)
+	if err := x.WriteSegment(image.Segment{Address: 0x1002, Data: []byte{1, 2, 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.WriteSegment(image.Segment{Address: 0x2000}); err != nil {
+		t.Fatal(err)
+	}
+	x.Close()
+
+	want := "S107100000000102e5\nS107100403000000e1\n"
+	if sb.String() != want {
+		fmt.Printf("got %q, want %q\n", sb.String(), want)
+		t.Fail()
+	}
+	if o := x.Options(); !o.FixedRecords || x.CloneTo(io.Discard).Options().Fill != 0 {
+		fmt.Println("fixed records not in the options")
+		t.Fail()
+	}
+
+	// A padded Flush mid-stream leaves the following data in place
+	sb.Reset()
+	x = NewWriter(&sb, Addr16)
+	x.SetWidth(4)
+	x.SetFixedRecords(0)
+	x.SetCloseChecks(true)
+	x.Write([]byte{1, 2, 3})
+	x.Flush()
+	x.Write([]byte{4, 5, 6})
+	if err := x.Close(); err != nil {
+		fmt.Println("close after padded flush:", err)
+		t.Fail()
+	}
+	if x.Accepted() != 6 || x.Emitted() != 6 {
+		fmt.Printf("accepted %d, emitted %d\n", x.Accepted(), x.Emitted())
+		t.Fail()
+	}
+	recs, err := ReadAll(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addrs []uint32
+	for _, r := range recs {
+		if r.RecordType == S1Data {
+			addrs = append(addrs, r.Address)
+		}
+	}
+	if fmt.Sprint(addrs) != "[0 3]" {
+		fmt.Printf("data after padded flush:\n%s", sb.String())
+		t.Fail()
+	}
+}
+
+func TestReader(t *testing.T) {
+	fmt.Println("TestReader()")
+
+	r := NewReader(strings.NewReader("S00600004844521B\n\nS1040000AA51\nS9030000FC\n"))
+	var types []SrecType
+	for r.Scan() {
+		types = append(types, r.Record().RecordType)
+	}
+	if r.Err() != nil || fmt.Sprint(types) != fmt.Sprint([]SrecType{S0Header, S1Data, S9Start}) || r.Line() != 4 {
+		fmt.Println(types, r.Line(), r.Err())
+		t.Fail()
+	}
+
+	r = NewReader(strings.NewReader("S1040000AA51\nS1040001AA51\n"))
+	for r.Scan() {
+	}
+	if r.Err() == nil || r.Line() != 2 || r.Scan() {
+		fmt.Println("bad checksum on line", r.Line(), r.Err())
+		t.Fail()
+	}
+}
+
+func TestExtract(t *testing.T) {
+	fmt.Println("TestExtract()")
+
+	recs, err := ReadAll(strings.NewReader("S00600004844521B\nS1040000AA51\nS3090001000001020304eb\nS9030000FC\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := Extract(recs, 0xFFFE, 5, 0xFF); !bytes.Equal(b, []byte{0xFF, 0xFF, 1, 2, 3}) {
+		fmt.Printf("extracted % X\n", b)
+		t.Fail()
+	}
+	if b := Extract(recs, 0, 2, 0); !bytes.Equal(b, []byte{0xAA, 0}) {
+		fmt.Printf("extracted % X\n", b)
+		t.Fail()
+	}
+}
+
+func TestWriteBinary(t *testing.T) {
+	fmt.Println("TestWriteBinary()")
+
+	recs, err := ReadAll(strings.NewReader("S00600004844521B\nS1040000AA51\nS10500030102f4\nS9030000FC\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if base, err := WriteBinary(&buf, recs, 0xFF); err != nil || base != 0 || !bytes.Equal(buf.Bytes(), []byte{0xAA, 0xFF, 0xFF, 1, 2}) {
+		fmt.Printf("wrote % X at 0x%X, %v\n", buf.Bytes(), base, err)
+		t.Fail()
+	}
+
+	fn := filepath.Join(t.TempDir(), "fw.bin")
+	if _, err := WriteBinaryFile(fn, recs, 0); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(fn); err != nil || !bytes.Equal(b, []byte{0xAA, 0, 0, 1, 2}) {
+		fmt.Printf("file holds % X, %v\n", b, err)
+		t.Fail()
+	}
+}
+
+func TestLineEnding(t *testing.T) {
+	fmt.Println("TestLineEnding()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb, Addr16)
+	x.SetLineEnding(CRLF)
+	x.Write([]byte("CRLF terminated"))
+	x.Close()
+
+	out := sb.String()
+	if n := strings.Count(out, "\n"); n != 2 || strings.Count(out, "\r\n") != n {
+		fmt.Printf("records not CRLF terminated: %q\n", out)
+		t.Fail()
+	}
+	recs, err := ReadAll(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := NewFile(recs).Image().Extract(0, 15, 0); string(got) != "CRLF terminated" {
+		fmt.Printf("read back %q\n", got)
+		t.Fail()
+	}
+
+	clone := x.CloneTo(io.Discard)
+	if clone.Options().LineEnding != CRLF {
+		fmt.Println("line ending not cloned")
+		t.Fail()
+	}
+
+	o := x.Options()
+	o.LineEnding = "\n;"
+	if o.Validate() == nil {
+		fmt.Println("printable line ending accepted")
+		t.Fail()
+	}
+}
+
+func TestWriterOptions(t *testing.T) {
+	fmt.Println("TestWriterOptions()")
+
+	var sb strings.Builder
+	x := NewWriter(&sb, Addr16, WithWidth(4), WithHeader([]byte("hdr")),
+		WithStartAddress(0x10), WithCountRecord(), WithUppercase())
+	x.Write([]byte("options!"))
+	x.Close()
+
+	out := sb.String()
+	if out != strings.ToUpper(out) {
+		fmt.Printf("lower case digits:\n%s", out)
+		t.Fail()
+	}
+	recs, err := ReadAll(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []SrecType
+	for _, r := range recs {
+		types = append(types, r.RecordType)
+	}
+	if !reflect.DeepEqual(types, []SrecType{S0Header, S1Data, S1Data, S5Count, S9Start}) {
+		fmt.Printf("unexpected output:\n%s", out)
+		t.Fail()
+	}
+
+	y := NewWriter(io.Discard, Addr32, WithOptions(x.Options()), WithWidth(8))
+	if o := y.Options(); o.AddrMode != Addr16 || o.Width != 8 || !o.Uppercase || !o.EmitCount {
+		fmt.Printf("options not carried over: %+v\n", o)
+		t.Fail()
+	}
+}
+
+func TestLayout(t *testing.T) {
+	fmt.Println("TestLayout()")
+
+	recs := []*HexRec{
+		{RecordType: S0Header, Data: []byte("hdr")},
+		{RecordType: S1Data, Address: 0x1000, Data: make([]byte, 16)},
+		{RecordType: S1Data, Address: 0x1020, Data: make([]byte, 16)},
+		{RecordType: S3Data, Address: 0x102F, Data: make([]byte, 2)},
+		{RecordType: S9Start},
+	}
+	want := []image.Span{
+		{Start: 0x1010, End: 0x1020, Kind: image.Gap},
+		{Start: 0x102F, End: 0x1030, Kind: image.Overlap},
+	}
+	if got := Layout(recs); !reflect.DeepEqual(got, want) {
+		fmt.Printf("failure: got %v\n", got)
+		t.Fail()
+	}
+}
+
+func TestValidate(t *testing.T) {
+	fmt.Println("TestValidate()")
+
+	text := "S1050010414267\nS1050010414268\n\ngarbage\r\nS9030000FC\n"
+	recs, errs, err := Validate(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 || len(errs) != 2 ||
+		errs[0].Line != 2 || errs[0].Offset != 15 || errs[0].RawRecord != "S1050010414268" ||
+		errs[1].Line != 4 || errs[1].Offset != 31 || errs[1].RawRecord != "garbage" {
+		fmt.Printf("failure: %d records, errors %+v\n", len(recs), errs)
+		t.Fail()
+	}
+
+	d := NewDecoder(strings.NewReader("S1050010414267\n"))
+	d.SetStrictOrder(true)
+	_, errs, err = d.Validate()
+	if err != nil || len(errs) != 1 || errs[0].Line != 2 || errs[0].Offset != 15 || !errors.Is(errs[0], ErrOrder) {
+		fmt.Printf("failure: errors %+v, %v\n", errs, err)
+		t.Fail()
+	}
+}
+
+func TestEncodeStart(t *testing.T) {
+	fmt.Println("TestEncodeStart()")
+
+	m := image.New()
+	m.Write(0x100, []byte{1, 2, 3, 4})
+
+	var buf bytes.Buffer
+	if err := m.Encode(&buf, image.SRecord); err != nil || buf.String() != "S107010001020304ed\nS9030000fc\n" {
+		fmt.Printf("default start: %q, %v\n", buf.String(), err)
+		t.Fail()
+	}
+
+	// The start address lies beyond the data, and needs S2 records
+	buf.Reset()
+	if err := m.Encode(&buf, image.SRecord, image.WithStart(0x12345)); err != nil ||
+		buf.String() != "S20800010001020304ec\nS80401234592\n" {
+		fmt.Printf("start: %q, %v\n", buf.String(), err)
+		t.Fail()
+	}
+
+	buf.Reset()
+	if err := m.Encode(&buf, image.SRecord, image.WithStart(0x12345), image.WithDataOnly()); err != nil ||
+		strings.Contains(buf.String(), "S8") || strings.Contains(buf.String(), "S9") {
+		fmt.Printf("data only: %q, %v\n", buf.String(), err)
+		t.Fail()
+	}
+}
+
+func TestEncodeWidthRange(t *testing.T) {
+	fmt.Println("TestEncodeWidthRange()")
+
+	m := image.New()
+	m.Write(0, make([]byte, 300))
+
+	// S1 records hold at most 252 data bytes
+	if err := m.Encode(io.Discard, image.SRecord, image.WithWidth(252)); err != nil {
+		fmt.Println("width 252:", err)
+		t.Fail()
+	}
+	for _, w := range []int{253, 300} {
+		if err := m.Encode(io.Discard, image.SRecord, image.WithWidth(w)); err == nil {
+			fmt.Println("encoded with width", w)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package srec
+
+import "io"
+
+// Reader parses S-Records incrementally in the manner of bufio.Scanner:
+// each call to Scan decodes the next record, made available by Record,
+// until the input ends or an error occurs.  Only the current record is
+// held, so files of hundreds of megabytes, such as FPGA bitstreams, are
+// parsed in constant memory.
+//
+//	r := srec.NewReader(f)
+//	for r.Scan() {
+//		rec := r.Record()
+//		...
+//	}
+//	if err := r.Err(); err != nil {
+//		...
+//	}
+type Reader struct {
+	d   *Decoder
+	rec *HexRec
+	err error
+}
+
+// NewReader creates a Reader parsing the S-Records read from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{d: NewDecoder(r)}
+}
+
+// Decoder returns the decoder underlying r, to configure it before the
+// first Scan, e.g. with SetChecksum or SetStrictOrder
+func (r *Reader) Decoder() *Decoder {
+	return r.d
+}
+
+// Scan advances to the next record, skipping blank lines.  It returns
+// false at the end of the input or on the first error, which Err then
+// reports.
+func (r *Reader) Scan() bool {
+	if r.err != nil {
+		return false
+	}
+	r.rec, r.err = r.d.Decode()
+	if r.err != nil {
+		r.rec = nil
+		return false
+	}
+	return true
+}
+
+// Record returns the record decoded by the last successful Scan
+func (r *Reader) Record() *HexRec {
+	return r.rec
+}
+
+// Line returns the line number of the current record, or of the line
+// that failed to decode
+func (r *Reader) Line() int {
+	return r.d.Line()
+}
+
+// Err returns the first error Scan met, nil if it only reached the end
+// of the input
+func (r *Reader) Err() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
+}
@@ -0,0 +1,89 @@
+package srec
+
+import (
+	"fmt"
+)
+
+// NewDataRec creates an S1, S2 or S3 data record, as selected by mode,
+// holding data at addr
+func NewDataRec(mode AddrMode, addr uint32, data []byte) (*HexRec, error) {
+	return newRec(mode.DataType(), addr, data)
+}
+
+// NewHeaderRec creates an S0 header record with content h
+func NewHeaderRec(h []byte) (*HexRec, error) {
+	return newRec(S0Header, 0, h)
+}
+
+// NewCountRec creates a count record for n data records: an S5 record,
+// or an S6 record if n does not fit in 16 bits
+func NewCountRec(n uint32) (*HexRec, error) {
+	if n > 0xFFFF {
+		return newRec(S6Count, n, nil)
+	}
+	return newRec(S5Count, n, nil)
+}
+
+// NewStartRec creates an S9, S8 or S7 start record, as selected by mode,
+// for entry point addr
+func NewStartRec(mode AddrMode, addr uint32) (*HexRec, error) {
+	return newRec(mode.StartType(), addr, nil)
+}
+
+func newRec(t SrecType, addr uint32, data []byte) (*HexRec, error) {
+	r := &HexRec{Address: addr, RecordType: t, Data: data}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Validate checks that the record could appear in a conforming file:
+// the record type is known, the address fits the record's address field,
+// only S0 and data records carry data, and the byte count stays within
+// 255.
+func (r *HexRec) Validate() error {
+	if _, ok := srecStrMap[r.RecordType]; !ok {
+		return fmt.Errorf("unknown record type %v", r.RecordType)
+	}
+
+	n := addrSize(r.RecordType)
+	if n < 4 && r.Address>>(8*n) != 0 {
+		return fmt.Errorf("address 0x%X exceeds the %d-bit field of an %v record",
+			r.Address, 8*n, r.RecordType)
+	}
+
+	if len(r.Data) > 0 && r.RecordType != S0Header && !r.RecordType.IsData() {
+		return fmt.Errorf("%v record cannot carry data", r.RecordType)
+	}
+
+	// The byte count covers address, data and checksum
+	if limit := 255 - n - 1; len(r.Data) > limit {
+		return fmt.Errorf("%d data bytes exceed the %d byte limit of an %v record",
+			len(r.Data), limit, r.RecordType)
+	}
+	return nil
+}
+
+// StripMetadata reduces the records of a file to its data records, for
+// loaders that accept nothing else and for scrubbing build metadata:
+// header, count and start records are dropped.  The data records are
+// renumbered to the smallest address mode reaching the end of the data,
+// S1 where possible.  The data of recs is shared, not copied.
+func StripMetadata(recs []*HexRec) []*HexRec {
+	var end uint64
+	for _, r := range recs {
+		if r.RecordType.IsData() {
+			end = max(end, uint64(r.Address)+uint64(len(r.Data)))
+		}
+	}
+
+	mode := AddrModeReaching(end)
+	var out []*HexRec
+	for _, r := range recs {
+		if r.RecordType.IsData() {
+			out = append(out, &HexRec{Address: r.Address, RecordType: mode.DataType(), Data: r.Data})
+		}
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+package srec
+
+import (
+	"github.com/peteArnt/GoHexIO/internal/stats"
+)
+
+// Stats summarizes the content of an S-Record file
+type Stats struct {
+	Records    int              // Total number of records
+	ByType     map[SrecType]int // Record counts keyed by record type
+	DataBytes  int              // Total number of data bytes in S1/S2/S3 records
+	MinAddress uint32           // Lowest data address
+	MaxAddress uint32           // Highest data address (inclusive)
+	LargestGap uint32           // Largest run of unused addresses between data
+}
+
+// Stats gathers record counts, address bounds and gap information in a
+// single pass over the file.
+func (f *File) Stats() Stats {
+	t := stats.Tally[SrecType]{ByType: make(map[SrecType]int)}
+
+	for _, r := range f.recs {
+		t.Record(r.RecordType)
+		switch r.RecordType {
+		case S1Data, S2Data, S3Data:
+			t.Data(r.Address, len(r.Data))
+		}
+	}
+
+	st := Stats{Records: t.Records, ByType: t.ByType, DataBytes: t.DataBytes}
+	st.MinAddress, st.MaxAddress, st.LargestGap = t.Bounds()
+	return st
+}
@@ -0,0 +1,53 @@
+package srec
+
+import (
+	"fmt"
+	"io"
+)
+
+// ParseError describes one problem found by Validate
+type ParseError struct {
+	Line      int    // Line number, counting from 1
+	Offset    int64  // Byte offset of the line within the input
+	Reason    error  // What is wrong
+	RawRecord string // The line as read, without its line ending
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Reason)
+}
+
+func (e ParseError) Unwrap() error {
+	return e.Reason
+}
+
+// Validate decodes the rest of the input like DecodeAll, but carries on
+// past bad records, so a corrupted file can be repaired in one pass: it
+// returns the records that decoded and, in input order, a ParseError for
+// every one that did not.  Problems with the input as a whole, such as a
+// missing start record under SetStrictOrder, are reported for the line
+// after the last.  err is reserved for failures reading the input.
+func (d *Decoder) Validate() (recs []*HexRec, errs []ParseError, err error) {
+	for {
+		hr, err := d.Decode()
+		switch {
+		case err == io.EOF:
+			return recs, errs, nil
+		case err == nil:
+			recs = append(recs, hr)
+		case d.s.Err() != nil:
+			return recs, errs, d.s.Err()
+		case d.eof:
+			errs = append(errs, ParseError{Line: d.line + 1, Offset: d.next, Reason: err})
+			return recs, errs, nil
+		default:
+			errs = append(errs, ParseError{Line: d.line, Offset: d.off, Reason: err, RawRecord: d.s.Text()})
+		}
+	}
+}
+
+// Validate reads S-Record records from r like ReadAll, collecting every
+// problem instead of stopping at the first; see Decoder.Validate
+func Validate(r io.Reader, opts ...DecoderOption) ([]*HexRec, []ParseError, error) {
+	return NewDecoder(r, opts...).Validate()
+}
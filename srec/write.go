@@ -2,10 +2,22 @@ package srec
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peteArnt/GoHexIO/checksum"
+	"github.com/peteArnt/GoHexIO/image"
+	"github.com/peteArnt/GoHexIO/internal/atomicfile"
+	"github.com/peteArnt/GoHexIO/internal/hexenc"
+	"github.com/peteArnt/GoHexIO/internal/integrity"
 )
 
 // AddrMode is a data type used for Address Mode enumerations
@@ -18,6 +30,42 @@ const (
 	Addr32 AddrMode = 32
 )
 
+// DataType returns the data record type used in address mode m: S1, S2
+// or S3
+func (m AddrMode) DataType() SrecType {
+	switch m {
+	case Addr24:
+		return S2Data
+	case Addr32:
+		return S3Data
+	}
+	return S1Data
+}
+
+// StartType returns the start record type used in address mode m: S9, S8
+// or S7
+func (m AddrMode) StartType() SrecType {
+	switch m {
+	case Addr24:
+		return S8Start
+	case Addr32:
+		return S7Start
+	}
+	return S9Start
+}
+
+// Errors reported by a Writer
+var (
+	ErrNoData = errors.New("no data written")       // Closed without having written any data
+	ErrClosed = errors.New("Writer already closed") // Used after Close
+)
+
+// Record terminators for SetLineEnding
+const (
+	LF   = "\n"   // Unix, the default
+	CRLF = "\r\n" // DOS, expected by many device programmers and PROM burners
+)
+
 // Writer implements the Motorola S-Record writer
 type Writer struct {
 	// State vars
@@ -28,6 +76,10 @@ type Writer struct {
 	tail  []byte       // post-fragment buffer
 	fifo  bytes.Buffer // Used as internal Write FIFO
 
+	check    bool  // Close checks the output for sanity
+	accepted int64 // Data bytes taken by Write
+	emitted  int64 // Data bytes serialized into data records
+
 	// Configuration vars
 	emitCountRec  bool     // Emit appropriate count record at file close
 	emitStartRec  bool     // Emit Start Record at stream close
@@ -36,11 +88,62 @@ type Writer struct {
 	width         int      // bytes per line in SREC ourput
 	header        []byte   // Header bytes
 	headerEmitted bool
+	sum           checksum.Algorithm // Record checksum algorithm
+	log           *slog.Logger       // Optional diagnostics sink
+	trace         func(HexRec)       // Optional per-record callback
+	scale         int                // Bytes per address unit, 0 taken as 1
+	empty         EmptyPolicy        // Handling of empty segments
+	fixed         bool               // Pad every data record to width
+	fill          byte               // Value of the padding bytes
+	integrity     checksum.Digest    // Digest of the integrity trailer, "" for none
+	digest        *integrity.Sum     // Digest of the records written so far
+	eol           string             // Record terminator, "" for "\n"
+	upper         bool               // Upper case hex digits
+	line          []byte             // Scratch space for the ASCII record
+}
+
+// NewWriter creates a new SREC writer, configured by opts on top of the
+// defaults
+func NewWriter(w io.Writer, aMode AddrMode, opts ...Option) *Writer {
+	x := &Writer{w: w, width: 10, addrMode: aMode, sum: checksum.OnesComplement}
+	for _, o := range opts {
+		o(x)
+	}
+	return x
+}
+
+// Reset discards any buffered data and makes the writer start over on w
+// at address 0, keeping its configuration and scratch buffers.  The
+// header record is emitted again and the record count restarts.
+func (x *Writer) Reset(w io.Writer) {
+	x.w = w
+	x.addr = 0
+	x.count = 0
+	x.fin = false
+	x.tail = nil
+	x.fifo.Reset()
+	x.headerEmitted = false
+	x.accepted, x.emitted = 0, 0
+	x.digest = integrity.NewSum(x.integrity)
+}
+
+// Accepted returns the number of data bytes taken by Write since the
+// writer was created or Reset, whether or not they are in records yet
+func (x *Writer) Accepted() int64 {
+	return x.accepted
+}
+
+// Emitted returns the number of data bytes serialized into data records
+// since the writer was created or Reset.  Accepted less Emitted is the
+// data still buffered, waiting for a full record or a Flush.
+func (x *Writer) Emitted() int64 {
+	return x.emitted
 }
 
-// NewWriter creates a new, default SREC writer
-func NewWriter(w io.Writer, aMode AddrMode) *Writer {
-	return &Writer{w: w, width: 10, addrMode: aMode}
+// Buffered returns the number of data bytes held back waiting for a full
+// record or a Flush
+func (x *Writer) Buffered() int {
+	return x.fifo.Len()
 }
 
 // SetStartAddress enables emitting a Start Record as the terminating record before Close()
@@ -75,31 +178,168 @@ func (x *Writer) SetHeader(h []byte) {
 	x.header = h
 }
 
+// SetAutoHeader makes the writer emit a header identifying the tool
+// and version generating the file, stamped with BuildTime
+func (x *Writer) SetAutoHeader(tool, version string) {
+	x.header = AutoHeader(tool, version, BuildTime())
+}
+
+// AutoHeader returns header content identifying the tool that generates
+// a file, of the form "tool version timestamp" with the timestamp in
+// UTC and RFC 3339 format.  Content beyond the 252 bytes a header record
+// holds is cut off.
+func AutoHeader(tool, version string, t time.Time) []byte {
+	h := strings.Join(strings.Fields(tool+" "+version), " ") + " " + t.UTC().Format(time.RFC3339)
+	return []byte(strings.TrimSpace(h[:min(len(h), 252)]))
+}
+
+// BuildTime returns the time to stamp headers with: the time given in
+// seconds since the Unix epoch by the SOURCE_DATE_EPOCH environment
+// variable, the convention for reproducible builds, if set and valid,
+// otherwise the current time
+func BuildTime() time.Time {
+	if s, ok := os.LookupEnv("SOURCE_DATE_EPOCH"); ok {
+		if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(sec, 0)
+		}
+	}
+	return time.Now()
+}
+
+// SetAddressScale makes addresses in the output count units of n bytes,
+// e.g. 2 for word-addressed DSPs whose programmers expect word
+// addresses.  SetAddress and SetStartAddress take file addresses;
+// WriteSegment and WriteImage take byte addresses and divide them by n.
+// Data records, and hence the record width, must then hold a multiple of
+// n bytes.
+func (x *Writer) SetAddressScale(n int) {
+	x.scale = n
+}
+
+// unit returns the number of bytes per address unit
+func (x *Writer) unit() int {
+	return max(x.scale, 1)
+}
+
+// SetCloseChecks makes Close fail with a descriptive error when the
+// output is suspect: no data was written, wrapping ErrNoData, a start
+// address was given without any data, or buffered data could not be
+// flushed.  Such output is then left without its terminating records.
+func (x *Writer) SetCloseChecks(on bool) {
+	x.check = on
+}
+
+// SetEmptyData selects what WriteSegment makes of a segment without data:
+// a zero-length data record at its address, the default, nothing, or an
+// error wrapping ErrEmptyData
+func (x *Writer) SetEmptyData(p EmptyPolicy) {
+	x.empty = p
+}
+
+// SetIntegrity makes Close write an integrity trailer before the count
+// and start records: an S0 record whose content, such as
+// "GoHexIO CRC32:1a2b3c4d", carries digest d of every record before it.
+// Decoders verify it under SetVerifyIntegrity; other tools take it for a
+// second header.  "" writes none.
+func (x *Writer) SetIntegrity(d checksum.Digest) {
+	x.integrity = d
+	x.digest = integrity.NewSum(d)
+}
+
+// SetFixedRecords makes every data record carry exactly the record
+// width, for ROM emulators that choke on runt records: the data a Flush
+// leaves short of a full record is padded with fill, though later data
+// still follows on from the end of the data, and WriteImage and
+// WriteSegment pad the data to whole records aligned to multiples of the
+// width.  Segments without data write nothing unless SetEmptyData rejects
+// them.
+func (x *Writer) SetFixedRecords(fill byte) {
+	x.fixed = true
+	x.fill = fill
+}
+
+// SetLineEnding sets the terminator written after each record, e.g.
+// CRLF for device programmers that insist on DOS line endings.  The
+// readers of this package split records at line feeds, accepting LF and
+// CRLF alike, so a bare "\r" is for other tools only.  "" restores the
+// default LF.
+func (x *Writer) SetLineEnding(eol string) {
+	x.eol = eol
+}
+
+// SetChecksum selects the record checksum algorithm, for target tools
+// that deviate from the Motorola standard
+func (x *Writer) SetChecksum(a checksum.Algorithm) {
+	x.sum = a
+}
+
+// Generic emit-record; b holds the byte count, address and data fields
+func (x *Writer) emitRecord(t SrecType, b []byte) error {
+	// Calculate checksum, append checksum to buffer
+	b = append(b, x.sum.Sum(b))
+
+	// Create ASCII representation w/record header in the reused line
+	// buffer
+	x.line = append(x.line[:0], srecStrMap[t]...)
+	if x.upper {
+		x.line = hexenc.AppendUpper(x.line, b)
+	} else {
+		x.line = hex.AppendEncode(x.line, b)
+	}
+	if x.eol == "" {
+		x.line = append(x.line, '\n')
+	} else {
+		x.line = append(x.line, x.eol...)
+	}
+
+	_, err := x.w.Write(x.line)
+	if err != nil {
+		return err
+	}
+
+	if x.trace != nil || x.digest != nil {
+		n := addrSize(t)
+		var a uint32
+		for _, v := range b[1 : 1+n] {
+			a = a<<8 | uint32(v)
+		}
+		if x.digest != nil {
+			x.digest.Add(byte(t), a, b[1+n:len(b)-1])
+		}
+		if x.trace != nil {
+			x.trace(HexRec{Address: a, RecordType: t, Data: b[1+n : len(b)-1]})
+		}
+	}
+
+	return nil
+}
+
 func (x *Writer) emitHeaderRecord() error {
 	var binBuf bytes.Buffer
 
 	binBuf.WriteByte(byte(len(x.header)) + 3)
 	binBuf.Write([]byte{0, 0})
 
-	// Add data bytes, calculate checksum, append checksum to buffer
+	// Add data bytes
 	binBuf.Write(x.header)
-	binBuf.WriteByte(calcChecksum(binBuf.Bytes()))
-
-	// Create ASCII representation w/record header
-	asciiBuf := fmt.Sprintf("S0%s", hex.EncodeToString(binBuf.Bytes()))
 
-	_, err := fmt.Fprintln(x.w, asciiBuf)
+	err := x.emitRecord(S0Header, binBuf.Bytes())
 	if err != nil {
 		return err
 	}
 
+	x.logger().Debug("srec: emitted header record", "length", len(x.header))
 	return nil
 }
 
 func (x *Writer) emitDataRecord(p []byte) error {
+	if len(p)%x.unit() != 0 {
+		return fmt.Errorf("%d bytes are not a multiple of the address scale %d", len(p), x.unit())
+	}
+
 	var (
 		binBuf bytes.Buffer
-		recTyp byte
+		recTyp SrecType
 		addr   = bigEndianBin(x.addr)
 	)
 
@@ -109,46 +349,60 @@ func (x *Writer) emitDataRecord(p []byte) error {
 		// can be calculated
 		binBuf.WriteByte(byte(len(p)) + 3) // Length
 		binBuf.Write(addr[2:])             // 16-bit address big endian
-		recTyp = '1'
+		recTyp = S1Data
 
 	case Addr24:
 		// Construct a binary image of the record so a checksum
 		// can be calculated
 		binBuf.WriteByte(byte(len(p)) + 4) // Length
 		binBuf.Write(addr[1:])             // 24-bit address big endian
-		recTyp = '2'
+		recTyp = S2Data
 
 	case Addr32:
 		// Construct a binary image of the record so a checksum
 		// can be calculated
-		binBuf.WriteByte(byte(len(p)) + 4) // Length
+		binBuf.WriteByte(byte(len(p)) + 5) // Length
 		binBuf.Write(addr)                 // 32-bit address big endian
-		recTyp = '3'
+		recTyp = S3Data
 	}
 
-	// Add data bytes, calculate checksum, append checksum to buffer
+	// Add data bytes
 	binBuf.Write(p)
-	binBuf.WriteByte(calcChecksum(binBuf.Bytes()))
-
-	// Create ASCII representation w/record header
-	asciiBuf := fmt.Sprintf("S%c%s", recTyp, hex.EncodeToString(binBuf.Bytes()))
 
-	_, err := fmt.Fprintln(x.w, asciiBuf)
+	err := x.emitRecord(recTyp, binBuf.Bytes())
 	if err != nil {
 		return err
 	}
 
-	x.addr += uint32(len(p))
+	x.addr += uint32(len(p) / x.unit())
 	x.count++
+	x.emitted += int64(len(p))
 
 	return nil
 }
 
+// emitTrailer writes the integrity trailer, which is not itself digested
+func (x *Writer) emitTrailer() error {
+	if x.digest == nil {
+		return fmt.Errorf("unknown integrity digest %q", x.integrity)
+	}
+	b := x.digest.Trailer()
+	x.digest = nil
+
+	err := x.emitRecord(S0Header, append([]byte{byte(len(b)) + 3, 0, 0}, b...))
+	if err != nil {
+		return err
+	}
+
+	x.logger().Debug("srec: emitted integrity trailer", "digest", x.integrity)
+	return nil
+}
+
 func (x *Writer) emitCountRecord() error {
 	var (
 		binBuf  bytes.Buffer
 		bigFile = (x.count > 65535)
-		recTyp  byte
+		recTyp  SrecType
 	)
 
 	if bigFile {
@@ -156,28 +410,28 @@ func (x *Writer) emitCountRecord() error {
 		binBuf.WriteByte(3 + 1)
 		c := bigEndianBin(x.count)
 		binBuf.Write(c[1:])
-		recTyp = '6'
+		recTyp = S6Count
 	} else {
 		// length = 2 count + 1 checksum
 		binBuf.WriteByte(2 + 1)
 		c := bigEndianBin(x.count)
 		binBuf.Write(c[2:])
-		recTyp = '5'
+		recTyp = S5Count
 	}
 
-	binBuf.WriteByte(calcChecksum(binBuf.Bytes()))
-
-	// Create ASCII representation w/record header
-	asciiBuf := fmt.Sprintf("S%c%s", recTyp, hex.EncodeToString(binBuf.Bytes()))
+	err := x.emitRecord(recTyp, binBuf.Bytes())
+	if err != nil {
+		return err
+	}
 
-	_, err := fmt.Fprintln(x.w, asciiBuf)
-	return err
+	x.logger().Debug("srec: emitted count record", "type", srecStrMap[recTyp], "count", x.count)
+	return nil
 }
 
 func (x *Writer) emitStartAddrRec() error {
 	var (
 		binBuf bytes.Buffer
-		recTyp byte
+		recTyp SrecType
 		addr   = bigEndianBin(x.startAddr)
 	)
 
@@ -193,36 +447,37 @@ func (x *Writer) emitStartAddrRec() error {
 		// can be calculated
 		binBuf.WriteByte(recLen16) // Length
 		binBuf.Write(addr[2:])     // 16-bit address big endian
-		recTyp = '9'
+		recTyp = S9Start
 
 	case Addr24:
 		// Construct a binary image of the record so a checksum
 		// can be calculated
 		binBuf.WriteByte(recLen24) // Length
 		binBuf.Write(addr[1:])     // 24-bit address big endian
-		recTyp = '8'
+		recTyp = S8Start
 
 	case Addr32:
 		// Construct a binary image of the record so a checksum
 		// can be calculated
 		binBuf.WriteByte(recLen32) // Length
 		binBuf.Write(addr)         // 32-bit address big endian
-		recTyp = '7'
+		recTyp = S7Start
 	}
 
-	// Add data bytes, calculate checksum, append checksum to buffer
-	binBuf.WriteByte(calcChecksum(binBuf.Bytes()))
-
-	// Create ASCII representation w/record header
-	asciiBuf := fmt.Sprintf("S%c%s", recTyp, hex.EncodeToString(binBuf.Bytes()))
+	err := x.emitRecord(recTyp, binBuf.Bytes())
+	if err != nil {
+		return err
+	}
 
-	_, err := fmt.Fprintln(x.w, asciiBuf)
-	return err
+	x.logger().Debug("srec: emitted start record", "type", srecStrMap[recTyp],
+		"address", x.startAddr)
+	return nil
 }
 
 // Write is the idiomatic Go write function used for writing blocks of data
-// to a stream;
-func (x *Writer) Write(p []byte) (int, error) {
+// to a stream.  Data short of a full record is buffered, yet counted as
+// written; Emitted tells how much has gone into records.
+func (x *Writer) Write(p []byte) (n int, err error) {
 	var (
 		writeCount  int
 		origXferLen = len(p)
@@ -230,8 +485,9 @@ func (x *Writer) Write(p []byte) (int, error) {
 
 	// Has this writer already been closed?
 	if x.fin {
-		return 0, errors.New("Writer closed")
+		return 0, ErrClosed
 	}
+	defer func() { x.accepted += int64(n) }()
 
 	// Write out Header record if appropriate & this is THE first Write
 	if (x.header != nil) && !x.headerEmitted {
@@ -239,9 +495,22 @@ func (x *Writer) Write(p []byte) (int, error) {
 		x.emitHeaderRecord()
 	}
 
+	// Fast path: with nothing buffered, encode full width records
+	// straight from the caller's slice and only buffer the tail
+	if x.fifo.Len() == 0 {
+		for len(p) >= x.width {
+			err := x.emitDataRecord(p[:x.width])
+			if err != nil {
+				return writeCount, err
+			}
+			writeCount += x.width
+			p = p[x.width:]
+		}
+	}
+
 	// Write caller's data to an internal FIFO; there may be residual
 	// bytes left over from a previous write.
-	_, err := x.fifo.Write(p)
+	_, err = x.fifo.Write(p)
 	if err != nil {
 		return 0, err
 	}
@@ -262,29 +531,52 @@ func (x *Writer) Write(p []byte) (int, error) {
 func (x *Writer) Flush() error {
 	remaining := x.fifo.Len()
 	if remaining > 0 {
-		err := x.emitDataRecord(x.fifo.Next(remaining))
+		p := x.fifo.Next(remaining)
+		pad := 0
+		if x.fixed && remaining < x.width {
+			// Pad a copy, not the FIFO's buffer
+			pad = x.width - remaining
+			p = append(p[:remaining:remaining], bytes.Repeat([]byte{x.fill}, pad)...)
+		}
+		err := x.emitDataRecord(p)
 		if err != nil {
 			return err
 		}
+		// The padding is not data: leave the address counter at the
+		// end of the data and keep it out of the emitted count
+		x.addr -= uint32(pad / x.unit())
+		x.emitted -= int64(pad)
 	}
 	return nil
 }
 
 // Close is used to flush any buffered data to the output stream and
-// write potential termination record(s).
+// write potential termination record(s).  Closing a second time fails
+// with ErrClosed and writes nothing.
 // Note: any underlying io.Writer will NOT closed here
 func (x *Writer) Close() error {
 	if x.fin {
-		return errors.New("Writer already closed")
+		return ErrClosed
 	}
 
 	defer func() { x.fin = true }()
 
 	err := x.Flush()
+	if x.check {
+		if err := x.checkClose(err); err != nil {
+			return err
+		}
+	}
 	if err != nil {
 		return err
 	}
 
+	if x.integrity != "" {
+		if err := x.emitTrailer(); err != nil {
+			return err
+		}
+	}
+
 	if x.emitCountRec {
 		err := x.emitCountRecord()
 		if err != nil {
@@ -302,6 +594,171 @@ func (x *Writer) Close() error {
 	return nil
 }
 
+// checkClose returns the error Close reports under SetCloseChecks, given
+// the result of the final flush
+func (x *Writer) checkClose(flushErr error) error {
+	switch {
+	case flushErr != nil:
+		return fmt.Errorf("Close: %d buffered bytes could not be flushed: %w",
+			x.accepted-x.emitted, flushErr)
+	case x.emitted == 0 && x.emitStartRec:
+		return fmt.Errorf("Close: start address 0x%X set, but %w", x.startAddr, ErrNoData)
+	case x.emitted == 0:
+		return fmt.Errorf("Close: %w", ErrNoData)
+	}
+	return nil
+}
+
+// WriteImage writes the data of memory image m through the writer, each
+// segment starting a new data record at its address
+func (x *Writer) WriteImage(m *image.Image) error {
+	if x.fixed {
+		m = m.Pad(x.width, x.fill)
+	}
+	for _, s := range m.Segments() {
+		if err := x.writeSegment(s); err != nil {
+			return err
+		}
+	}
+	return x.Flush()
+}
+
+// WriteSegment writes the data of s through the writer starting at its
+// address.  Any data already buffered is flushed first, and s is flushed
+// in turn.  A segment without data is handled as set by SetEmptyData.
+func (x *Writer) WriteSegment(s image.Segment) error {
+	if !x.fixed || len(s.Data) == 0 {
+		return x.writeSegment(s)
+	}
+	m := image.New()
+	m.Write(s.Address, s.Data)
+	for _, s := range m.Pad(x.width, x.fill).Segments() {
+		if err := x.writeSegment(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSegment is WriteSegment without the padding of SetFixedRecords
+func (x *Writer) writeSegment(s image.Segment) error {
+	if u := uint32(x.unit()); s.Address%u != 0 {
+		return fmt.Errorf("segment at 0x%X is not aligned to the address scale %d", s.Address, u)
+	}
+	if len(s.Data) == 0 {
+		if ok, err := x.emptySegment(s.Address); !ok {
+			return err
+		}
+	}
+	x.SetAddress(s.Address / uint32(x.unit()))
+	if _, err := x.Write(s.Data); err != nil {
+		return err
+	}
+	if len(s.Data) == 0 {
+		return x.emitDataRecord(nil)
+	}
+	return x.Flush()
+}
+
+// emptySegment applies the empty data policy to a segment at addr without data;
+// it reports whether a marker record is to be written
+func (x *Writer) emptySegment(addr uint32) (bool, error) {
+	switch x.empty {
+	case EmptyDrop:
+		return false, nil
+	case EmptyReject:
+		return false, fmt.Errorf("%w at 0x%X", ErrEmptyData, addr)
+	}
+	return !x.fixed, nil
+}
+
+// AddrModeFor returns the smallest address mode able to reach every
+// address of m
+func AddrModeFor(m *image.Image) AddrMode {
+	var end uint64
+	if segs := m.Segments(); len(segs) > 0 {
+		end = segs[len(segs)-1].End()
+	}
+	return AddrModeReaching(end)
+}
+
+// AddrModeReaching returns the smallest address mode able to reach every
+// address below end
+func AddrModeReaching(end uint64) AddrMode {
+	switch {
+	case end <= 1<<16:
+		return Addr16
+	case end <= 1<<24:
+		return Addr24
+	}
+	return Addr32
+}
+
+// Encode writes the memory image m to w as S-Records, using the smallest
+// address mode that reaches the top of the image, followed by a start
+// record for address 0.
+func Encode(w io.Writer, m *image.Image) error {
+	return encode(w, m, image.EncodeOptions{})
+}
+
+func init() {
+	image.RegisterEncoder(image.SRecord, encode)
+}
+
+// encode is Encode configured by o: o.Width bytes per data record, 0 for
+// the default, a start record for o.Start, and no start record if
+// o.DataOnly is set
+func encode(w io.Writer, m *image.Image, o image.EncodeOptions) error {
+	mode := AddrModeFor(m)
+	if o.HasStart {
+		mode = max(mode, AddrModeReaching(uint64(o.Start)+1))
+	}
+	x := NewWriter(w, mode)
+	if o.Width > 0 {
+		x.SetWidth(o.Width)
+	}
+	if !o.DataOnly {
+		x.SetStartAddress(o.Start)
+	}
+	if err := x.Options().Validate(); err != nil {
+		return err
+	}
+	if err := x.WriteImage(m); err != nil {
+		return err
+	}
+	return x.Close()
+}
+
+// WriteFile writes the memory image m to the file named fn as S-Records
+// configured by opts.  A zero AddrMode selects the smallest mode reaching
+// the top of m, a zero Width and a nil Checksum the defaults.  The file
+// is replaced atomically: the records go to a temporary file renamed
+// over fn once complete, so an interrupted conversion never leaves a
+// truncated file for a programmer to pick up.
+func WriteFile(fn string, m *image.Image, opts Options) error {
+	if opts.AddrMode == 0 {
+		opts.AddrMode = AddrModeFor(m)
+	}
+	if opts.Width == 0 {
+		opts.Width = 10
+	}
+	if opts.Checksum == nil {
+		opts.Checksum = checksum.OnesComplement
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(fn, 0644, func(w io.Writer) error {
+		x := &Writer{w: w}
+		x.apply(opts)
+		if err := x.WriteImage(m); err != nil {
+			return err
+		}
+		return x.Close()
+	})
+}
+
 // uint32 to []byte big-endian
 func bigEndianBin(x uint32) []byte {
 	var buf = make([]byte, 4)
@@ -311,3 +768,37 @@ func bigEndianBin(x uint32) []byte {
 	buf[3] = byte(x)
 	return buf
 }
+
+// CopyContext copies binary data from src into the S-Record writer dst
+// until EOF is reached on src, returning the number of bytes copied.
+// The copy is abandoned with ctx.Err() if ctx is cancelled or its
+// deadline expires.  Residual data is left buffered in dst; the caller
+// is still responsible for calling Flush() or Close().
+func CopyContext(ctx context.Context, dst *Writer, src io.Reader) (int64, error) {
+	var (
+		buf     = make([]byte, 32*1024)
+		written int64
+	)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		n, err := src.Read(buf)
+		if n > 0 {
+			nw, werr := dst.Write(buf[:n])
+			written += int64(nw)
+			if werr != nil {
+				return written, werr
+			}
+		}
+
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
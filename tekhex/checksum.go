@@ -0,0 +1,30 @@
+package tekhex
+
+// charValues maps every character allowed in a record to its checksum
+// value: digits count 0-9, upper case letters 10-35, then '$', '%', '.'
+// and '_' 36-39 and lower case letters 40-65.  The checksum is the sum of
+// the values of all characters after the leading '%' except the two
+// checksum digits themselves, modulo 256.
+var charValues = func() (v [256]int) {
+	for i := range v {
+		v[i] = -1
+	}
+	for i, c := range "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ$%._abcdefghijklmnopqrstuvwxyz" {
+		v[c] = i
+	}
+	return
+}()
+
+// calcChecksum returns the checksum over the characters of s, and false
+// if s holds a character records may not contain
+func calcChecksum(s string) (byte, bool) {
+	var sum int
+	for i := 0; i < len(s); i++ {
+		v := charValues[s[i]]
+		if v < 0 {
+			return 0, false
+		}
+		sum += v
+	}
+	return byte(sum), true
+}
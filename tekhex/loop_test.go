@@ -0,0 +1,122 @@
+package tekhex
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestLoopback(t *testing.T) {
+	fmt.Println("TestLoopback()")
+
+	m := image.New()
+	m.Write(0x0100, []byte("Tektronix Extended hex format"))
+	m.Write(0x12345678, []byte{0xDE, 0xAD, 0xBE, 0xEF})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m); err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	m2, err := Decode(&buf)
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	if !reflect.DeepEqual(m.Segments(), m2.Segments()) {
+		fmt.Println("failure: images differ")
+		t.Fail()
+	}
+}
+
+func TestKnownRecords(t *testing.T) {
+	fmt.Println("TestKnownRecords()")
+
+	recs, err := ReadAll(strings.NewReader("%1A626810000000202020202020\n%0781010\n"))
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	if len(recs) != 2 || recs[0].Address != 0x10000000 ||
+		!bytes.Equal(recs[0].Data, []byte("      ")) ||
+		recs[1].RecordType != Termination || recs[1].Address != 0 {
+		fmt.Printf("failure: bad decode %v\n", recs)
+		t.Fail()
+	}
+
+	var buf bytes.Buffer
+	x := NewWriter(&buf)
+	x.SetAddress(0x10000000)
+	x.Write([]byte("      "))
+	x.Close()
+	if want := "%1A626810000000202020202020\n%0781010\n"; buf.String() != want {
+		fmt.Printf("failure: wrote %q, want %q\n", buf.String(), want)
+		t.Fail()
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	fmt.Println("TestSymbols()")
+
+	syms := []Symbol{{Type: Section, Value: 0x1000, Size: 0x200}}
+	for i := range 20 {
+		syms = append(syms, Symbol{Type: GlobalCode, Name: fmt.Sprintf("func_%d", i), Value: 0x1000 + uint32(i)*16})
+	}
+	syms = append(syms, Symbol{Type: LocalData, Name: "$counter.0", Value: 0x1FF0})
+
+	var buf bytes.Buffer
+	x := NewWriter(&buf)
+	if err := x.WriteSymbols("text", syms...); err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+	if err := x.WriteSymbols("text", Symbol{Type: GlobalCode, Name: "has space"}); err == nil {
+		fmt.Println("failure: bad symbol name accepted")
+		t.Fail()
+	}
+	x.Close()
+
+	recs, err := ReadAll(&buf)
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	var got []Symbol
+	for _, rec := range recs {
+		if rec.RecordType == Symbols {
+			if rec.Section != "text" {
+				fmt.Printf("failure: section %q\n", rec.Section)
+				t.Fail()
+			}
+			got = append(got, rec.Symbols...)
+		}
+	}
+	if len(recs) < 3 || !reflect.DeepEqual(got, syms) {
+		fmt.Printf("failure: %d records, symbols %v\n", len(recs), got)
+		t.Fail()
+	}
+}
+
+func TestBadChecksum(t *testing.T) {
+	fmt.Println("TestBadChecksum()")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write([]byte{1, 2, 3})
+	w.Close()
+
+	rec := []byte(buf.String())
+	rec[8] ^= 1 // first data digit
+	if _, err := ReadAll(bytes.NewReader(rec)); err == nil {
+		fmt.Println("corrupt record accepted")
+		t.Fail()
+	}
+}
@@ -0,0 +1,329 @@
+// Package tekhex reads and writes the Tektronix Extended hex format, still
+// the only input of several legacy debuggers and emulators.  Each record
+// is laid out as
+//
+//	%LLTCC...
+//
+// where LL is the number of characters after the '%', T the record type
+// and CC the checksum over the rest of the record (see below).  Numbers,
+// addresses included, are a digit giving their length, 0 standing for 16,
+// followed by that many hex digits; names likewise are a length digit
+// followed by up to 16 characters.  Data records (type 6) carry a load
+// address and the data, symbol records (type 3) a section name followed
+// by section definitions and symbols, and the termination record (type 8)
+// the start address.
+//
+// The checksum is the sum, modulo 256, of the values of the record's
+// characters: 0-9 for digits, 10-35 for upper case letters, 36-39 for
+// '$', '%', '.' and '_' and 40-65 for lower case letters.
+package tekhex
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// RecTyp indicates the type of Tektronix Extended record
+type RecTyp byte
+
+// Enumerated record types, named after their type digit
+const (
+	Symbols     RecTyp = '3'
+	Data        RecTyp = '6'
+	Termination RecTyp = '8'
+)
+
+// String is the idiomatic Go string-ize method
+func (t RecTyp) String() string {
+	switch t {
+	case Symbols:
+		return "Symbols"
+	case Data:
+		return "Data"
+	case Termination:
+		return "Termination"
+	}
+	return fmt.Sprintf("RecTyp(%q)", byte(t))
+}
+
+// SymTyp indicates the kind of a field of a symbol record
+type SymTyp byte
+
+// Enumerated symbol record fields
+const (
+	Section       SymTyp = iota // Section definition: base address and length
+	GlobalAddress               // Global symbols...
+	GlobalScalar
+	GlobalCode
+	GlobalData
+	LocalAddress // Local symbols...
+	LocalScalar
+	LocalCode
+	LocalData
+)
+
+// Symbol is one field of a symbol record: a section definition, giving
+// the base address and length of the record's section, or a symbol
+type Symbol struct {
+	Type  SymTyp
+	Name  string // Symbol name, empty for a section definition
+	Value uint32 // Symbol value, or section base address
+	Size  uint32 // Section length, 0 for a symbol
+}
+
+// HexRec is a decoded Tektronix Extended record
+type HexRec struct {
+	RecordType RecTyp
+	Address    uint32   // Load address of a data record, start address of the termination record
+	Data       []byte   // Content of a data record
+	Section    string   // Section name of a symbol record
+	Symbols    []Symbol // Fields of a symbol record
+}
+
+// String is the idiomatic Go string-ize method
+func (r HexRec) String() string {
+	if r.RecordType == Symbols {
+		return fmt.Sprintf("Type: %s, Section: %s, content: %v",
+			r.RecordType, r.Section, r.Symbols)
+	}
+	return fmt.Sprintf("Address: 0x%08X, Type: %s, content: %v",
+		r.Address, r.RecordType, r.Data)
+}
+
+// fields consumes the fields of a record body
+type fields struct {
+	s   string
+	err error
+}
+
+// field returns the next field, a length digit, 0 standing for 16,
+// followed by that many characters
+func (f *fields) field(what string) string {
+	if f.err != nil {
+		return ""
+	}
+	if f.s == "" {
+		f.err = fmt.Errorf("missing %s", what)
+		return ""
+	}
+	n, err := strconv.ParseUint(f.s[:1], 16, 8)
+	if err != nil {
+		f.err = fmt.Errorf("bad %s length %q", what, f.s[:1])
+		return ""
+	}
+	if n == 0 {
+		n = 16
+	}
+	if len(f.s) < int(n)+1 {
+		f.err = fmt.Errorf("%s cut short", what)
+		return ""
+	}
+	v := f.s[1 : n+1]
+	f.s = f.s[n+1:]
+	return v
+}
+
+// number returns the next number field, which must fit 32 bits
+func (f *fields) number(what string) uint32 {
+	s := f.field(what)
+	if f.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		f.err = fmt.Errorf("bad %s %q", what, s)
+	}
+	return uint32(v)
+}
+
+func decodeRecord(s string) (*HexRec, error) {
+	if len(s) < 6 || s[0] != '%' {
+		return nil, errors.New("Malformed Tektronix Extended record")
+	}
+
+	n, err := strconv.ParseUint(s[1:3], 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("Length field error: %s", err)
+	}
+	if int(n) != len(s)-1 {
+		return nil, fmt.Errorf("record length %d, not %d as recorded", len(s)-1, n)
+	}
+
+	cs, err := strconv.ParseUint(s[4:6], 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("Checksum field error: %s", err)
+	}
+	sum, ok := calcChecksum(s[1:4] + s[6:])
+	if !ok {
+		return nil, errors.New("Invalid character in record")
+	}
+	if byte(cs) != sum {
+		return nil, errors.New("Checksum error")
+	}
+
+	hr := &HexRec{RecordType: RecTyp(s[3])}
+	f := &fields{s: s[6:]}
+	switch hr.RecordType {
+	case Data:
+		hr.Address = f.number("address")
+		if f.err == nil {
+			if len(f.s)%2 != 0 {
+				return nil, errors.New("odd number of data digits")
+			}
+			hr.Data = make([]byte, len(f.s)/2)
+			for i := range hr.Data {
+				b, err := strconv.ParseUint(f.s[2*i:2*i+2], 16, 8)
+				if err != nil {
+					return nil, fmt.Errorf("Data chars bad: %s", err)
+				}
+				hr.Data[i] = byte(b)
+			}
+			f.s = ""
+		}
+
+	case Termination:
+		hr.Address = f.number("start address")
+
+	case Symbols:
+		hr.Section = f.field("section name")
+		for f.err == nil && f.s != "" {
+			sym := Symbol{Type: SymTyp(f.s[0] - '0')}
+			f.s = f.s[1:]
+			switch {
+			case sym.Type == Section:
+				sym.Value = f.number("section base")
+				sym.Size = f.number("section length")
+			case sym.Type <= LocalData:
+				sym.Name = f.field("symbol name")
+				sym.Value = f.number("symbol value")
+			default:
+				return nil, fmt.Errorf("unknown symbol type %q", byte(sym.Type)+'0')
+			}
+			hr.Symbols = append(hr.Symbols, sym)
+		}
+
+	default:
+		return nil, fmt.Errorf("Unknown Tektronix Extended record type %q", s[3])
+	}
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.s != "" {
+		return nil, fmt.Errorf("unexpected %q at the end of %s record", f.s, hr.RecordType)
+	}
+	return hr, nil
+}
+
+// Reader parses Tektronix Extended records incrementally in the manner
+// of bufio.Scanner: each call to Scan decodes the next record, made
+// available by Record, until the termination record has been read, the
+// input ends or an error occurs.
+type Reader struct {
+	s    *bufio.Scanner
+	rec  *HexRec
+	line int
+	err  error
+	done bool
+}
+
+// NewReader creates a Reader parsing the records read from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{s: bufio.NewScanner(r)}
+}
+
+// Scan advances to the next record, skipping blank lines.  It returns
+// false after the termination record, at the end of the input or on the
+// first error, which Err then reports.
+func (r *Reader) Scan() bool {
+	r.rec = nil
+	if r.err != nil || r.done {
+		return false
+	}
+	for r.s.Scan() {
+		r.line++
+		if len(r.s.Bytes()) == 0 {
+			continue
+		}
+		hr, err := decodeRecord(r.s.Text())
+		if err != nil {
+			r.err = fmt.Errorf("line %d: %w", r.line, err)
+			return false
+		}
+		r.rec = hr
+		r.done = hr.RecordType == Termination
+		return true
+	}
+	r.err = r.s.Err()
+	return false
+}
+
+// Record returns the record decoded by the last successful Scan
+func (r *Reader) Record() *HexRec {
+	return r.rec
+}
+
+// Line returns the line number of the current record, or of the line
+// that failed to decode
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// Err returns the first error Scan met, nil at the end of the input
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// ReadAll reads Tektronix Extended records from r up to and including the
+// termination record and returns them in file order.
+func ReadAll(r io.Reader) ([]*HexRec, error) {
+	var hrecs []*HexRec
+
+	rd := NewReader(r)
+	for rd.Scan() {
+		hrecs = append(hrecs, rd.Record())
+	}
+	if err := rd.Err(); err != nil {
+		return nil, err
+	}
+
+	return hrecs, nil
+}
+
+// ReadFile reads the Tektronix Extended file named fn
+func ReadFile(fn string) ([]*HexRec, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadAll(f)
+}
+
+// Decode reads Tektronix Extended records from r into a memory image.
+// Symbol records are skipped.
+func Decode(r io.Reader) (*image.Image, error) {
+	recs, err := ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := image.New()
+	for _, rec := range recs {
+		if rec.RecordType == Data {
+			if uint64(rec.Address)+uint64(len(rec.Data)) > 1<<32 {
+				return nil, fmt.Errorf("data record at 0x%X exceeds the 32-bit address space", rec.Address)
+			}
+			m.Write(rec.Address, rec.Data)
+		}
+	}
+
+	return m, nil
+}
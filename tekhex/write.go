@@ -0,0 +1,209 @@
+package tekhex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// MaxWidth is the largest number of data bytes a data record can carry
+// at any address
+const MaxWidth = (255 - 5 - 9) / 2
+
+// Writer implements a Tektronix Extended format writer
+type Writer struct {
+	w     io.Writer    // Underlying writer object
+	width int          // Standard length for data records
+	addr  uint32       // Address counter for data records
+	start uint32       // Address placed in the termination record
+	fin   bool         // Close() has been called
+	fifo  bytes.Buffer // FIFO for writes
+}
+
+// NewWriterWidth creates a new Tektronix Extended writer with a specific
+// data record length, at most MaxWidth
+func NewWriterWidth(w io.Writer, width int) *Writer {
+	return &Writer{w: w, width: width}
+}
+
+// NewWriter creates a new Tektronix Extended writer with a default length
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterWidth(w, 16)
+}
+
+// SetAddress flushes any buffered data and sets the address of the next
+// data record
+func (x *Writer) SetAddress(a uint32) error {
+	if err := x.Flush(); err != nil {
+		return err
+	}
+	x.addr = a
+	return nil
+}
+
+// SetStartAddress sets the address carried by the termination record
+func (x *Writer) SetStartAddress(a uint32) {
+	x.start = a
+}
+
+// number formats v as a number field with as few digits as possible
+func number(v uint32) string {
+	s := strings.ToUpper(strconv.FormatUint(uint64(v), 16))
+	return fmt.Sprintf("%X%s", len(s), s)
+}
+
+// name formats s as a name field
+func name(s string) (string, error) {
+	if len(s) < 1 || len(s) > 16 {
+		return "", fmt.Errorf("name %q not 1 to 16 characters long", s)
+	}
+	if _, ok := calcChecksum(s); !ok {
+		return "", fmt.Errorf("invalid character in name %q", s)
+	}
+	return fmt.Sprintf("%X%s", len(s)&0xF, s), nil
+}
+
+// Generic emit-record
+func (x *Writer) emitRecord(t RecTyp, body string) error {
+	n := 5 + len(body)
+	if n > 255 {
+		return fmt.Errorf("%s record of %d characters exceeds 255", t, n)
+	}
+	head := fmt.Sprintf("%02X%c", n, t)
+	cs, _ := calcChecksum(head + body)
+	_, err := fmt.Fprintf(x.w, "%%%s%02X%s\n", head, cs, body)
+	return err
+}
+
+func (x *Writer) emitData(p []byte) error {
+	return x.emitRecord(Data, number(x.addr)+fmt.Sprintf("%X", p))
+}
+
+// Write is the idiomatic Go Write() method.  Residual data shorter than
+// the record width is held until a follow-up Write(), Flush() or Close().
+func (x *Writer) Write(p []byte) (int, error) {
+	if x.fin {
+		return 0, errors.New("Writer closed")
+	}
+
+	x.fifo.Write(p)
+
+	for x.fifo.Len() >= x.width {
+		chunk := x.fifo.Next(x.width)
+		if err := x.emitData(chunk); err != nil {
+			return 0, err
+		}
+		x.addr += uint32(len(chunk))
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any data remaining in the FIFO as a runt record
+func (x *Writer) Flush() error {
+	if x.fifo.Len() > 0 {
+		chunk := x.fifo.Next(x.fifo.Len())
+		if err := x.emitData(chunk); err != nil {
+			return err
+		}
+		x.addr += uint32(len(chunk))
+	}
+	return nil
+}
+
+// WriteSymbols flushes any buffered data and writes symbol records for
+// the named section holding syms, as many as they need.  Symbols of type
+// Section define the section's base address and length and carry no
+// name.
+func (x *Writer) WriteSymbols(section string, syms ...Symbol) error {
+	if x.fin {
+		return errors.New("Writer closed")
+	}
+	if err := x.Flush(); err != nil {
+		return err
+	}
+
+	sect, err := name(section)
+	if err != nil {
+		return err
+	}
+
+	body := sect
+	for _, sym := range syms {
+		var field string
+		switch {
+		case sym.Type == Section:
+			field = "0" + number(sym.Value) + number(sym.Size)
+		case sym.Type <= LocalData:
+			n, err := name(sym.Name)
+			if err != nil {
+				return err
+			}
+			field = fmt.Sprintf("%d%s%s", sym.Type, n, number(sym.Value))
+		default:
+			return fmt.Errorf("unknown symbol type %d", sym.Type)
+		}
+
+		if 5+len(body)+len(field) > 255 {
+			if err := x.emitRecord(Symbols, body); err != nil {
+				return err
+			}
+			body = sect
+		}
+		body += field
+	}
+	return x.emitRecord(Symbols, body)
+}
+
+// Close flushes buffered data and writes the termination record.
+// Note: the underlying io.Writer is NOT closed
+func (x *Writer) Close() error {
+	if x.fin {
+		return errors.New("Writer already closed")
+	}
+	x.fin = true
+
+	if err := x.Flush(); err != nil {
+		return err
+	}
+
+	return x.emitRecord(Termination, number(x.start))
+}
+
+// Encode writes the memory image m to w in Tektronix Extended format
+func Encode(w io.Writer, m *image.Image) error {
+	return encode(w, m, 0)
+}
+
+func init() {
+	image.RegisterEncoder(image.TekHex, func(w io.Writer, m *image.Image, o image.EncodeOptions) error {
+		return encode(w, m, o.Width)
+	})
+}
+
+// encode is Encode with width bytes per data record, 0 for the default
+func encode(w io.Writer, m *image.Image, width int) error {
+	if width > MaxWidth {
+		return fmt.Errorf("record width %d exceeds %d", width, MaxWidth)
+	}
+	x := NewWriter(w)
+	if width > 0 {
+		x = NewWriterWidth(w, width)
+	}
+
+	for _, s := range m.Segments() {
+		if err := x.SetAddress(s.Address); err != nil {
+			return err
+		}
+		if _, err := x.Write(s.Data); err != nil {
+			return err
+		}
+	}
+
+	return x.Close()
+}
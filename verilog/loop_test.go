@@ -0,0 +1,82 @@
+package verilog
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+func TestLoopback(t *testing.T) {
+	fmt.Println("TestLoopback()")
+
+	m := image.New()
+	m.Write(0x10, []byte("verilog memory file, more than one line!"))
+	m.Write(0x1000, []byte{0xDE, 0xAD, 0xBE, 0xEF})
+
+	for _, o := range []Options{
+		{},
+		{Word: 4},
+		{Word: 2, BigEndian: true, PerLine: 3},
+		{Word: 4, Binary: true},
+	} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, m, o); err != nil {
+			fmt.Println("\t", err)
+			t.FailNow()
+		}
+
+		m2, err := Decode(&buf, o)
+		if err != nil {
+			fmt.Println("\t", err)
+			t.FailNow()
+		}
+
+		if !reflect.DeepEqual(m.Segments(), m2.Segments()) {
+			fmt.Printf("failure: images differ for %+v\n", o)
+			t.Fail()
+		}
+	}
+}
+
+func TestEncodeWords(t *testing.T) {
+	fmt.Println("TestEncodeWords()")
+
+	m := image.New()
+	m.Write(0x42, []byte{0x01, 0x02, 0x03})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, Options{Word: 4, Fill: 0xFF}); err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+	if want := "@00000010\n0201FFFF FFFFFF03\n"; buf.String() != want {
+		fmt.Printf("failure: got %q, want %q\n", buf.String(), want)
+		t.Fail()
+	}
+}
+
+func TestDecodeTokens(t *testing.T) {
+	fmt.Println("TestDecodeTokens()")
+
+	text := "// comment\nDEAD_BEEF 1 /* block\ncomment */ @3 xz0F\n"
+	m, err := Decode(strings.NewReader(text), Options{Word: 4, BigEndian: true})
+	if err != nil {
+		fmt.Println("\t", err)
+		t.FailNow()
+	}
+
+	if !bytes.Equal(m.Extract(0, 8, 0), []byte{0xDE, 0xAD, 0xBE, 0xEF, 0, 0, 0, 1}) ||
+		!bytes.Equal(m.Extract(12, 4, 0xFF), []byte{0, 0, 0, 0x0F}) {
+		fmt.Printf("failure: bad decode %v\n", m.Segments())
+		t.Fail()
+	}
+
+	if _, err := Decode(strings.NewReader("1FF\n"), Options{}); err == nil {
+		fmt.Println("failure: word too wide accepted")
+		t.Fail()
+	}
+}
@@ -0,0 +1,156 @@
+// Package verilog reads and writes Verilog memory initialization files,
+// the text format loaded by the $readmemh and $readmemb system tasks, so
+// firmware can be turned into simulation memory contents and back.
+//
+//	// Program memory
+//	@00000040
+//	48656c6c 00006f2c
+//
+// Each number is one memory word of a configurable number of bytes, in
+// hex or, for $readmemb, binary, and @address markers give the word
+// address of the words that follow.  Words map to bytes of the image in
+// little-endian order unless configured otherwise.
+package verilog
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Decode reads a memory initialization file configured by o from r into
+// a memory image.  Words start at word address 0 unless an @address
+// marker says otherwise, and may be written with fewer digits than the
+// word holds, or with '_' separators.  Unknown x and high impedance z
+// digits read as 0.  Line and block comments are skipped.
+func Decode(r io.Reader, o Options) (*image.Image, error) {
+	if o.Word < 0 {
+		return nil, fmt.Errorf("invalid word size %d", o.Word)
+	}
+
+	var (
+		m       = image.New()
+		n       = o.word()
+		addr    uint64 // Word address of the next word
+		comment bool   // Within a block comment
+	)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		var text string
+		text, comment = stripComments(scanner.Text(), comment)
+
+		for _, tok := range strings.Fields(text) {
+			if a, ok := strings.CutPrefix(tok, "@"); ok {
+				v, err := strconv.ParseUint(strings.ReplaceAll(a, "_", ""), 16, 32)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: address marker error: %v", line, err)
+				}
+				addr = v
+				continue
+			}
+
+			word, err := parseWord(tok, n, o.Binary)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			if !o.BigEndian {
+				slices.Reverse(word)
+			}
+			if (addr+1)*uint64(n) > 1<<32 {
+				return nil, fmt.Errorf("line %d: word address 0x%X exceeds the 32-bit address space", line, addr)
+			}
+			m.Write(uint32(addr*uint64(n)), word)
+			addr++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// stripComments returns line without its comments, given whether it
+// starts within a block comment, and whether it ends within one
+func stripComments(line string, inBlock bool) (string, bool) {
+	var b strings.Builder
+	for line != "" {
+		if inBlock {
+			_, rest, ok := strings.Cut(line, "*/")
+			if !ok {
+				return b.String(), true
+			}
+			line, inBlock = rest, false
+			b.WriteByte(' ')
+			continue
+		}
+		i := strings.Index(line, "/")
+		if i < 0 || i+1 == len(line) {
+			break
+		}
+		switch line[i+1] {
+		case '/':
+			b.WriteString(line[:i])
+			return b.String(), false
+		case '*':
+			b.WriteString(line[:i])
+			line, inBlock = line[i+2:], true
+			continue
+		}
+		b.WriteString(line[:i+1])
+		line = line[i+1:]
+	}
+	b.WriteString(line)
+	return b.String(), inBlock
+}
+
+// parseWord returns the bytes of a word of n bytes, most significant
+// first, written in hex or binary digits
+func parseWord(tok string, n int, binary bool) ([]byte, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch r {
+		case '_':
+			return -1
+		case 'x', 'X', 'z', 'Z', '?':
+			return '0'
+		}
+		return r
+	}, tok)
+
+	per := 2 // Digits per byte
+	if binary {
+		per = 8
+	}
+	digits = strings.TrimLeft(digits, "0")
+	if len(digits) > n*per {
+		return nil, fmt.Errorf("word %q exceeds %d bytes", tok, n)
+	}
+	digits = strings.Repeat("0", n*per-len(digits)) + digits
+
+	if !binary {
+		word, err := hex.DecodeString(digits)
+		if err != nil {
+			return nil, fmt.Errorf("bad word %q", tok)
+		}
+		return word, nil
+	}
+
+	word := make([]byte, n)
+	for i, c := range digits {
+		switch c {
+		case '1':
+			word[i/8] |= 0x80 >> (i % 8)
+		case '0':
+		default:
+			return nil, errors.New("bad binary word " + strconv.Quote(tok))
+		}
+	}
+	return word, nil
+}
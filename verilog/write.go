@@ -0,0 +1,79 @@
+package verilog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/peteArnt/GoHexIO/image"
+)
+
+// Options describe the memory a file initializes.  The zero value is a
+// byte wide memory in $readmemh format.
+type Options struct {
+	Word      int  // Bytes per memory word; 0 means 1
+	BigEndian bool // The byte at the lowest address is the most significant of a word
+	Binary    bool // Words in binary, for $readmemb, rather than hex
+	PerLine   int  // Words per line written; 0 means 16 bytes' worth, at least 1
+	Fill      byte // Value of the bytes missing from a partly populated word
+}
+
+// word returns the number of bytes per memory word
+func (o Options) word() int {
+	return max(o.Word, 1)
+}
+
+// Encode writes the memory image m to w as a memory initialization file
+// configured by o.  Each run of data is preceded by an @address marker
+// holding its word address; runs not aligned to whole words are padded
+// to them with o.Fill.
+func Encode(w io.Writer, m *image.Image, o Options) error {
+	if o.Word < 0 {
+		return fmt.Errorf("invalid word size %d", o.Word)
+	}
+
+	var (
+		n       = o.word()
+		perLine = o.PerLine
+		bw      = bufio.NewWriter(w)
+		word    = make([]byte, n)
+	)
+	if perLine <= 0 {
+		perLine = max(16/n, 1)
+	}
+	if n > 1 {
+		m = m.Pad(n, o.Fill)
+	}
+
+	for _, s := range m.Segments() {
+		fmt.Fprintf(bw, "@%08X\n", s.Address/uint32(n))
+		for i := 0; i < len(s.Data); i += n {
+			copy(word, s.Data[i:i+n])
+			if !o.BigEndian {
+				slices.Reverse(word)
+			}
+
+			if i/n%perLine > 0 {
+				bw.WriteByte(' ')
+			}
+			if o.Binary {
+				for _, b := range word {
+					fmt.Fprintf(bw, "%08b", b)
+				}
+			} else {
+				fmt.Fprintf(bw, "%X", word)
+			}
+			if i/n%perLine == perLine-1 || i+n == len(s.Data) {
+				bw.WriteByte('\n')
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func init() {
+	image.RegisterEncoder(image.Verilog, func(w io.Writer, m *image.Image, o image.EncodeOptions) error {
+		return Encode(w, m, Options{PerLine: o.Width, Fill: o.Fill})
+	})
+}